@@ -0,0 +1,35 @@
+package audit
+
+import (
+	"context"
+	"github.com/opentracing/opentracing-go"
+	"github.com/uber/jaeger-client-go"
+)
+
+type commandTypeKey struct{}
+
+// WithCommandType records the type of the command or event being handled, so mutations it causes can be audited
+// against it.
+func WithCommandType(ctx context.Context, commandType string) context.Context {
+	return context.WithValue(ctx, commandTypeKey{}, commandType)
+}
+
+// CommandType returns the command or event type recorded by WithCommandType, or an empty string.
+func CommandType(ctx context.Context) string {
+	if t, ok := ctx.Value(commandTypeKey{}).(string); ok {
+		return t
+	}
+	return ""
+}
+
+// TraceId returns the trace id of the span in the context, or an empty string.
+func TraceId(ctx context.Context) string {
+	span := opentracing.SpanFromContext(ctx)
+	if span == nil {
+		return ""
+	}
+	if sc, ok := span.Context().(jaeger.SpanContext); ok {
+		return sc.TraceID().String()
+	}
+	return ""
+}
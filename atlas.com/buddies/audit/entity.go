@@ -0,0 +1,52 @@
+package audit
+
+import (
+	"encoding/json"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"time"
+)
+
+func Migration(db *gorm.DB) error {
+	return db.AutoMigrate(&Entity{})
+}
+
+// Entity is an entry of the buddy list audit log. Entries are only ever appended, in the same transaction as the
+// mutation they describe.
+type Entity struct {
+	Id          uuid.UUID `gorm:"primaryKey;type:uuid"`
+	TenantId    uuid.UUID `gorm:"not null;index:idx_audits_subject"`
+	ActorId     uint32    `gorm:"not null;index"`
+	SubjectId   uint32    `gorm:"not null;index:idx_audits_subject"`
+	Action      string    `gorm:"not null"`
+	Before      string    `gorm:"type:text"`
+	After       string    `gorm:"type:text"`
+	CommandType string
+	TraceId     string
+	CreatedAt   time.Time `gorm:"not null;index"`
+}
+
+func (e Entity) TableName() string {
+	return "audits"
+}
+
+func Make(e Entity) (Model, error) {
+	return Model{
+		id:          e.Id,
+		actorId:     e.ActorId,
+		subjectId:   e.SubjectId,
+		action:      e.Action,
+		before:      rawOrNil(e.Before),
+		after:       rawOrNil(e.After),
+		commandType: e.CommandType,
+		traceId:     e.TraceId,
+		createdAt:   e.CreatedAt,
+	}, nil
+}
+
+func rawOrNil(s string) json.RawMessage {
+	if s == "" {
+		return nil
+	}
+	return json.RawMessage(s)
+}
@@ -0,0 +1,50 @@
+package audit
+
+import (
+	"encoding/json"
+	"github.com/google/uuid"
+	"time"
+)
+
+const (
+	ActionListCreated     = "LIST_CREATED"
+	ActionListDeleted     = "LIST_DELETED"
+	ActionBuddyAdded      = "BUDDY_ADDED"
+	ActionBuddyUpdated    = "BUDDY_UPDATED"
+	ActionBuddyRemoved    = "BUDDY_REMOVED"
+	ActionCapacityChanged = "CAPACITY_CHANGED"
+	ActionPresenceChanged = "PRESENCE_CHANGED"
+	ActionSettingsChanged = "SETTINGS_CHANGED"
+	// ActionCapacityRecorded is not written to the audit log. It presents capacity ledger entries in audit exports.
+	ActionCapacityRecorded = "CAPACITY_RECORDED"
+)
+
+type Model struct {
+	id          uuid.UUID
+	actorId     uint32
+	subjectId   uint32
+	action      string
+	before      json.RawMessage
+	after       json.RawMessage
+	commandType string
+	traceId     string
+	createdAt   time.Time
+}
+
+// ActorId is the character whose command or event caused the mutation.
+func (m Model) ActorId() uint32 {
+	return m.actorId
+}
+
+// SubjectId is the character whose buddy list was mutated.
+func (m Model) SubjectId() uint32 {
+	return m.subjectId
+}
+
+func (m Model) Action() string {
+	return m.action
+}
+
+func (m Model) CreatedAt() time.Time {
+	return m.createdAt
+}
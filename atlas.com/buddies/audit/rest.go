@@ -0,0 +1,50 @@
+package audit
+
+import (
+	"encoding/json"
+	"github.com/google/uuid"
+	"time"
+)
+
+type RestModel struct {
+	Id          uuid.UUID       `json:"-"`
+	ActorId     uint32          `json:"actorId"`
+	SubjectId   uint32          `json:"subjectId"`
+	Action      string          `json:"action"`
+	Before      json.RawMessage `json:"before,omitempty"`
+	After       json.RawMessage `json:"after,omitempty"`
+	CommandType string          `json:"commandType"`
+	TraceId     string          `json:"traceId"`
+	CreatedAt   time.Time       `json:"createdAt"`
+}
+
+func (r RestModel) GetName() string {
+	return "audits"
+}
+
+func (r RestModel) GetID() string {
+	return r.Id.String()
+}
+
+func (r *RestModel) SetID(strId string) error {
+	id, err := uuid.Parse(strId)
+	if err != nil {
+		return err
+	}
+	r.Id = id
+	return nil
+}
+
+func Transform(m Model) (RestModel, error) {
+	return RestModel{
+		Id:          m.id,
+		ActorId:     m.actorId,
+		SubjectId:   m.subjectId,
+		Action:      m.action,
+		Before:      m.before,
+		After:       m.after,
+		CommandType: m.commandType,
+		TraceId:     m.traceId,
+		CreatedAt:   m.createdAt,
+	}, nil
+}
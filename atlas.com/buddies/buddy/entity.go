@@ -3,6 +3,7 @@ package buddy
 import (
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"time"
 )
 
 func Migration(db *gorm.DB) error {
@@ -11,12 +12,16 @@ func Migration(db *gorm.DB) error {
 
 type Entity struct {
 	CharacterId   uint32    `gorm:"primaryKey;autoIncrement:false;not null"`
-	ListId        uuid.UUID `gorm:"not null"`
+	ListId        uuid.UUID `gorm:"not null;index"`
 	Group         string    `gorm:"not null"`
 	CharacterName string    `gorm:"not null"`
+	WorldId       *byte     `gorm:"default:null"` // nil for buddies last seen before the world was recorded.
 	ChannelId     int8      `gorm:"not null;default:-1"`
-	InShop        bool      `gorm:"not null;default:false"`
-	Pending       bool      `gorm:"not null;default:false"`
+	MapId         uint32    `gorm:"not null;default:0"`
+	LastSeen      *time.Time
+	InShop        bool `gorm:"not null;default:false"`
+	Pending       bool `gorm:"not null;default:false"`
+	CreatedAt     time.Time
 }
 
 func (e Entity) TableName() string {
@@ -24,13 +29,19 @@ func (e Entity) TableName() string {
 }
 
 func Make(e Entity) (Model, error) {
+	var lastSeen time.Time
+	if e.LastSeen != nil {
+		lastSeen = *e.LastSeen
+	}
 	return Model{
 		listId:        e.ListId,
 		characterId:   e.CharacterId,
 		group:         e.Group,
 		characterName: e.CharacterName,
 		channelId:     e.ChannelId,
+		mapId:         e.MapId,
 		inShop:        e.InShop,
 		pending:       e.Pending,
+		lastSeen:      lastSeen,
 	}, nil
 }
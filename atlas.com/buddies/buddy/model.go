@@ -1,6 +1,9 @@
 package buddy
 
-import "github.com/google/uuid"
+import (
+	"github.com/google/uuid"
+	"time"
+)
 
 type Model struct {
 	listId        uuid.UUID
@@ -8,8 +11,10 @@ type Model struct {
 	group         string
 	characterName string
 	channelId     int8
+	mapId         uint32
 	inShop        bool
 	pending       bool
+	lastSeen      time.Time
 }
 
 func (m Model) CharacterId() uint32 {
@@ -27,3 +32,20 @@ func (m Model) Group() string {
 func (m Model) ChannelId() int8 {
 	return m.channelId
 }
+
+func (m Model) MapId() uint32 {
+	return m.mapId
+}
+
+func (m Model) InShop() bool {
+	return m.inShop
+}
+
+func (m Model) Pending() bool {
+	return m.pending
+}
+
+// LastSeen is the last time (UTC) the buddy was observed online. The zero value means it is unknown.
+func (m Model) LastSeen() time.Time {
+	return m.lastSeen
+}
@@ -0,0 +1,36 @@
+package buddy
+
+import "time"
+
+// Filter narrows the buddies returned for a buddy list. Nil criteria are not applied.
+type Filter struct {
+	Online  *bool
+	Group   *string
+	Pending *bool
+	InShop  *bool
+	// NotSeenSince matches offline buddies last seen before the given time, or never seen at all.
+	NotSeenSince *time.Time
+}
+
+type Sort struct {
+	Field      string
+	Descending bool
+}
+
+type Query struct {
+	Filter Filter
+	Sort   []Sort
+	Offset int
+	Limit  int
+}
+
+// SortColumns maps the sortable REST attributes to their backing columns.
+var SortColumns = map[string]string{
+	"characterId":   "character_id",
+	"characterName": "character_name",
+	"group":         "group",
+	"channelId":     "channel_id",
+	"inShop":        "in_shop",
+	"pending":       "pending",
+	"lastSeen":      "last_seen",
+}
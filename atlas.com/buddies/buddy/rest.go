@@ -2,15 +2,17 @@ package buddy
 
 import (
 	"strconv"
+	"time"
 )
 
 type RestModel struct {
-	CharacterId   uint32 `json:"characterId"`
-	Group         string `json:"group"`
-	CharacterName string `json:"characterName"`
-	ChannelId     int8   `json:"channelId"`
-	InShop        bool   `json:"inShop"`
-	Pending       bool   `json:"pending"`
+	CharacterId   uint32     `json:"characterId"`
+	Group         string     `json:"group"`
+	CharacterName string     `json:"characterName"`
+	ChannelId     int8       `json:"channelId"`
+	InShop        bool       `json:"inShop"`
+	Pending       bool       `json:"pending"`
+	LastSeen      *time.Time `json:"lastSeen"`
 }
 
 func (r RestModel) GetName() string {
@@ -31,6 +33,10 @@ func (r *RestModel) SetID(strId string) error {
 }
 
 func Transform(m Model) (RestModel, error) {
+	var lastSeen *time.Time
+	if !m.lastSeen.IsZero() {
+		lastSeen = &m.lastSeen
+	}
 	return RestModel{
 		CharacterId:   m.characterId,
 		Group:         m.group,
@@ -38,5 +44,39 @@ func Transform(m Model) (RestModel, error) {
 		ChannelId:     m.channelId,
 		InShop:        m.inShop,
 		Pending:       m.pending,
+		LastSeen:      lastSeen,
+	}, nil
+}
+
+type LocationRestModel struct {
+	CharacterId uint32 `json:"characterId"`
+	ChannelId   int8   `json:"channelId"`
+	MapId       uint32 `json:"mapId"`
+	InShop      bool   `json:"inShop"`
+}
+
+func (r LocationRestModel) GetName() string {
+	return "locations"
+}
+
+func (r LocationRestModel) GetID() string {
+	return strconv.Itoa(int(r.CharacterId))
+}
+
+func (r *LocationRestModel) SetID(strId string) error {
+	id, err := strconv.Atoi(strId)
+	if err != nil {
+		return err
+	}
+	r.CharacterId = uint32(id)
+	return nil
+}
+
+func TransformLocation(m Model) (LocationRestModel, error) {
+	return LocationRestModel{
+		CharacterId: m.characterId,
+		ChannelId:   m.channelId,
+		MapId:       m.mapId,
+		InShop:      m.inShop,
 	}, nil
 }
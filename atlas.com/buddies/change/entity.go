@@ -0,0 +1,43 @@
+package change
+
+import (
+	"encoding/json"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"time"
+)
+
+func Migration(db *gorm.DB) error {
+	return db.AutoMigrate(&Entity{})
+}
+
+// Entity is an entry of the change feed of a buddy list. Each entry records the change which advanced the list to
+// Version, and is written in the same transaction as that change.
+type Entity struct {
+	TenantId    uuid.UUID `gorm:"primaryKey;type:uuid;not null"`
+	CharacterId uint32    `gorm:"primaryKey;autoIncrement:false;not null"`
+	Version     uint32    `gorm:"primaryKey;autoIncrement:false;not null"`
+	Type        string    `gorm:"not null"`
+	BuddyId     uint32    `gorm:"not null"`
+	Data        string    `gorm:"type:text"`
+	CreatedAt   time.Time `gorm:"not null"`
+}
+
+func (e Entity) TableName() string {
+	return "buddy_list_changes"
+}
+
+func Make(e Entity) (Model, error) {
+	var data json.RawMessage
+	if e.Data != "" {
+		data = json.RawMessage(e.Data)
+	}
+	return Model{
+		characterId: e.CharacterId,
+		version:     e.Version,
+		changeType:  e.Type,
+		buddyId:     e.BuddyId,
+		data:        data,
+		createdAt:   e.CreatedAt,
+	}, nil
+}
@@ -0,0 +1,50 @@
+package change
+
+import (
+	"encoding/json"
+	"time"
+)
+
+const (
+	TypeBuddyAdded      = "BUDDY_ADDED"
+	TypeBuddyUpdated    = "BUDDY_UPDATED"
+	TypeBuddyRemoved    = "BUDDY_REMOVED"
+	TypePresence        = "PRESENCE"
+	TypeCapacityChanged = "CAPACITY_CHANGED"
+)
+
+// Retention is the number of most recent changes kept per buddy list. Older changes are compacted away, and clients
+// asking for changes beyond them must reload the list.
+const Retention = 200
+
+type Model struct {
+	characterId uint32
+	version     uint32
+	changeType  string
+	buddyId     uint32
+	data        json.RawMessage
+	createdAt   time.Time
+}
+
+// CharacterId is the owner of the buddy list which changed.
+func (m Model) CharacterId() uint32 {
+	return m.characterId
+}
+
+// Version is the version of the buddy list after the change.
+func (m Model) Version() uint32 {
+	return m.version
+}
+
+func (m Model) Type() string {
+	return m.changeType
+}
+
+// BuddyId is the character of the buddy entry which changed. It is zero for changes to the list itself.
+func (m Model) BuddyId() uint32 {
+	return m.buddyId
+}
+
+func (m Model) CreatedAt() time.Time {
+	return m.createdAt
+}
@@ -0,0 +1,44 @@
+package change
+
+import (
+	"encoding/json"
+	"strconv"
+	"time"
+)
+
+type RestModel struct {
+	Version     uint32          `json:"version"`
+	CharacterId uint32          `json:"characterId"`
+	Type        string          `json:"type"`
+	BuddyId     uint32          `json:"buddyId,omitempty"`
+	Data        json.RawMessage `json:"data,omitempty"`
+	CreatedAt   time.Time       `json:"createdAt"`
+}
+
+func (r RestModel) GetName() string {
+	return "buddy-list-changes"
+}
+
+func (r RestModel) GetID() string {
+	return strconv.Itoa(int(r.Version))
+}
+
+func (r *RestModel) SetID(strId string) error {
+	id, err := strconv.Atoi(strId)
+	if err != nil {
+		return err
+	}
+	r.Version = uint32(id)
+	return nil
+}
+
+func Transform(m Model) (RestModel, error) {
+	return RestModel{
+		Version:     m.version,
+		CharacterId: m.characterId,
+		Type:        m.changeType,
+		BuddyId:     m.buddyId,
+		Data:        m.data,
+		CreatedAt:   m.createdAt,
+	}, nil
+}
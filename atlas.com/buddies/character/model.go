@@ -1,9 +1,14 @@
 package character
 
 type Model struct {
-	id   uint32
-	name string
-	gm   int
+	id      uint32
+	worldId byte
+	name    string
+	gm      int
+}
+
+func (m Model) WorldId() byte {
+	return m.worldId
 }
 
 func (m Model) Name() string {
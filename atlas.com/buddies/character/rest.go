@@ -6,9 +6,10 @@ import (
 )
 
 type RestModel struct {
-	Id   uint32 `json:"-"`
-	Name string `json:"name"`
-	Gm   int    `json:"gm"`
+	Id      uint32 `json:"-"`
+	WorldId byte   `json:"worldId"`
+	Name    string `json:"name"`
+	Gm      int    `json:"gm"`
 }
 
 func (r *RestModel) GetName() string {
@@ -66,8 +67,9 @@ func (r *RestModel) SetReferencedStructs(references map[string]map[string]jsonap
 
 func Extract(rm RestModel) (Model, error) {
 	return Model{
-		id:   rm.Id,
-		name: rm.Name,
-		gm:   rm.Gm,
+		id:      rm.Id,
+		worldId: rm.WorldId,
+		name:    rm.Name,
+		gm:      rm.Gm,
 	}, nil
 }
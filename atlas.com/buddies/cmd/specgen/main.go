@@ -0,0 +1,28 @@
+// Command specgen writes the OpenAPI and AsyncAPI documents of the service.
+package main
+
+import (
+	"atlas-buddies/spec"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+func main() {
+	out := flag.String("out", "api", "directory to write the documents to")
+	flag.Parse()
+
+	for name, build := range map[string]func() ([]byte, error){spec.OpenAPIFile: spec.OpenAPI, spec.AsyncAPIFile: spec.AsyncAPI} {
+		b, err := build()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Unable to build [%s]: %v\n", name, err)
+			os.Exit(1)
+		}
+		err = os.WriteFile(filepath.Join(*out, name), b, 0644)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Unable to write [%s]: %v\n", name, err)
+			os.Exit(1)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package configuration
+
+import (
+	"encoding/json"
+	"github.com/Chronicle20/atlas-tenant"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"os"
+	"sync"
+)
+
+// EnvConfigurationPath names the environment variable holding the path of the JSON configuration file.
+const EnvConfigurationPath = "BUDDY_CONFIGURATION_PATH"
+
+// Configuration holds the buddy service settings. Settings are resolved from the defaults, then any matching region
+// and major version, then any matching tenant. Later matches override earlier ones field by field.
+type Configuration struct {
+	Defaults Settings          `json:"defaults"`
+	Versions []VersionSettings `json:"versions"`
+	Tenants  []TenantSettings  `json:"tenants"`
+}
+
+type VersionSettings struct {
+	Region       string `json:"region"`
+	MajorVersion uint16 `json:"majorVersion"`
+	Settings
+}
+
+type TenantSettings struct {
+	Id uuid.UUID `json:"id"`
+	Settings
+}
+
+// Settings is a partial set of options. Nil options are inherited.
+type Settings struct {
+	AllowCrossWorld *bool             `json:"allowCrossWorld,omitempty"`
+	Capacity        *CapacitySettings `json:"capacity,omitempty"`
+	MaxBatchSize    *int              `json:"maxBatchSize,omitempty"`
+}
+
+type CapacitySettings struct {
+	Default *byte `json:"default,omitempty"`
+	Maximum *byte `json:"maximum,omitempty"`
+	// Items maps cash item ids to the capacity they grant when used. Entries are merged with inherited items, and an
+	// increment of zero removes an inherited item.
+	Items map[uint32]byte `json:"items,omitempty"`
+}
+
+var configuration Configuration
+var once sync.Once
+
+// Get returns the configuration loaded from the file named by EnvConfigurationPath. When no file is configured, or it
+// cannot be read, the built-in defaults apply.
+func Get(l logrus.FieldLogger) Configuration {
+	once.Do(func() {
+		path, ok := os.LookupEnv(EnvConfigurationPath)
+		if !ok || path == "" {
+			return
+		}
+		c, err := Load(path)
+		if err != nil {
+			l.WithError(err).Errorf("Unable to load configuration from [%s]. Using defaults.", path)
+			return
+		}
+		configuration = c
+	})
+	return configuration
+}
+
+func Load(path string) (Configuration, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return Configuration{}, err
+	}
+	var c Configuration
+	err = json.Unmarshal(b, &c)
+	if err != nil {
+		return Configuration{}, err
+	}
+	return c, nil
+}
+
+// ForTenant resolves the configuration applicable to the tenant.
+func ForTenant(l logrus.FieldLogger, t tenant.Model) Model {
+	return Get(l).Resolve(t.Id(), t.Region(), t.MajorVersion())
+}
+
+func (c Configuration) Resolve(tenantId uuid.UUID, region string, majorVersion uint16) Model {
+	m := defaultModel()
+	m = m.apply(c.Defaults)
+	for _, v := range c.Versions {
+		if v.Region == region && v.MajorVersion == majorVersion {
+			m = m.apply(v.Settings)
+		}
+	}
+	for _, ts := range c.Tenants {
+		if ts.Id == tenantId {
+			m = m.apply(ts.Settings)
+		}
+	}
+	return m
+}
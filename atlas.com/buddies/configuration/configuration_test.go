@@ -0,0 +1,115 @@
+package configuration
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestResolve(t *testing.T) {
+	tenantId := uuid.New()
+	raw := `{
+		"defaults": {},
+		"versions": [
+			{"region": "GMS", "majorVersion": 83, "allowCrossWorld": true, "capacity": {"default": 20, "maximum": 100}}
+		],
+		"tenants": [
+			{"id": "` + tenantId.String() + `", "allowCrossWorld": false, "capacity": {"maximum": 50}}
+		]
+	}`
+
+	var c Configuration
+	if err := json.Unmarshal([]byte(raw), &c); err != nil {
+		t.Fatalf("Failed to parse configuration: %v", err)
+	}
+
+	tests := []struct {
+		name         string
+		tenantId     uuid.UUID
+		region       string
+		majorVersion uint16
+		expected     bool
+		defaultCap   byte
+		maximumCap   byte
+	}{
+		{"Defaults", uuid.New(), "JMS", 185, false, DefaultCapacity, MaximumCapacity},
+		{"Version override", uuid.New(), "GMS", 83, true, 20, 100},
+		{"Tenant override", tenantId, "GMS", 83, false, 20, 50},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := c.Resolve(tt.tenantId, tt.region, tt.majorVersion)
+			if m.AllowCrossWorld() != tt.expected {
+				t.Errorf("Expected allowCrossWorld %t, but got %t", tt.expected, m.AllowCrossWorld())
+			}
+			if m.Capacity().Default() != tt.defaultCap {
+				t.Errorf("Expected default capacity %d, but got %d", tt.defaultCap, m.Capacity().Default())
+			}
+			if m.Capacity().Maximum() != tt.maximumCap {
+				t.Errorf("Expected maximum capacity %d, but got %d", tt.maximumCap, m.Capacity().Maximum())
+			}
+		})
+	}
+}
+
+func TestResolveCapacityItems(t *testing.T) {
+	tenantId := uuid.New()
+	raw := `{
+		"defaults": {"capacity": {"items": {"5140000": 5, "5140001": 10}}},
+		"tenants": [
+			{"id": "` + tenantId.String() + `", "capacity": {"items": {"5140001": 0, "5140002": 20}}}
+		]
+	}`
+
+	var c Configuration
+	if err := json.Unmarshal([]byte(raw), &c); err != nil {
+		t.Fatalf("Failed to parse configuration: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		tenantId uuid.UUID
+		itemId   uint32
+		expected byte
+		ok       bool
+	}{
+		{"Default item", uuid.New(), 5140001, 10, true},
+		{"Unknown item", uuid.New(), 5140002, 0, false},
+		{"Inherited item", tenantId, 5140000, 5, true},
+		{"Removed item", tenantId, 5140001, 0, false},
+		{"Tenant item", tenantId, 5140002, 20, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inc, ok := c.Resolve(tt.tenantId, "GMS", 83).Capacity().ItemIncrement(tt.itemId)
+			if ok != tt.ok || inc != tt.expected {
+				t.Errorf("Expected increment %d (%t), but got %d (%t)", tt.expected, tt.ok, inc, ok)
+			}
+		})
+	}
+}
+
+func TestResolveMaxBatchSize(t *testing.T) {
+	tenantId := uuid.New()
+	raw := `{
+		"defaults": {},
+		"tenants": [
+			{"id": "` + tenantId.String() + `", "maxBatchSize": 25}
+		]
+	}`
+
+	var c Configuration
+	if err := json.Unmarshal([]byte(raw), &c); err != nil {
+		t.Fatalf("Failed to parse configuration: %v", err)
+	}
+
+	if m := c.Resolve(uuid.New(), "GMS", 83); m.MaxBatchSize() != DefaultMaxBatchSize {
+		t.Errorf("Expected max batch size %d, but got %d", DefaultMaxBatchSize, m.MaxBatchSize())
+	}
+	if m := c.Resolve(tenantId, "GMS", 83); m.MaxBatchSize() != 25 {
+		t.Errorf("Expected max batch size %d, but got %d", 25, m.MaxBatchSize())
+	}
+}
@@ -0,0 +1,99 @@
+package configuration
+
+const (
+	DefaultCapacity = byte(30)
+	MaximumCapacity = byte(255)
+	// DefaultMaxBatchSize is the number of characters a batch request may ask for when not configured.
+	DefaultMaxBatchSize = 100
+)
+
+type Model struct {
+	allowCrossWorld bool
+	capacity        CapacityPolicy
+	maxBatchSize    int
+}
+
+// CapacityPolicy bounds the capacity of buddy lists.
+type CapacityPolicy struct {
+	defaultCapacity byte
+	maximumCapacity byte
+	items           map[uint32]byte
+}
+
+func defaultModel() Model {
+	return Model{
+		allowCrossWorld: false,
+		capacity: CapacityPolicy{
+			defaultCapacity: DefaultCapacity,
+			maximumCapacity: MaximumCapacity,
+		},
+		maxBatchSize: DefaultMaxBatchSize,
+	}
+}
+
+func (m Model) apply(s Settings) Model {
+	if s.AllowCrossWorld != nil {
+		m.allowCrossWorld = *s.AllowCrossWorld
+	}
+	if s.MaxBatchSize != nil {
+		m.maxBatchSize = *s.MaxBatchSize
+	}
+	if s.Capacity != nil {
+		if s.Capacity.Default != nil {
+			m.capacity.defaultCapacity = *s.Capacity.Default
+		}
+		if s.Capacity.Maximum != nil {
+			m.capacity.maximumCapacity = *s.Capacity.Maximum
+		}
+		if len(s.Capacity.Items) > 0 {
+			items := make(map[uint32]byte, len(m.capacity.items)+len(s.Capacity.Items))
+			for id, inc := range m.capacity.items {
+				items[id] = inc
+			}
+			for id, inc := range s.Capacity.Items {
+				if inc == 0 {
+					delete(items, id)
+					continue
+				}
+				items[id] = inc
+			}
+			m.capacity.items = items
+		}
+	}
+	return m
+}
+
+// AllowCrossWorld reports whether characters on different worlds may become buddies.
+func (m Model) AllowCrossWorld() bool {
+	return m.allowCrossWorld
+}
+
+// MaxBatchSize is the number of characters a batch request may ask for.
+func (m Model) MaxBatchSize() int {
+	return m.maxBatchSize
+}
+
+func (m Model) Capacity() CapacityPolicy {
+	return m.capacity
+}
+
+// Default is the capacity given to newly created buddy lists.
+func (p CapacityPolicy) Default() byte {
+	return p.defaultCapacity
+}
+
+// Maximum is the largest capacity a buddy list may be given.
+func (p CapacityPolicy) Maximum() byte {
+	return p.maximumCapacity
+}
+
+// Allows reports whether capacity is within the policy.
+func (p CapacityPolicy) Allows(capacity byte) bool {
+	return capacity > 0 && capacity <= p.maximumCapacity
+}
+
+// ItemIncrement returns the capacity granted by using the cash item, and whether the item grants capacity at all.
+func (p CapacityPolicy) ItemIncrement(itemId uint32) (byte, bool) {
+	inc, ok := p.items[itemId]
+	return inc, ok
+}
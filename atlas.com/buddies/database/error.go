@@ -0,0 +1,15 @@
+package database
+
+import (
+	"gorm.io/gorm"
+)
+
+// TranslateError maps a driver error to the gorm error it corresponds to, e.g. gorm.ErrDuplicatedKey for a unique
+// violation, when the dialector supports it. Translation is applied by the call sites which expect such errors rather
+// than for the connection as a whole, so other callers keep seeing the driver errors they always have.
+func TranslateError(db *gorm.DB, err error) error {
+	if t, ok := db.Dialector.(gorm.ErrorTranslator); ok {
+		return t.Translate(err)
+	}
+	return err
+}
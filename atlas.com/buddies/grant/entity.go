@@ -0,0 +1,26 @@
+package grant
+
+import (
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"time"
+)
+
+func Migration(db *gorm.DB) error {
+	return db.AutoMigrate(&Entity{})
+}
+
+// Entity records a capacity item which has been applied to a buddy list. The transaction id is unique per tenant so a
+// replayed item use cannot grant capacity twice.
+type Entity struct {
+	TenantId      uuid.UUID `gorm:"primaryKey;type:uuid;not null"`
+	TransactionId string    `gorm:"primaryKey;not null"`
+	CharacterId   uint32    `gorm:"not null"`
+	ItemId        uint32    `gorm:"not null"`
+	Increment     byte      `gorm:"not null"`
+	CreatedAt     time.Time
+}
+
+func (e Entity) TableName() string {
+	return "capacity_grants"
+}
@@ -1,6 +1,7 @@
 package cashshop
 
 import (
+	"atlas-buddies/audit"
 	consumer2 "atlas-buddies/kafka/consumer"
 	cashshop2 "atlas-buddies/kafka/message/cashshop"
 	"atlas-buddies/list"
@@ -10,8 +11,10 @@ import (
 	"github.com/Chronicle20/atlas-kafka/message"
 	"github.com/Chronicle20/atlas-kafka/topic"
 	"github.com/Chronicle20/atlas-model/model"
+	"github.com/google/uuid"
 	"github.com/sirupsen/logrus"
 	"gorm.io/gorm"
+	"strconv"
 )
 
 func InitConsumers(l logrus.FieldLogger) func(func(config consumer.Config, decorators ...model.Decorator[consumer.Config])) func(consumerGroupId string) {
@@ -29,6 +32,7 @@ func InitHandlers(l logrus.FieldLogger) func(db *gorm.DB) func(rf func(topic str
 			t, _ = topic.EnvProvider(l)(cashshop2.EnvEventTopicStatus)()
 			_, _ = rf(t, message.AdaptHandler(message.PersistentConfig(handleStatusEventCharacterEnter(db))))
 			_, _ = rf(t, message.AdaptHandler(message.PersistentConfig(handleStatusEventCharacterExit(db))))
+			_, _ = rf(t, message.AdaptHandler(message.PersistentConfig(handleStatusEventItemUsed(db))))
 		}
 	}
 }
@@ -50,3 +54,23 @@ func handleStatusEventCharacterExit(db *gorm.DB) message.Handler[cashshop2.Statu
 		_ = list.NewProcessor(l, ctx, db).UpdateBuddyShopStatusAndEmit(e.Body.CharacterId, e.WorldId, false)
 	}
 }
+
+func handleStatusEventItemUsed(db *gorm.DB) message.Handler[cashshop2.StatusEvent[cashshop2.ItemUsedBody]] {
+	return func(l logrus.FieldLogger, ctx context.Context, e cashshop2.StatusEvent[cashshop2.ItemUsedBody]) {
+		if e.Type != cashshop2.EventStatusTypeItemUsed {
+			return
+		}
+		if e.Body.TransactionId == uuid.Nil && e.Body.SerialNumber == 0 {
+			l.Errorf("Item [%d] used by character [%d] has neither a transaction id nor a serial number. Ignoring.", e.Body.ItemId, e.Body.CharacterId)
+			return
+		}
+		transactionId := e.Body.TransactionId.String()
+		if e.Body.TransactionId == uuid.Nil {
+			transactionId = strconv.FormatUint(e.Body.SerialNumber, 10)
+		}
+		err := list.NewProcessor(l, audit.WithCommandType(ctx, e.Type), db).ApplyCapacityItemAndEmit(e.Body.CharacterId, e.WorldId, e.Body.ItemId, transactionId)
+		if err != nil {
+			l.WithError(err).Errorf("Unable to apply item [%d] used by character [%d].", e.Body.ItemId, e.Body.CharacterId)
+		}
+	}
+}
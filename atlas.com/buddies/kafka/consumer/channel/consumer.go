@@ -0,0 +1,45 @@
+package channel
+
+import (
+	consumer2 "atlas-buddies/kafka/consumer"
+	channel2 "atlas-buddies/kafka/message/channel"
+	"atlas-buddies/list"
+	"context"
+	"github.com/Chronicle20/atlas-kafka/consumer"
+	"github.com/Chronicle20/atlas-kafka/handler"
+	"github.com/Chronicle20/atlas-kafka/message"
+	"github.com/Chronicle20/atlas-kafka/topic"
+	"github.com/Chronicle20/atlas-model/model"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+func InitConsumers(l logrus.FieldLogger) func(func(config consumer.Config, decorators ...model.Decorator[consumer.Config])) func(consumerGroupId string) {
+	return func(rf func(config consumer.Config, decorators ...model.Decorator[consumer.Config])) func(consumerGroupId string) {
+		return func(consumerGroupId string) {
+			rf(consumer2.NewConfig(l)("channel_status_event")(channel2.EnvEventTopicStatus)(consumerGroupId), consumer.SetHeaderParsers(consumer.SpanHeaderParser, consumer.TenantHeaderParser))
+		}
+	}
+}
+
+func InitHandlers(l logrus.FieldLogger) func(db *gorm.DB) func(rf func(topic string, handler handler.Handler) (string, error)) {
+	return func(db *gorm.DB) func(rf func(topic string, handler handler.Handler) (string, error)) {
+		return func(rf func(topic string, handler handler.Handler) (string, error)) {
+			var t string
+			t, _ = topic.EnvProvider(l)(channel2.EnvEventTopicStatus)()
+			_, _ = rf(t, message.AdaptHandler(message.PersistentConfig(handleStatusEventShutdown(db))))
+		}
+	}
+}
+
+func handleStatusEventShutdown(db *gorm.DB) message.Handler[channel2.StatusEvent] {
+	return func(l logrus.FieldLogger, ctx context.Context, e channel2.StatusEvent) {
+		if e.Type != channel2.EventStatusTypeShutdown {
+			return
+		}
+		err := list.NewProcessor(l, ctx, db).ClearChannelPresenceAndEmit(e.WorldId, e.ChannelId)
+		if err != nil {
+			l.WithError(err).Errorf("Unable to clear buddy presence for world [%d] channel [%d].", e.WorldId, e.ChannelId)
+		}
+	}
+}
@@ -1,8 +1,11 @@
 package character
 
 import (
+	"atlas-buddies/audit"
 	consumer2 "atlas-buddies/kafka/consumer"
+	message2 "atlas-buddies/kafka/message"
 	"atlas-buddies/kafka/message/character"
+	"atlas-buddies/kafka/producer"
 	"atlas-buddies/list"
 	"context"
 	"github.com/Chronicle20/atlas-kafka/consumer"
@@ -32,6 +35,7 @@ func InitHandlers(l logrus.FieldLogger) func(db *gorm.DB) func(rf func(topic str
 			_, _ = rf(t, message.AdaptHandler(message.PersistentConfig(handleStatusEventLogin(db))))
 			_, _ = rf(t, message.AdaptHandler(message.PersistentConfig(handleStatusEventLogout(db))))
 			_, _ = rf(t, message.AdaptHandler(message.PersistentConfig(handleStatusEventChannelChanged(db))))
+			_, _ = rf(t, message.AdaptHandler(message.PersistentConfig(handleStatusEventMapChanged(db))))
 		}
 	}
 }
@@ -41,7 +45,7 @@ func handleStatusEventCreated(db *gorm.DB) message.Handler[character.StatusEvent
 		if e.Type != character.StatusEventTypeCreated {
 			return
 		}
-		_, _ = list.NewProcessor(l, ctx, db).Create(e.CharacterId, 30)
+		_, _ = list.NewProcessor(l, audit.WithCommandType(ctx, e.Type), db).CreateDefault(e.CharacterId, e.WorldId)
 	}
 }
 
@@ -51,7 +55,7 @@ func handleStatusEventDeleted(db *gorm.DB) message.Handler[character.StatusEvent
 			return
 		}
 
-		err := list.NewProcessor(l, ctx, db).DeleteAndEmit(e.CharacterId, e.WorldId)
+		err := list.NewProcessor(l, audit.WithCommandType(ctx, e.Type), db).PurgeAndEmit(e.CharacterId, e.WorldId)
 		if err != nil {
 			l.WithError(err).Errorf("Unable to delete for character [%d].", e.CharacterId)
 		}
@@ -63,7 +67,19 @@ func handleStatusEventLogin(db *gorm.DB) func(l logrus.FieldLogger, ctx context.
 		if event.Type != character.StatusEventTypeLogin {
 			return
 		}
-		err := list.NewProcessor(l, ctx, db).UpdateBuddyChannelAndEmit(event.CharacterId, event.WorldId, int8(event.Body.ChannelId))
+		p := list.NewProcessor(l, ctx, db)
+		err := message2.Emit(producer.ProviderImpl(l)(ctx))(func(buf *message2.Buffer) error {
+			err := p.UpdateBuddyChannel(buf)(event.CharacterId, event.WorldId, int8(event.Body.ChannelId), event.Body.MapId)
+			if err != nil {
+				return err
+			}
+			// a snapshot which cannot be built must not withhold the channel change from buddies.
+			err = p.Snapshot(buf)(event.CharacterId, event.WorldId)
+			if err != nil {
+				l.WithError(err).Errorf("Unable to emit buddy list snapshot for character [%d].", event.CharacterId)
+			}
+			return nil
+		})
 		if err != nil {
 			l.WithError(err).Errorf("Unable to process login for character [%d].", event.CharacterId)
 		}
@@ -75,7 +91,7 @@ func handleStatusEventLogout(db *gorm.DB) func(l logrus.FieldLogger, ctx context
 		if event.Type != character.StatusEventTypeLogout {
 			return
 		}
-		err := list.NewProcessor(l, ctx, db).UpdateBuddyChannelAndEmit(event.CharacterId, event.WorldId, -1)
+		err := list.NewProcessor(l, ctx, db).UpdateBuddyChannelAndEmit(event.CharacterId, event.WorldId, -1, 0)
 		if err != nil {
 			l.WithError(err).Errorf("Unable to process logout for character [%d].", event.CharacterId)
 		}
@@ -90,9 +106,24 @@ func handleStatusEventChannelChanged(db *gorm.DB) func(l logrus.FieldLogger, ctx
 		if event.Body.ChannelId == event.Body.OldChannelId {
 			return
 		}
-		err := list.NewProcessor(l, ctx, db).UpdateBuddyChannelAndEmit(event.CharacterId, event.WorldId, int8(event.Body.ChannelId))
+		err := list.NewProcessor(l, ctx, db).UpdateBuddyChannelAndEmit(event.CharacterId, event.WorldId, int8(event.Body.ChannelId), event.Body.MapId)
 		if err != nil {
 			l.WithError(err).Errorf("Unable to process change channel for character [%d].", event.CharacterId)
 		}
 	}
 }
+
+func handleStatusEventMapChanged(db *gorm.DB) func(l logrus.FieldLogger, ctx context.Context, event character.StatusEvent[character.MapChangedStatusEventBody]) {
+	return func(l logrus.FieldLogger, ctx context.Context, event character.StatusEvent[character.MapChangedStatusEventBody]) {
+		if event.Type != character.StatusEventTypeMapChanged {
+			return
+		}
+		if event.Body.TargetMapId == event.Body.OldMapId {
+			return
+		}
+		err := list.NewProcessor(l, ctx, db).UpdateBuddyMap(event.CharacterId, event.Body.TargetMapId)
+		if err != nil {
+			l.WithError(err).Errorf("Unable to process map change for character [%d].", event.CharacterId)
+		}
+	}
+}
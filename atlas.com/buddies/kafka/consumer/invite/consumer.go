@@ -1,6 +1,7 @@
 package invite
 
 import (
+	"atlas-buddies/audit"
 	consumer2 "atlas-buddies/kafka/consumer"
 	invite2 "atlas-buddies/kafka/message/invite"
 	"atlas-buddies/list"
@@ -43,7 +44,7 @@ func handleAcceptedStatusEvent(db *gorm.DB) func(l logrus.FieldLogger, ctx conte
 			return
 		}
 
-		_ = list.NewProcessor(l, ctx, db).AcceptInviteAndEmit(e.Body.TargetId, e.WorldId, e.Body.OriginatorId)
+		_ = list.NewProcessor(l, audit.WithCommandType(ctx, e.Type), db).AcceptInviteAndEmit(e.Body.TargetId, e.WorldId, e.Body.OriginatorId)
 	}
 }
 
@@ -57,6 +58,6 @@ func handleRejectedStatusEvent(db *gorm.DB) func(l logrus.FieldLogger, ctx conte
 			return
 		}
 
-		_ = list.NewProcessor(l, ctx, db).DeleteBuddyAndEmit(e.Body.OriginatorId, e.WorldId, e.Body.TargetId)
+		_ = list.NewProcessor(l, audit.WithCommandType(ctx, e.Type), db).DeleteBuddyAndEmit(e.Body.OriginatorId, e.WorldId, e.Body.TargetId)
 	}
 }
@@ -1,15 +1,21 @@
 package list
 
 import (
+	"atlas-buddies/audit"
 	consumer2 "atlas-buddies/kafka/consumer"
 	list2 "atlas-buddies/kafka/message/list"
 	"atlas-buddies/list"
+	"atlas-buddies/settings"
+	"atlas-buddies/stream"
 	"context"
+	"encoding/json"
 	"github.com/Chronicle20/atlas-kafka/consumer"
 	"github.com/Chronicle20/atlas-kafka/handler"
 	"github.com/Chronicle20/atlas-kafka/message"
 	"github.com/Chronicle20/atlas-kafka/topic"
 	"github.com/Chronicle20/atlas-model/model"
+	"github.com/Chronicle20/atlas-tenant"
+	"github.com/segmentio/kafka-go"
 	"github.com/sirupsen/logrus"
 	"gorm.io/gorm"
 )
@@ -31,16 +37,54 @@ func InitHandlers(l logrus.FieldLogger) func(db *gorm.DB) func(rf func(topic str
 			_, _ = rf(t, message.AdaptHandler(message.PersistentConfig(handleRequestBuddyAddCommand(db))))
 			_, _ = rf(t, message.AdaptHandler(message.PersistentConfig(handleRequestBuddyDeleteCommand(db))))
 			_, _ = rf(t, message.AdaptHandler(message.PersistentConfig(handleIncreaseCapacityCommand(db))))
+			_, _ = rf(t, message.AdaptHandler(message.PersistentConfig(handleSetCapacityCommand(db))))
+			_, _ = rf(t, message.AdaptHandler(message.PersistentConfig(handleDeleteBuddyListCommand(db))))
+			_, _ = rf(t, message.AdaptHandler(message.PersistentConfig(handleUpdateSettingsCommand(db))))
 		}
 	}
 }
 
+// InitStatusEventConsumers registers the consumer of buddy list status events which feeds the event stream. Each
+// instance must use a consumer group of its own, so that its stream subscribers observe every event. Consumption starts
+// at the newest events, as subscribers catch up on older changes through the change feed.
+func InitStatusEventConsumers(l logrus.FieldLogger) func(func(config consumer.Config, decorators ...model.Decorator[consumer.Config])) func(consumerGroupId string) {
+	return func(rf func(config consumer.Config, decorators ...model.Decorator[consumer.Config])) func(consumerGroupId string) {
+		return func(consumerGroupId string) {
+			rf(consumer2.NewConfig(l)("buddy_list_status_event")(list2.EnvStatusEventTopic)(consumerGroupId), consumer.SetHeaderParsers(consumer.SpanHeaderParser, consumer.TenantHeaderParser), consumer.SetStartOffset(kafka.LastOffset))
+		}
+	}
+}
+
+func InitStatusEventHandlers(l logrus.FieldLogger) func(h *stream.Hub) func(rf func(topic string, handler handler.Handler) (string, error)) {
+	return func(h *stream.Hub) func(rf func(topic string, handler handler.Handler) (string, error)) {
+		return func(rf func(topic string, handler handler.Handler) (string, error)) {
+			var t string
+			t, _ = topic.EnvProvider(l)(list2.EnvStatusEventTopic)()
+			_, _ = rf(t, message.AdaptHandler(message.PersistentConfig(handleStatusEvent(h))))
+		}
+	}
+}
+
+// handleStatusEvent publishes a buddy list status event, of any type, to the stream subscribers of the character and
+// tenant it belongs to.
+func handleStatusEvent(h *stream.Hub) message.Handler[list2.StatusEvent[json.RawMessage]] {
+	return func(l logrus.FieldLogger, ctx context.Context, e list2.StatusEvent[json.RawMessage]) {
+		t := tenant.MustFromContext(ctx)
+		data, err := json.Marshal(e)
+		if err != nil {
+			l.WithError(err).Errorf("Unable to encode [%s] status event of character [%d] for streaming.", e.Type, e.CharacterId)
+			return
+		}
+		h.Publish(t.Id(), e.CharacterId, stream.Event{Version: e.Version, CorrelationId: e.CorrelationId, Type: e.Type, Data: data})
+	}
+}
+
 func handleCreateBuddyListCommand(db *gorm.DB) func(l logrus.FieldLogger, ctx context.Context, c list2.Command[list2.CreateCommandBody]) {
 	return func(l logrus.FieldLogger, ctx context.Context, c list2.Command[list2.CreateCommandBody]) {
 		if c.Type != list2.CommandTypeCreate {
 			return
 		}
-		_, err := list.NewProcessor(l, ctx, db).Create(c.CharacterId, c.Body.Capacity)
+		_, err := list.NewProcessor(l, audit.WithCommandType(ctx, c.Type), db).Correlate(c.CorrelationId).CreateAndEmit(c.CharacterId, c.WorldId, c.Body.Capacity)
 		if err != nil {
 			l.WithError(err).Errorf("Error creating buddy list for character [%d].", c.CharacterId)
 		}
@@ -52,7 +96,7 @@ func handleRequestBuddyAddCommand(db *gorm.DB) message.Handler[list2.Command[lis
 		if c.Type != list2.CommandTypeRequestAdd {
 			return
 		}
-		err := list.NewProcessor(l, ctx, db).RequestAddBuddyAndEmit(c.CharacterId, c.WorldId, c.Body.CharacterId, c.Body.Group)
+		err := list.NewProcessor(l, audit.WithCommandType(ctx, c.Type), db).IfMatch(c.IfMatch).Correlate(c.CorrelationId).RequestAddBuddyAndEmit(c.CharacterId, c.WorldId, c.Body.CharacterId, c.Body.Group)
 		if err != nil {
 			l.WithError(err).Errorf("Error attempting to add [%d] to character [%d] buddy list.", c.Body.CharacterId, c.CharacterId)
 		}
@@ -64,7 +108,7 @@ func handleRequestBuddyDeleteCommand(db *gorm.DB) message.Handler[list2.Command[
 		if c.Type != list2.CommandTypeRequestDelete {
 			return
 		}
-		err := list.NewProcessor(l, ctx, db).RequestDeleteBuddyAndEmit(c.CharacterId, c.WorldId, c.Body.CharacterId)
+		err := list.NewProcessor(l, audit.WithCommandType(ctx, c.Type), db).IfMatch(c.IfMatch).Correlate(c.CorrelationId).RequestDeleteBuddyAndEmit(c.CharacterId, c.WorldId, c.Body.CharacterId)
 		if err != nil {
 			l.WithError(err).Errorf("Error attempting to delete [%d] to character [%d] buddy list.", c.Body.CharacterId, c.CharacterId)
 		}
@@ -86,7 +130,7 @@ func handleRequestBuddyDeleteCommand(db *gorm.DB) message.Handler[list2.Command[
 //
 // Event Emission:
 //   - Success: CAPACITY_CHANGE event with new capacity
-//   - Failure: ERROR event with specific error type (INVALID_CAPACITY, CHARACTER_NOT_FOUND, UNKNOWN_ERROR)
+//   - Failure: ERROR event with specific error type (INVALID_CAPACITY, CHARACTER_NOT_FOUND, VERSION_CONFLICT, UNKNOWN_ERROR)
 //
 // Parameters:
 //   - db: Database connection for the processor
@@ -98,9 +142,57 @@ func handleIncreaseCapacityCommand(db *gorm.DB) message.Handler[list2.Command[li
 		if c.Type != list2.CommandTypeIncreaseCapacity {
 			return
 		}
-		err := list.NewProcessor(l, ctx, db).IncreaseCapacityAndEmit(c.CharacterId, c.WorldId, c.Body.NewCapacity)
+		err := list.NewProcessor(l, audit.WithCommandType(ctx, c.Type), db).IfMatch(c.IfMatch).Correlate(c.CorrelationId).IncreaseCapacityAndEmit(c.CharacterId, c.WorldId, c.Body.NewCapacity)
 		if err != nil {
 			l.WithError(err).Errorf("Failed to increase buddy list capacity for character [%d].", c.CharacterId)
 		}
 	}
 }
+
+// handleSetCapacityCommand creates a Kafka message handler for the administrative SET_CAPACITY command, which may lower
+// a character's buddy list capacity according to the supplied overflow policy.
+func handleSetCapacityCommand(db *gorm.DB) message.Handler[list2.Command[list2.SetCapacityCommandBody]] {
+	return func(l logrus.FieldLogger, ctx context.Context, c list2.Command[list2.SetCapacityCommandBody]) {
+		if c.Type != list2.CommandTypeSetCapacity {
+			return
+		}
+		err := list.NewProcessor(l, audit.WithCommandType(ctx, c.Type), db).IfMatch(c.IfMatch).Correlate(c.CorrelationId).SetCapacityAndEmit(c.CharacterId, c.WorldId, c.Body.Capacity, c.Body.OverflowPolicy)
+		if err != nil {
+			l.WithError(err).Errorf("Failed to set buddy list capacity for character [%d].", c.CharacterId)
+		}
+	}
+}
+
+// handleDeleteBuddyListCommand creates a Kafka message handler for the administrative DELETE command, which deletes the
+// buddy list of a character and removes the character from the buddy lists of their buddies.
+func handleDeleteBuddyListCommand(db *gorm.DB) message.Handler[list2.Command[list2.DeleteCommandBody]] {
+	return func(l logrus.FieldLogger, ctx context.Context, c list2.Command[list2.DeleteCommandBody]) {
+		if c.Type != list2.CommandTypeDelete {
+			return
+		}
+		err := list.NewProcessor(l, audit.WithCommandType(ctx, c.Type), db).IfMatch(c.IfMatch).Correlate(c.CorrelationId).DeleteAndEmit(c.CharacterId, c.WorldId)
+		if err != nil {
+			l.WithError(err).Errorf("Unable to delete buddy list of character [%d].", c.CharacterId)
+		}
+	}
+}
+
+// handleUpdateSettingsCommand creates a Kafka message handler for UPDATE_SETTINGS commands, which change the privacy
+// settings of a character and, with them, which buddies are shown the character's presence.
+func handleUpdateSettingsCommand(db *gorm.DB) message.Handler[list2.Command[list2.UpdateSettingsCommandBody]] {
+	return func(l logrus.FieldLogger, ctx context.Context, c list2.Command[list2.UpdateSettingsCommandBody]) {
+		if c.Type != list2.CommandTypeUpdateSettings {
+			return
+		}
+		sc := settings.Change{
+			WhisperPolicy:   c.Body.WhisperPolicy,
+			DeclineRequests: c.Body.DeclineRequests,
+			Visibility:      c.Body.Visibility,
+			HiddenGroups:    c.Body.HiddenGroups,
+		}
+		err := list.NewProcessor(l, audit.WithCommandType(ctx, c.Type), db).Correlate(c.CorrelationId).UpdateSettingsAndEmit(c.CharacterId, c.WorldId, sc)
+		if err != nil {
+			l.WithError(err).Errorf("Unable to update settings of character [%d].", c.CharacterId)
+		}
+	}
+}
@@ -57,4 +57,28 @@ func TestHandleIncreaseCapacityCommandCreation(t *testing.T) {
 	if handler == nil {
 		t.Error("Expected handler function to be created, got nil")
 	}
-}
\ No newline at end of file
+}
+
+// TestHandleDeleteBuddyListCommandTypeGuard tests that the DELETE handler ignores other command types
+func TestHandleDeleteBuddyListCommandTypeGuard(t *testing.T) {
+	logger := logrus.New()
+	handler := handleDeleteBuddyListCommand(nil) // nil db is ok for type guard test
+
+	for _, commandType := range []string{list2.CommandTypeCreate, list2.CommandTypeRequestDelete, "UNKNOWN_TYPE", ""} {
+		t.Run("Command type: "+commandType, func(t *testing.T) {
+			command := list2.Command[list2.DeleteCommandBody]{
+				WorldId:     1,
+				CharacterId: 12345,
+				Type:        commandType,
+			}
+			func() {
+				defer func() {
+					if r := recover(); r != nil {
+						t.Errorf("Handler panicked for command type %s: %v", commandType, r)
+					}
+				}()
+				handler(logger, nil, command) // nil context is ok for type guard test
+			}()
+		})
+	}
+}
@@ -1,9 +1,12 @@
 package cashshop
 
+import "github.com/google/uuid"
+
 const (
 	EnvEventTopicStatus           = "EVENT_TOPIC_CASH_SHOP_STATUS"
 	EventStatusTypeCharacterEnter = "CHARACTER_ENTER"
 	EventStatusTypeCharacterExit  = "CHARACTER_EXIT"
+	EventStatusTypeItemUsed       = "ITEM_USED"
 )
 
 type StatusEvent[E any] struct {
@@ -15,3 +18,10 @@ type StatusEvent[E any] struct {
 type MovementBody struct {
 	CharacterId uint32 `json:"characterId"`
 }
+
+type ItemUsedBody struct {
+	CharacterId   uint32    `json:"characterId"`
+	ItemId        uint32    `json:"itemId"`
+	TransactionId uuid.UUID `json:"transactionId"`
+	SerialNumber  uint64    `json:"serialNumber"`
+}
@@ -0,0 +1,15 @@
+package channel
+
+const (
+	EnvEventTopicStatus     = "EVENT_TOPIC_CHANNEL_STATUS"
+	EventStatusTypeStarted  = "STARTED"
+	EventStatusTypeShutdown = "SHUTDOWN"
+)
+
+type StatusEvent struct {
+	Type      string `json:"type"`
+	WorldId   byte   `json:"worldId"`
+	ChannelId byte   `json:"channelId"`
+	IpAddress string `json:"ipAddress"`
+	Port      int    `json:"port"`
+}
@@ -7,6 +7,7 @@ const (
 	StatusEventTypeLogin          = "LOGIN"
 	StatusEventTypeLogout         = "LOGOUT"
 	StatusEventTypeChannelChanged = "CHANNEL_CHANGED"
+	StatusEventTypeMapChanged     = "MAP_CHANGED"
 )
 
 type StatusEvent[E any] struct {
@@ -38,3 +39,10 @@ type ChannelChangedStatusEventBody struct {
 	OldChannelId byte   `json:"oldChannelId"`
 	MapId        uint32 `json:"mapId"`
 }
+
+type MapChangedStatusEventBody struct {
+	ChannelId      byte   `json:"channelId"`
+	OldMapId       uint32 `json:"oldMapId"`
+	TargetMapId    uint32 `json:"targetMapId"`
+	TargetPortalId uint32 `json:"targetPortalId"`
+}
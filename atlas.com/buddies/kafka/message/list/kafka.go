@@ -1,5 +1,7 @@
 package list
 
+import "time"
+
 const (
 	// EnvCommandTopic defines the environment variable for the buddy list command topic
 	EnvCommandTopic            = "COMMAND_TOPIC_BUDDY_LIST"
@@ -11,13 +13,28 @@ const (
 	CommandTypeRequestDelete   = "REQUEST_DELETE"
 	// CommandTypeIncreaseCapacity is the command type for increasing buddy list capacity
 	CommandTypeIncreaseCapacity = "INCREASE_CAPACITY"
+	// CommandTypeSetCapacity is the administrative command type for setting buddy list capacity to any allowed value
+	CommandTypeSetCapacity = "SET_CAPACITY"
+	// CommandTypeDelete is the administrative command type for deleting a buddy list
+	CommandTypeDelete = "DELETE"
+	// CommandTypeUpdateSettings is the command type for updating the privacy settings of a character
+	CommandTypeUpdateSettings = "UPDATE_SETTINGS"
+
+	// OverflowPolicyReject rejects a capacity below the number of entries already on the list
+	OverflowPolicyReject = "REJECT"
+	// OverflowPolicyTrimPending removes the newest pending entries until the list fits the new capacity
+	OverflowPolicyTrimPending = "TRIM_PENDING"
 )
 
 type Command[E any] struct {
 	WorldId     byte   `json:"worldId"`
 	CharacterId uint32 `json:"characterId"`
-	Type        string `json:"type"`
-	Body        E      `json:"body"`
+	// IfMatch, when set, requires the buddy list to be at this version for the command to apply.
+	IfMatch *uint32 `json:"ifMatch,omitempty"`
+	// CorrelationId, when set, is echoed on every status event the command results in.
+	CorrelationId string `json:"correlationId,omitempty"`
+	Type          string `json:"type"`
+	Body          E      `json:"body"`
 }
 
 type CreateCommandBody struct {
@@ -34,6 +51,10 @@ type RequestDeleteBuddyCommandBody struct {
 	CharacterId uint32 `json:"characterId"`
 }
 
+// DeleteCommandBody represents the body of a delete command, which has no parameters.
+type DeleteCommandBody struct {
+}
+
 // IncreaseCapacityCommandBody represents the body of an increase capacity command.
 // This command is used to increase a character's buddy list capacity.
 type IncreaseCapacityCommandBody struct {
@@ -41,6 +62,28 @@ type IncreaseCapacityCommandBody struct {
 	NewCapacity byte `json:"newCapacity"`
 }
 
+// SetCapacityCommandBody represents the body of a set capacity command.
+// Unlike IncreaseCapacityCommandBody, the capacity may be lower than the current capacity.
+type SetCapacityCommandBody struct {
+	// Capacity is the new capacity value
+	Capacity byte `json:"capacity"`
+	// OverflowPolicy decides what happens when the list holds more entries than Capacity. Defaults to REJECT.
+	OverflowPolicy string `json:"overflowPolicy,omitempty"`
+}
+
+// UpdateSettingsCommandBody represents the body of an update settings command. Omitted settings are left unchanged.
+type UpdateSettingsCommandBody struct {
+	// WhisperPolicy decides who may whisper the character, EVERYONE or BUDDIES.
+	WhisperPolicy *string `json:"whisperPolicy,omitempty"`
+	// DeclineRequests silently declines every buddy request made to the character.
+	DeclineRequests *bool `json:"declineRequests,omitempty"`
+	// Visibility decides which buddies are shown the presence of the character, VISIBLE, HIDDEN or HIDDEN_FROM_GROUPS.
+	Visibility *string `json:"visibility,omitempty"`
+	// HiddenGroups are the groups of the character's buddy list hidden from under HIDDEN_FROM_GROUPS. Null leaves them
+	// unchanged, while an empty list clears them.
+	HiddenGroups []string `json:"hiddenGroups"`
+}
+
 const (
 	// EnvStatusEventTopic defines the environment variable for the buddy list status event topic
 	EnvStatusEventTopic                = "EVENT_TOPIC_BUDDY_LIST_STATUS"
@@ -54,6 +97,12 @@ const (
 	StatusEventTypeBuddyChannelChange  = "BUDDY_CHANNEL_CHANGE"
 	// StatusEventTypeBuddyCapacityUpdate is emitted when buddy list capacity changes
 	StatusEventTypeBuddyCapacityUpdate = "CAPACITY_CHANGE"
+	// StatusEventTypeListSnapshot is emitted to a character with their full buddy list on login
+	StatusEventTypeListSnapshot = "LIST_SNAPSHOT"
+	// StatusEventTypeSettingsChange is emitted when the privacy settings of a character change
+	StatusEventTypeSettingsChange = "SETTINGS_CHANGE"
+	// StatusEventTypeListDeleted is emitted to a character when their buddy list is deleted
+	StatusEventTypeListDeleted = "LIST_DELETED"
 	// StatusEventTypeError is emitted when an operation fails
 	StatusEventTypeError               = "ERROR"
 
@@ -67,8 +116,16 @@ const (
 	StatusEventErrorCannotBuddyGm     = "CANNOT_BUDDY_GM"
 	// StatusEventErrorCharacterNotFound indicates the character could not be found
 	StatusEventErrorCharacterNotFound = "CHARACTER_NOT_FOUND"
-	// StatusEventErrorInvalidCapacity indicates the new capacity is invalid (not greater than current)
+	// StatusEventErrorDifferentWorld indicates the characters are in different worlds
+	StatusEventErrorDifferentWorld = "DIFFERENT_WORLD"
+	// StatusEventErrorInvalidCapacity indicates the new capacity is invalid (not greater than current, or outside of policy)
 	StatusEventErrorInvalidCapacity   = "INVALID_CAPACITY"
+	// StatusEventErrorCapacityOverflow indicates the list holds more entries than the requested capacity
+	StatusEventErrorCapacityOverflow = "CAPACITY_OVERFLOW"
+	// StatusEventErrorVersionConflict indicates the buddy list changed since the version the command was based on
+	StatusEventErrorVersionConflict = "VERSION_CONFLICT"
+	// StatusEventErrorInvalidSettings indicates the requested privacy settings are not valid
+	StatusEventErrorInvalidSettings = "INVALID_SETTINGS"
 	// StatusEventErrorUnknownError indicates an unexpected error occurred
 	StatusEventErrorUnknownError      = "UNKNOWN_ERROR"
 )
@@ -76,15 +133,21 @@ const (
 type StatusEvent[E any] struct {
 	WorldId     byte   `json:"worldId"`
 	CharacterId uint32 `json:"characterId"`
-	Type        string `json:"type"`
-	Body        E      `json:"body"`
+	// Version is the buddy list version after the change the event describes. It is omitted from ERROR events, and from
+	// the SETTINGS_CHANGE of a character without a buddy list.
+	Version uint32 `json:"version,omitempty"`
+	// CorrelationId is the correlation id of the command the event results from, if it carried one.
+	CorrelationId string `json:"correlationId,omitempty"`
+	Type          string `json:"type"`
+	Body          E      `json:"body"`
 }
 
 type BuddyAddedStatusEventBody struct {
-	CharacterId   uint32 `json:"characterId"`
-	Group         string `json:"group"`
-	CharacterName string `json:"characterName"`
-	ChannelId     int8   `json:"channelId"`
+	CharacterId   uint32     `json:"characterId"`
+	Group         string     `json:"group"`
+	CharacterName string     `json:"characterName"`
+	ChannelId     int8       `json:"channelId"`
+	LastSeen      *time.Time `json:"lastSeen,omitempty"`
 }
 
 type BuddyRemovedStatusEventBody struct {
@@ -92,22 +155,51 @@ type BuddyRemovedStatusEventBody struct {
 }
 
 type BuddyUpdatedStatusEventBody struct {
-	CharacterId   uint32 `json:"characterId"`
-	Group         string `json:"group"`
-	CharacterName string `json:"characterName"`
-	ChannelId     int8   `json:"channelId"`
-	InShop        bool   `json:"inShop"`
+	CharacterId   uint32     `json:"characterId"`
+	Group         string     `json:"group"`
+	CharacterName string     `json:"characterName"`
+	ChannelId     int8       `json:"channelId"`
+	InShop        bool       `json:"inShop"`
+	LastSeen      *time.Time `json:"lastSeen,omitempty"`
 }
 
 type BuddyChannelChangeStatusEventBody struct {
-	CharacterId uint32 `json:"characterId"`
-	ChannelId   int8   `json:"channelId"`
+	CharacterId uint32     `json:"characterId"`
+	ChannelId   int8       `json:"channelId"`
+	LastSeen    *time.Time `json:"lastSeen,omitempty"`
 }
 
 type BuddyCapacityChangeStatusEventBody struct {
 	Capacity byte `json:"capacity"`
 }
 
+type ListSnapshotStatusEventBody struct {
+	Capacity byte                `json:"capacity"`
+	Buddies  []ListSnapshotBuddy `json:"buddies"`
+}
+
+type ListSnapshotBuddy struct {
+	CharacterId   uint32     `json:"characterId"`
+	Group         string     `json:"group"`
+	CharacterName string     `json:"characterName"`
+	ChannelId     int8       `json:"channelId"`
+	MapId         uint32     `json:"mapId"`
+	InShop        bool       `json:"inShop"`
+	Pending       bool       `json:"pending"`
+	LastSeen      *time.Time `json:"lastSeen,omitempty"`
+}
+
+type SettingsChangeStatusEventBody struct {
+	WhisperPolicy   string   `json:"whisperPolicy"`
+	DeclineRequests bool     `json:"declineRequests"`
+	Visibility      string   `json:"visibility"`
+	HiddenGroups    []string `json:"hiddenGroups"`
+}
+
+// ListDeletedStatusEventBody represents the body of a list deleted event, which has no attributes.
+type ListDeletedStatusEventBody struct {
+}
+
 type ErrorStatusEventBody struct {
 	Error string `json:"error"`
 }
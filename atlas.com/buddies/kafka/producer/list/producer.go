@@ -5,11 +5,13 @@ import (
 	"github.com/Chronicle20/atlas-kafka/producer"
 	"github.com/Chronicle20/atlas-model/model"
 	"github.com/segmentio/kafka-go"
+	"time"
 )
 
-func CreateCommandProvider(characterId uint32, capacity byte) model.Provider[[]kafka.Message] {
+func CreateCommandProvider(characterId uint32, worldId byte, capacity byte) model.Provider[[]kafka.Message] {
 	key := producer.CreateKey(int(characterId))
 	value := &list2.Command[list2.CreateCommandBody]{
+		WorldId:     worldId,
 		CharacterId: characterId,
 		Type:        list2.CommandTypeCreate,
 		Body: list2.CreateCommandBody{
@@ -19,27 +21,108 @@ func CreateCommandProvider(characterId uint32, capacity byte) model.Provider[[]k
 	return producer.SingleMessageProvider(key, value)
 }
 
-func BuddyAddedStatusEventProvider(characterId uint32, worldId byte, buddyId uint32, buddyName string, buddyChannelId int8, group string) model.Provider[[]kafka.Message] {
+func RequestAddCommandProvider(characterId uint32, worldId byte, targetId uint32, targetName string, group string, ifMatch *uint32, correlationId string) model.Provider[[]kafka.Message] {
+	key := producer.CreateKey(int(characterId))
+	value := &list2.Command[list2.RequestAddBuddyCommandBody]{
+		WorldId:       worldId,
+		CharacterId:   characterId,
+		IfMatch:       ifMatch,
+		CorrelationId: correlationId,
+		Type:          list2.CommandTypeRequestAdd,
+		Body: list2.RequestAddBuddyCommandBody{
+			CharacterId:   targetId,
+			CharacterName: targetName,
+			Group:         group,
+		},
+	}
+	return producer.SingleMessageProvider(key, value)
+}
+
+func RequestDeleteCommandProvider(characterId uint32, worldId byte, targetId uint32, ifMatch *uint32, correlationId string) model.Provider[[]kafka.Message] {
+	key := producer.CreateKey(int(characterId))
+	value := &list2.Command[list2.RequestDeleteBuddyCommandBody]{
+		WorldId:       worldId,
+		CharacterId:   characterId,
+		IfMatch:       ifMatch,
+		CorrelationId: correlationId,
+		Type:          list2.CommandTypeRequestDelete,
+		Body: list2.RequestDeleteBuddyCommandBody{
+			CharacterId: targetId,
+		},
+	}
+	return producer.SingleMessageProvider(key, value)
+}
+
+func DeleteCommandProvider(characterId uint32, worldId byte, ifMatch *uint32, correlationId string) model.Provider[[]kafka.Message] {
+	key := producer.CreateKey(int(characterId))
+	value := &list2.Command[list2.DeleteCommandBody]{
+		WorldId:       worldId,
+		CharacterId:   characterId,
+		IfMatch:       ifMatch,
+		CorrelationId: correlationId,
+		Type:          list2.CommandTypeDelete,
+		Body:          list2.DeleteCommandBody{},
+	}
+	return producer.SingleMessageProvider(key, value)
+}
+
+func SetCapacityCommandProvider(characterId uint32, worldId byte, capacity byte, overflowPolicy string, ifMatch *uint32, correlationId string) model.Provider[[]kafka.Message] {
+	key := producer.CreateKey(int(characterId))
+	value := &list2.Command[list2.SetCapacityCommandBody]{
+		WorldId:       worldId,
+		CharacterId:   characterId,
+		IfMatch:       ifMatch,
+		CorrelationId: correlationId,
+		Type:          list2.CommandTypeSetCapacity,
+		Body: list2.SetCapacityCommandBody{
+			Capacity:       capacity,
+			OverflowPolicy: overflowPolicy,
+		},
+	}
+	return producer.SingleMessageProvider(key, value)
+}
+
+func UpdateSettingsCommandProvider(characterId uint32, worldId byte, whisperPolicy *string, declineRequests *bool, visibility *string, hiddenGroups []string, correlationId string) model.Provider[[]kafka.Message] {
+	key := producer.CreateKey(int(characterId))
+	value := &list2.Command[list2.UpdateSettingsCommandBody]{
+		WorldId:       worldId,
+		CharacterId:   characterId,
+		CorrelationId: correlationId,
+		Type:          list2.CommandTypeUpdateSettings,
+		Body: list2.UpdateSettingsCommandBody{
+			WhisperPolicy:   whisperPolicy,
+			DeclineRequests: declineRequests,
+			Visibility:      visibility,
+			HiddenGroups:    hiddenGroups,
+		},
+	}
+	return producer.SingleMessageProvider(key, value)
+}
+
+func BuddyAddedStatusEventProvider(characterId uint32, worldId byte, version uint32, buddyId uint32, buddyName string, buddyChannelId int8, group string, lastSeen time.Time) model.Provider[[]kafka.Message] {
 	key := producer.CreateKey(int(characterId))
 	value := &list2.StatusEvent[list2.BuddyAddedStatusEventBody]{
 		CharacterId: characterId,
 		WorldId:     worldId,
+		Version:     version,
 		Type:        list2.StatusEventTypeBuddyAdded,
 		Body: list2.BuddyAddedStatusEventBody{
 			CharacterId:   buddyId,
 			Group:         group,
 			CharacterName: buddyName,
 			ChannelId:     buddyChannelId,
+			LastSeen:      optionalTime(lastSeen),
 		},
 	}
 	return producer.SingleMessageProvider(key, value)
 }
 
-func BuddyRemovedStatusEventProvider(characterId uint32, worldId byte, buddyId uint32) model.Provider[[]kafka.Message] {
+func BuddyRemovedStatusEventProvider(characterId uint32, worldId byte, version uint32, buddyId uint32) model.Provider[[]kafka.Message] {
 	key := producer.CreateKey(int(characterId))
 	value := &list2.StatusEvent[list2.BuddyRemovedStatusEventBody]{
 		CharacterId: characterId,
 		WorldId:     worldId,
+		Version:     version,
 		Type:        list2.StatusEventTypeBuddyRemoved,
 		Body: list2.BuddyRemovedStatusEventBody{
 			CharacterId: buddyId,
@@ -48,11 +131,12 @@ func BuddyRemovedStatusEventProvider(characterId uint32, worldId byte, buddyId u
 	return producer.SingleMessageProvider(key, value)
 }
 
-func BuddyUpdatedStatusEventProvider(characterId uint32, worldId byte, buddyId uint32, group string, buddyName string, channelId int8, inShop bool) model.Provider[[]kafka.Message] {
+func BuddyUpdatedStatusEventProvider(characterId uint32, worldId byte, version uint32, buddyId uint32, group string, buddyName string, channelId int8, inShop bool, lastSeen time.Time) model.Provider[[]kafka.Message] {
 	key := producer.CreateKey(int(characterId))
 	value := &list2.StatusEvent[list2.BuddyUpdatedStatusEventBody]{
 		CharacterId: characterId,
 		WorldId:     worldId,
+		Version:     version,
 		Type:        list2.StatusEventTypeBuddyUpdated,
 		Body: list2.BuddyUpdatedStatusEventBody{
 			CharacterId:   buddyId,
@@ -60,30 +144,34 @@ func BuddyUpdatedStatusEventProvider(characterId uint32, worldId byte, buddyId u
 			CharacterName: buddyName,
 			ChannelId:     channelId,
 			InShop:        inShop,
+			LastSeen:      optionalTime(lastSeen),
 		},
 	}
 	return producer.SingleMessageProvider(key, value)
 }
 
-func BuddyChannelChangeStatusEventProvider(characterId uint32, worldId byte, buddyId uint32, buddyChannelId int8) model.Provider[[]kafka.Message] {
+func BuddyChannelChangeStatusEventProvider(characterId uint32, worldId byte, version uint32, buddyId uint32, buddyChannelId int8, lastSeen time.Time) model.Provider[[]kafka.Message] {
 	key := producer.CreateKey(int(characterId))
 	value := &list2.StatusEvent[list2.BuddyChannelChangeStatusEventBody]{
 		CharacterId: characterId,
 		WorldId:     worldId,
+		Version:     version,
 		Type:        list2.StatusEventTypeBuddyChannelChange,
 		Body: list2.BuddyChannelChangeStatusEventBody{
 			CharacterId: buddyId,
 			ChannelId:   buddyChannelId,
+			LastSeen:    optionalTime(lastSeen),
 		},
 	}
 	return producer.SingleMessageProvider(key, value)
 }
 
-func BuddyCapacityChangeStatusEventProvider(characterId uint32, worldId byte, capacity byte) model.Provider[[]kafka.Message] {
+func BuddyCapacityChangeStatusEventProvider(characterId uint32, worldId byte, version uint32, capacity byte) model.Provider[[]kafka.Message] {
 	key := producer.CreateKey(int(characterId))
 	value := &list2.StatusEvent[list2.BuddyCapacityChangeStatusEventBody]{
 		CharacterId: characterId,
 		WorldId:     worldId,
+		Version:     version,
 		Type:        list2.StatusEventTypeBuddyCapacityUpdate,
 		Body: list2.BuddyCapacityChangeStatusEventBody{
 			Capacity: capacity,
@@ -92,6 +180,50 @@ func BuddyCapacityChangeStatusEventProvider(characterId uint32, worldId byte, ca
 	return producer.SingleMessageProvider(key, value)
 }
 
+func ListSnapshotStatusEventProvider(characterId uint32, worldId byte, version uint32, capacity byte, buddies []list2.ListSnapshotBuddy) model.Provider[[]kafka.Message] {
+	key := producer.CreateKey(int(characterId))
+	value := &list2.StatusEvent[list2.ListSnapshotStatusEventBody]{
+		CharacterId: characterId,
+		WorldId:     worldId,
+		Version:     version,
+		Type:        list2.StatusEventTypeListSnapshot,
+		Body: list2.ListSnapshotStatusEventBody{
+			Capacity: capacity,
+			Buddies:  buddies,
+		},
+	}
+	return producer.SingleMessageProvider(key, value)
+}
+
+func SettingsChangeStatusEventProvider(characterId uint32, worldId byte, version uint32, whisperPolicy string, declineRequests bool, visibility string, hiddenGroups []string) model.Provider[[]kafka.Message] {
+	key := producer.CreateKey(int(characterId))
+	value := &list2.StatusEvent[list2.SettingsChangeStatusEventBody]{
+		CharacterId: characterId,
+		WorldId:     worldId,
+		Version:     version,
+		Type:        list2.StatusEventTypeSettingsChange,
+		Body: list2.SettingsChangeStatusEventBody{
+			WhisperPolicy:   whisperPolicy,
+			DeclineRequests: declineRequests,
+			Visibility:      visibility,
+			HiddenGroups:    hiddenGroups,
+		},
+	}
+	return producer.SingleMessageProvider(key, value)
+}
+
+func ListDeletedStatusEventProvider(characterId uint32, worldId byte, version uint32) model.Provider[[]kafka.Message] {
+	key := producer.CreateKey(int(characterId))
+	value := &list2.StatusEvent[list2.ListDeletedStatusEventBody]{
+		CharacterId: characterId,
+		WorldId:     worldId,
+		Version:     version,
+		Type:        list2.StatusEventTypeListDeleted,
+		Body:        list2.ListDeletedStatusEventBody{},
+	}
+	return producer.SingleMessageProvider(key, value)
+}
+
 func ErrorStatusEventProvider(characterId uint32, worldId byte, error string) model.Provider[[]kafka.Message] {
 	key := producer.CreateKey(int(characterId))
 	value := &list2.StatusEvent[list2.ErrorStatusEventBody]{
@@ -104,3 +236,10 @@ func ErrorStatusEventProvider(characterId uint32, worldId byte, error string) mo
 	}
 	return producer.SingleMessageProvider(key, value)
 }
+
+func optionalTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
@@ -2,8 +2,11 @@ package producer
 
 import (
 	"context"
+	"encoding/json"
 	"github.com/Chronicle20/atlas-kafka/producer"
 	"github.com/Chronicle20/atlas-kafka/topic"
+	"github.com/Chronicle20/atlas-model/model"
+	"github.com/segmentio/kafka-go"
 	"github.com/sirupsen/logrus"
 )
 
@@ -18,3 +21,41 @@ func ProviderImpl(l logrus.FieldLogger) func(ctx context.Context) func(token str
 		}
 	}
 }
+
+// CorrelatedProvider decorates a provider so every message it produces carries the correlation id as the top level
+// correlationId field of its JSON body. An empty correlation id leaves the provider unchanged.
+func CorrelatedProvider(p Provider, correlationId string) Provider {
+	if correlationId == "" {
+		return p
+	}
+	return func(token string) producer.MessageProducer {
+		mp := p(token)
+		return func(provider model.Provider[[]kafka.Message]) error {
+			return mp(model.Map(correlate(correlationId))(provider))
+		}
+	}
+}
+
+func correlate(correlationId string) model.Transformer[[]kafka.Message, []kafka.Message] {
+	return func(ms []kafka.Message) ([]kafka.Message, error) {
+		cid, err := json.Marshal(correlationId)
+		if err != nil {
+			return nil, err
+		}
+		results := make([]kafka.Message, 0, len(ms))
+		for _, m := range ms {
+			var body map[string]json.RawMessage
+			err = json.Unmarshal(m.Value, &body)
+			if err != nil {
+				return nil, err
+			}
+			body["correlationId"] = cid
+			m.Value, err = json.Marshal(body)
+			if err != nil {
+				return nil, err
+			}
+			results = append(results, m)
+		}
+		return results, nil
+	}
+}
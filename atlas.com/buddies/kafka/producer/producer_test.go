@@ -0,0 +1,32 @@
+package producer
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/segmentio/kafka-go"
+)
+
+func TestCorrelate(t *testing.T) {
+	ms := []kafka.Message{{Key: []byte("1"), Value: []byte(`{"characterId":12345,"type":"BUDDY_ADDED"}`)}}
+
+	results, err := correlate("abc")(ms)
+	if err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+	if len(results) != 1 || string(results[0].Key) != "1" {
+		t.Fatalf("Expected the message to be kept, got %v", results)
+	}
+
+	var body map[string]interface{}
+	err = json.Unmarshal(results[0].Value, &body)
+	if err != nil {
+		t.Fatalf("Failed to decode message: %v", err)
+	}
+	if body["correlationId"] != "abc" {
+		t.Errorf("Expected correlation id abc, got %v", body["correlationId"])
+	}
+	if body["type"] != "BUDDY_ADDED" {
+		t.Errorf("Expected body to be preserved, got %v", body)
+	}
+}
@@ -0,0 +1,37 @@
+package ledger
+
+import (
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"time"
+)
+
+func Migration(db *gorm.DB) error {
+	return db.AutoMigrate(&Entity{})
+}
+
+// Entity is an entry of the capacity change ledger. Entries are only ever appended.
+type Entity struct {
+	Id          uuid.UUID `gorm:"primaryKey;type:uuid"`
+	TenantId    uuid.UUID `gorm:"not null;index:idx_capacity_changes_character"`
+	CharacterId uint32    `gorm:"not null;index:idx_capacity_changes_character"`
+	OldCapacity byte      `gorm:"not null"`
+	NewCapacity byte      `gorm:"not null"`
+	Source      string    `gorm:"not null"`
+	CreatedAt   time.Time `gorm:"not null"`
+}
+
+func (e Entity) TableName() string {
+	return "capacity_changes"
+}
+
+func Make(e Entity) (Model, error) {
+	return Model{
+		id:          e.Id,
+		characterId: e.CharacterId,
+		oldCapacity: e.OldCapacity,
+		newCapacity: e.NewCapacity,
+		source:      e.Source,
+		createdAt:   e.CreatedAt,
+	}, nil
+}
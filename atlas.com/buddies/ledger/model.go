@@ -0,0 +1,50 @@
+package ledger
+
+import (
+	"github.com/google/uuid"
+	"time"
+)
+
+const (
+	// SourceDefault is recorded when a buddy list is created with the tenant default capacity.
+	SourceDefault = "DEFAULT"
+	// SourceCommand is recorded for capacity given by a CREATE or INCREASE_CAPACITY command.
+	SourceCommand = "COMMAND"
+	// SourceCashItem is recorded for capacity granted by using a cash item.
+	SourceCashItem = "CASH_ITEM"
+	// SourceAdmin is recorded for capacity set by the administrative SET_CAPACITY command.
+	SourceAdmin = "ADMIN"
+)
+
+type Model struct {
+	id          uuid.UUID
+	characterId uint32
+	oldCapacity byte
+	newCapacity byte
+	source      string
+	createdAt   time.Time
+}
+
+func (m Model) Id() uuid.UUID {
+	return m.id
+}
+
+func (m Model) CharacterId() uint32 {
+	return m.characterId
+}
+
+func (m Model) OldCapacity() byte {
+	return m.oldCapacity
+}
+
+func (m Model) NewCapacity() byte {
+	return m.newCapacity
+}
+
+func (m Model) Source() string {
+	return m.source
+}
+
+func (m Model) CreatedAt() time.Time {
+	return m.createdAt
+}
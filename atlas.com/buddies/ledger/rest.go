@@ -0,0 +1,43 @@
+package ledger
+
+import (
+	"github.com/google/uuid"
+	"time"
+)
+
+type RestModel struct {
+	Id          uuid.UUID `json:"-"`
+	CharacterId uint32    `json:"characterId"`
+	OldCapacity byte      `json:"oldCapacity"`
+	NewCapacity byte      `json:"newCapacity"`
+	Source      string    `json:"source"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+func (r RestModel) GetName() string {
+	return "capacity-changes"
+}
+
+func (r RestModel) GetID() string {
+	return r.Id.String()
+}
+
+func (r *RestModel) SetID(strId string) error {
+	id, err := uuid.Parse(strId)
+	if err != nil {
+		return err
+	}
+	r.Id = id
+	return nil
+}
+
+func Transform(m Model) (RestModel, error) {
+	return RestModel{
+		Id:          m.id,
+		CharacterId: m.characterId,
+		OldCapacity: m.oldCapacity,
+		NewCapacity: m.newCapacity,
+		Source:      m.source,
+		CreatedAt:   m.createdAt,
+	}, nil
+}
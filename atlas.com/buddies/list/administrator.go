@@ -1,28 +1,45 @@
 package list
 
 import (
+	"atlas-buddies/audit"
 	"atlas-buddies/buddy"
+	"atlas-buddies/change"
+	"atlas-buddies/database"
+	"atlas-buddies/grant"
+	"atlas-buddies/ledger"
+	presence2 "atlas-buddies/presence"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"github.com/Chronicle20/atlas-tenant"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
 )
 
-func create(db *gorm.DB, t tenant.Model, characterId uint32, capacity byte) (Model, error) {
+func create(db *gorm.DB, t tenant.Model, characterId uint32, worldId byte, capacity byte) (Model, error) {
 	e := &Entity{
 		TenantId:    t.Id(),
 		CharacterId: characterId,
+		WorldId:     &worldId,
 		Capacity:    capacity,
+		Version:     1,
 	}
 
 	err := db.Create(e).Error
 	if err != nil {
-		return Model{}, err
+		return Model{}, database.TranslateError(db, err)
 	}
 	return Make(*e)
 }
 
+func updateWorld(db *gorm.DB, tenantId uuid.UUID, characterId uint32, worldId byte) error {
+	return db.Model(&Entity{}).
+		Where("tenant_id = ? AND character_id = ?", tenantId, characterId).
+		Update("world_id", worldId).Error
+}
+
 func addPendingBuddy(db *gorm.DB, tenantId uuid.UUID, characterId uint32, targetId uint32, targetName string, group string) error {
 	return addBuddy(db, tenantId, characterId, targetId, targetName, group, true)
 }
@@ -44,10 +61,11 @@ func addBuddy(db *gorm.DB, tenantId uuid.UUID, characterId uint32, targetId uint
 	return db.Create(&nb).Error
 }
 
-func removeBuddy(db *gorm.DB, tenantId uuid.UUID, characterId uint32, targetId uint32) error {
+// removeBuddy removes targetId from the buddy list of characterId, returning the removed entry.
+func removeBuddy(db *gorm.DB, tenantId uuid.UUID, characterId uint32, targetId uint32) (buddy.Entity, error) {
 	e, err := byCharacterIdEntityProvider(tenantId, characterId)(db)()
 	if err != nil {
-		return err
+		return buddy.Entity{}, err
 	}
 
 	var rb buddy.Entity
@@ -59,13 +77,13 @@ func removeBuddy(db *gorm.DB, tenantId uuid.UUID, characterId uint32, targetId u
 	}
 
 	if rb.ListId == uuid.Nil {
-		return gorm.ErrRecordNotFound
+		return buddy.Entity{}, gorm.ErrRecordNotFound
 	}
 
-	return db.Delete(&rb).Error
+	return rb, db.Delete(&rb).Error
 }
 
-func updateBuddyChannel(db *gorm.DB, tenantId uuid.UUID, characterId uint32, targetId uint32, channelId int8) (bool, error) {
+func updateBuddyChannel(db *gorm.DB, tenantId uuid.UUID, characterId uint32, targetId uint32, worldId byte, channelId int8, mapId uint32, lastSeen time.Time) (bool, error) {
 	bbl, err := byCharacterIdEntityProvider(tenantId, targetId)(db)()
 	if err != nil {
 		return false, err
@@ -80,7 +98,36 @@ func updateBuddyChannel(db *gorm.DB, tenantId uuid.UUID, characterId uint32, tar
 	if meAsBuddy == nil {
 		return false, nil
 	}
+	meAsBuddy.WorldId = &worldId
 	meAsBuddy.ChannelId = channelId
+	meAsBuddy.MapId = mapId
+	if !lastSeen.IsZero() {
+		meAsBuddy.LastSeen = &lastSeen
+	}
+
+	err = db.Save(meAsBuddy).Error
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func updateBuddyMap(db *gorm.DB, tenantId uuid.UUID, characterId uint32, targetId uint32, mapId uint32) (bool, error) {
+	bbl, err := byCharacterIdEntityProvider(tenantId, targetId)(db)()
+	if err != nil {
+		return false, err
+	}
+
+	var meAsBuddy *buddy.Entity
+	for _, pm := range bbl.Buddies {
+		if pm.CharacterId == characterId {
+			meAsBuddy = &pm
+		}
+	}
+	if meAsBuddy == nil {
+		return false, nil
+	}
+	meAsBuddy.MapId = mapId
 
 	err = db.Save(meAsBuddy).Error
 	if err != nil {
@@ -113,6 +160,105 @@ func updateBuddyShopStatus(db *gorm.DB, tenantId uuid.UUID, characterId uint32,
 	return true, nil
 }
 
+// updateBuddyPresence sets the whole presence of characterId, as shown on the buddy list of targetId. False is returned
+// when targetId does not hold characterId.
+func updateBuddyPresence(db *gorm.DB, tenantId uuid.UUID, characterId uint32, targetId uint32, worldId byte, channelId int8, mapId uint32, inShop bool, lastSeen time.Time) (bool, error) {
+	bbl, err := byCharacterIdEntityProvider(tenantId, targetId)(db)()
+	if err != nil {
+		return false, err
+	}
+
+	var meAsBuddy *buddy.Entity
+	for _, pm := range bbl.Buddies {
+		if pm.CharacterId == characterId {
+			meAsBuddy = &pm
+		}
+	}
+	if meAsBuddy == nil {
+		return false, nil
+	}
+	meAsBuddy.WorldId = &worldId
+	meAsBuddy.ChannelId = channelId
+	meAsBuddy.MapId = mapId
+	meAsBuddy.InShop = inShop
+	if !lastSeen.IsZero() {
+		meAsBuddy.LastSeen = &lastSeen
+	}
+
+	err = db.Save(meAsBuddy).Error
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// recordPresence records the channel and map a character is actually on. A character going offline also leaves the
+// cash shop.
+func recordPresence(db *gorm.DB, tenantId uuid.UUID, characterId uint32, worldId byte, channelId int8, mapId uint32) error {
+	e := presence2.Entity{
+		TenantId:    tenantId,
+		CharacterId: characterId,
+		WorldId:     worldId,
+		ChannelId:   channelId,
+		MapId:       mapId,
+	}
+	columns := []string{"world_id", "channel_id", "map_id", "updated_at"}
+	if channelId < 0 {
+		columns = append(columns, "in_shop")
+	}
+	return db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "tenant_id"}, {Name: "character_id"}},
+		DoUpdates: clause.AssignmentColumns(columns),
+	}).Create(&e).Error
+}
+
+// recordMap records the map a character is actually on.
+func recordMap(db *gorm.DB, tenantId uuid.UUID, characterId uint32, mapId uint32) error {
+	return db.Model(&presence2.Entity{}).Where("tenant_id = ? AND character_id = ?", tenantId, characterId).Update("map_id", mapId).Error
+}
+
+// recordShopStatus records whether a character is actually in the cash shop.
+func recordShopStatus(db *gorm.DB, tenantId uuid.UUID, characterId uint32, inShop bool) error {
+	return db.Model(&presence2.Entity{}).Where("tenant_id = ? AND character_id = ?", tenantId, characterId).Update("in_shop", inShop).Error
+}
+
+// clearRecordedPresence records every character of the tenant on the given world channel as offline.
+func clearRecordedPresence(db *gorm.DB, tenantId uuid.UUID, worldId byte, channelId byte) error {
+	return db.Model(&presence2.Entity{}).
+		Where("tenant_id = ? AND world_id = ? AND channel_id = ?", tenantId, worldId, int8(channelId)).
+		Updates(map[string]interface{}{"channel_id": -1, "in_shop": false}).Error
+}
+
+type presence struct {
+	OwnerId     uint32
+	CharacterId uint32
+}
+
+// clearChannelPresence marks every buddy in the tenant present on the given world channel as offline. The affected
+// (buddy list owner, buddy) pairs are returned so the owners can be notified.
+func clearChannelPresence(db *gorm.DB, tenantId uuid.UUID, worldId byte, channelId byte, lastSeen time.Time) ([]presence, error) {
+	var results []presence
+	err := db.Table("buddies").
+		Select("lists.character_id AS owner_id, buddies.character_id AS character_id").
+		Joins("JOIN lists ON lists.id = buddies.list_id").
+		Where("lists.tenant_id = ? AND (buddies.world_id = ? OR buddies.world_id IS NULL) AND buddies.channel_id = ?", tenantId, worldId, int8(channelId)).
+		Scan(&results).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to find present buddies: %w", err)
+	}
+	if len(results) == 0 {
+		return results, nil
+	}
+
+	err = db.Model(&buddy.Entity{}).
+		Where("(world_id = ? OR world_id IS NULL) AND channel_id = ? AND list_id IN (?)", worldId, int8(channelId), db.Model(&Entity{}).Select("id").Where("tenant_id = ?", tenantId)).
+		Updates(map[string]interface{}{"channel_id": -1, "in_shop": false, "last_seen": lastSeen}).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to clear buddy presence: %w", err)
+	}
+	return results, nil
+}
+
 func deleteEntityWithBuddies(db *gorm.DB, tenantId uuid.UUID, characterId uint32) error {
 	var entity Entity
 
@@ -133,7 +279,14 @@ func deleteEntityWithBuddies(db *gorm.DB, tenantId uuid.UUID, characterId uint32
 		return fmt.Errorf("failed to delete buddies: %w", err)
 	}
 
-	// Step 3: Delete the Entity
+	// Step 3: Delete the change feed, as a recreated list starts over at version 1
+	if err := db.
+		Where("tenant_id = ? AND character_id = ?", tenantId, characterId).
+		Delete(&change.Entity{}).Error; err != nil {
+		return fmt.Errorf("failed to delete changes: %w", err)
+	}
+
+	// Step 4: Delete the Entity
 	if err := db.Delete(&entity).Error; err != nil {
 		return fmt.Errorf("failed to delete entity: %w", err)
 	}
@@ -173,7 +326,153 @@ func updateCapacity(db *gorm.DB, tenantId uuid.UUID, characterId uint32, newCapa
 		return errors.New("INVALID_CAPACITY")
 	}
 
-	// Update the capacity
-	entity.Capacity = newCapacity
-	return db.Save(&entity).Error
+	// Update the capacity. Only the capacity column is written, so a version incremented earlier in the transaction is kept.
+	return db.Model(&Entity{}).
+		Where("tenant_id = ? AND character_id = ?", tenantId, characterId).
+		Update("capacity", newCapacity).Error
+}
+
+// setCapacity sets the buddy list capacity for a character without comparing it to the current capacity.
+// Callers are responsible for ensuring the list fits within the new capacity.
+func setCapacity(db *gorm.DB, tenantId uuid.UUID, characterId uint32, capacity byte) error {
+	return db.Model(&Entity{}).
+		Where("tenant_id = ? AND character_id = ?", tenantId, characterId).
+		Update("capacity", capacity).Error
+}
+
+// removeNewestPendingBuddies removes up to count pending entries from the list, newest first. The character ids of the
+// removed entries are returned.
+func removeNewestPendingBuddies(db *gorm.DB, listId uuid.UUID, count int) ([]uint32, error) {
+	var rbs []buddy.Entity
+	err := db.Where("list_id = ? AND pending = ?", listId, true).
+		// Entries added before creation was recorded have no created_at, and are older than any which do.
+		Order("created_at DESC NULLS LAST").
+		Order("character_id DESC").
+		Limit(count).
+		Find(&rbs).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to find pending buddies: %w", err)
+	}
+
+	ids := make([]uint32, 0, len(rbs))
+	for _, rb := range rbs {
+		if err = db.Delete(&rb).Error; err != nil {
+			return nil, fmt.Errorf("failed to delete pending buddy: %w", err)
+		}
+		ids = append(ids, rb.CharacterId)
+	}
+	return ids, nil
+}
+
+// recordGrant records that the capacity item identified by transactionId has been applied. The tenant and
+// transaction id form the primary key, so recording the same transaction twice fails.
+func recordGrant(db *gorm.DB, tenantId uuid.UUID, transactionId string, characterId uint32, itemId uint32, increment byte) error {
+	return db.Create(&grant.Entity{
+		TenantId:      tenantId,
+		TransactionId: transactionId,
+		CharacterId:   characterId,
+		ItemId:        itemId,
+		Increment:     increment,
+	}).Error
+}
+
+// recordCapacityChange appends an entry to the capacity change ledger.
+func recordCapacityChange(db *gorm.DB, tenantId uuid.UUID, characterId uint32, oldCapacity byte, newCapacity byte, source string) error {
+	return db.Create(&ledger.Entity{
+		Id:          uuid.New(),
+		TenantId:    tenantId,
+		CharacterId: characterId,
+		OldCapacity: oldCapacity,
+		NewCapacity: newCapacity,
+		Source:      source,
+		CreatedAt:   time.Now().UTC(),
+	}).Error
+}
+
+// recordAudit appends an entry to the audit log. The before and after snapshots are stored as JSON, and a nil snapshot
+// is stored as empty.
+func recordAudit(db *gorm.DB, tenantId uuid.UUID, actorId uint32, subjectId uint32, action string, before interface{}, after interface{}, commandType string, traceId string) error {
+	bs, err := auditSnapshot(before)
+	if err != nil {
+		return err
+	}
+	as, err := auditSnapshot(after)
+	if err != nil {
+		return err
+	}
+	return db.Create(&audit.Entity{
+		Id:          uuid.New(),
+		TenantId:    tenantId,
+		ActorId:     actorId,
+		SubjectId:   subjectId,
+		Action:      action,
+		Before:      bs,
+		After:       as,
+		CommandType: commandType,
+		TraceId:     traceId,
+		CreatedAt:   time.Now().UTC(),
+	}).Error
+}
+
+func auditSnapshot(v interface{}) (string, error) {
+	if v == nil {
+		return "", nil
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal audit snapshot: %w", err)
+	}
+	return string(b), nil
+}
+
+// incrementVersion increments the version of the character's buddy list and returns the new version. When expected is
+// not nil the version is only incremented while the list is still at that version, and ErrVersionConflict is returned
+// otherwise.
+func incrementVersion(db *gorm.DB, tenantId uuid.UUID, characterId uint32, expected *uint32) (uint32, error) {
+	q := db.Model(&Entity{}).Where("tenant_id = ? AND character_id = ?", tenantId, characterId)
+	if expected != nil {
+		q = q.Where("version = ?", *expected)
+	}
+	res := q.UpdateColumn("version", gorm.Expr("version + ?", 1))
+	if res.Error != nil {
+		return 0, res.Error
+	}
+	if res.RowsAffected == 0 {
+		if expected != nil {
+			return 0, ErrVersionConflict
+		}
+		return 0, gorm.ErrRecordNotFound
+	}
+
+	e, err := byCharacterIdWithoutBuddiesEntityProvider(tenantId, characterId)(db)()
+	if err != nil {
+		return 0, err
+	}
+	return e.Version, nil
+}
+
+// recordChange appends the change which advanced the character's buddy list to version to its change feed. The data
+// snapshot is stored as JSON, and a nil snapshot is stored as empty. Changes older than the retention window are
+// compacted away.
+func recordChange(db *gorm.DB, tenantId uuid.UUID, characterId uint32, version uint32, changeType string, buddyId uint32, data interface{}) error {
+	ds, err := auditSnapshot(data)
+	if err != nil {
+		return err
+	}
+	err = db.Create(&change.Entity{
+		TenantId:    tenantId,
+		CharacterId: characterId,
+		Version:     version,
+		Type:        changeType,
+		BuddyId:     buddyId,
+		Data:        ds,
+		CreatedAt:   time.Now().UTC(),
+	}).Error
+	if err != nil {
+		return err
+	}
+	if version <= change.Retention {
+		return nil
+	}
+	return db.Where("tenant_id = ? AND character_id = ? AND version <= ?", tenantId, characterId, version-change.Retention).Delete(&change.Entity{}).Error
 }
@@ -1,8 +1,13 @@
 package list
 
 import (
+	"atlas-buddies/audit"
+	"atlas-buddies/buddy"
+	"atlas-buddies/change"
+	"atlas-buddies/ledger"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
 	"gorm.io/driver/sqlite"
@@ -21,7 +26,10 @@ func setupTestDB() (*gorm.DB, error) {
 			tenant_id TEXT NOT NULL,
 			id TEXT PRIMARY KEY,
 			character_id INTEGER NOT NULL,
-			capacity INTEGER NOT NULL
+			world_id INTEGER,
+			capacity INTEGER NOT NULL,
+			version INTEGER NOT NULL DEFAULT 0,
+			UNIQUE (tenant_id, character_id)
 		)
 	`).Error
 	if err != nil {
@@ -34,7 +42,11 @@ func setupTestDB() (*gorm.DB, error) {
 			list_id TEXT NOT NULL,
 			"group" TEXT NOT NULL,
 			character_name TEXT NOT NULL,
+			world_id INTEGER,
 			channel_id INTEGER NOT NULL DEFAULT -1,
+			map_id INTEGER NOT NULL DEFAULT 0,
+			last_seen DATETIME,
+			created_at DATETIME,
 			in_shop BOOLEAN NOT NULL DEFAULT false,
 			pending BOOLEAN NOT NULL DEFAULT false
 		)
@@ -43,6 +55,102 @@ func setupTestDB() (*gorm.DB, error) {
 		return nil, err
 	}
 
+	err = db.Exec(`
+		CREATE TABLE capacity_grants (
+			tenant_id TEXT NOT NULL,
+			transaction_id TEXT NOT NULL,
+			character_id INTEGER NOT NULL,
+			item_id INTEGER NOT NULL,
+			increment INTEGER NOT NULL,
+			created_at DATETIME,
+			PRIMARY KEY (tenant_id, transaction_id)
+		)
+	`).Error
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.Exec(`
+		CREATE TABLE capacity_changes (
+			id TEXT PRIMARY KEY,
+			tenant_id TEXT NOT NULL,
+			character_id INTEGER NOT NULL,
+			old_capacity INTEGER NOT NULL,
+			new_capacity INTEGER NOT NULL,
+			source TEXT NOT NULL,
+			created_at DATETIME NOT NULL
+		)
+	`).Error
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.Exec(`
+		CREATE TABLE audits (
+			id TEXT PRIMARY KEY,
+			tenant_id TEXT NOT NULL,
+			actor_id INTEGER NOT NULL,
+			subject_id INTEGER NOT NULL,
+			action TEXT NOT NULL,
+			before TEXT,
+			after TEXT,
+			command_type TEXT,
+			trace_id TEXT,
+			created_at DATETIME NOT NULL
+		)
+	`).Error
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.Exec(`
+		CREATE TABLE buddy_list_changes (
+			tenant_id TEXT NOT NULL,
+			character_id INTEGER NOT NULL,
+			version INTEGER NOT NULL,
+			type TEXT NOT NULL,
+			buddy_id INTEGER NOT NULL,
+			data TEXT,
+			created_at DATETIME NOT NULL,
+			PRIMARY KEY (tenant_id, character_id, version)
+		)
+	`).Error
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.Exec(`
+		CREATE TABLE character_settings (
+			tenant_id TEXT NOT NULL,
+			character_id INTEGER NOT NULL,
+			whisper_policy TEXT NOT NULL DEFAULT 'EVERYONE',
+			decline_requests BOOLEAN NOT NULL DEFAULT false,
+			visibility TEXT NOT NULL DEFAULT 'VISIBLE',
+			hidden_groups TEXT NOT NULL DEFAULT '',
+			updated_at DATETIME,
+			PRIMARY KEY (tenant_id, character_id)
+		)
+	`).Error
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.Exec(`
+		CREATE TABLE character_presence (
+			tenant_id TEXT NOT NULL,
+			character_id INTEGER NOT NULL,
+			world_id INTEGER NOT NULL DEFAULT 0,
+			channel_id INTEGER NOT NULL DEFAULT -1,
+			map_id INTEGER NOT NULL DEFAULT 0,
+			in_shop BOOLEAN NOT NULL DEFAULT false,
+			updated_at DATETIME,
+			PRIMARY KEY (tenant_id, character_id)
+		)
+	`).Error
+	if err != nil {
+		return nil, err
+	}
+
 	return db, nil
 }
 
@@ -129,4 +237,476 @@ func TestUpdateCapacity(t *testing.T) {
 			}
 		})
 	}
-}
\ No newline at end of file
+}
+func TestUpdateBuddyLocation(t *testing.T) {
+	db, err := setupTestDB()
+	if err != nil {
+		t.Fatalf("Failed to setup test database: %v", err)
+	}
+
+	tenantId := uuid.New()
+	characterId := uint32(1)
+	buddyId := uint32(2)
+
+	for _, cid := range []uint32{characterId, buddyId} {
+		err = db.Create(&Entity{TenantId: tenantId, Id: uuid.New(), CharacterId: cid, Capacity: 20}).Error
+		if err != nil {
+			t.Fatalf("Failed to create test entity: %v", err)
+		}
+	}
+	err = addBuddy(db, tenantId, buddyId, characterId, "Owner", "Default Group", false)
+	if err != nil {
+		t.Fatalf("Failed to add buddy: %v", err)
+	}
+
+	seen := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	updated, err := updateBuddyChannel(db, tenantId, characterId, buddyId, 0, 1, 100000000, seen)
+	if err != nil || !updated {
+		t.Fatalf("Expected channel update to succeed, updated [%t] err [%v]", updated, err)
+	}
+
+	updated, err = updateBuddyMap(db, tenantId, characterId, buddyId, 104000000)
+	if err != nil || !updated {
+		t.Fatalf("Expected map update to succeed, updated [%t] err [%v]", updated, err)
+	}
+
+	e, err := byCharacterIdEntityProvider(tenantId, buddyId)(db)()
+	if err != nil {
+		t.Fatalf("Failed to retrieve buddy list: %v", err)
+	}
+	if len(e.Buddies) != 1 {
+		t.Fatalf("Expected 1 buddy, got %d", len(e.Buddies))
+	}
+	if e.Buddies[0].ChannelId != 1 || e.Buddies[0].MapId != 104000000 {
+		t.Errorf("Expected channel 1 map 104000000, got channel %d map %d", e.Buddies[0].ChannelId, e.Buddies[0].MapId)
+	}
+	if e.Buddies[0].LastSeen == nil || !e.Buddies[0].LastSeen.Equal(seen) {
+		t.Errorf("Expected last seen %v, got %v", seen, e.Buddies[0].LastSeen)
+	}
+
+	updated, err = updateBuddyMap(db, tenantId, buddyId, characterId, 104000000)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if updated {
+		t.Errorf("Expected no update for character not on buddy list")
+	}
+}
+
+func TestClearChannelPresence(t *testing.T) {
+	db, err := setupTestDB()
+	if err != nil {
+		t.Fatalf("Failed to setup test database: %v", err)
+	}
+
+	tenantId := uuid.New()
+	otherTenantId := uuid.New()
+	ownerListId := uuid.New()
+	otherListId := uuid.New()
+	err = db.Create(&Entity{TenantId: tenantId, Id: ownerListId, CharacterId: 1, Capacity: 20}).Error
+	if err != nil {
+		t.Fatalf("Failed to create test entity: %v", err)
+	}
+	err = db.Create(&Entity{TenantId: otherTenantId, Id: otherListId, CharacterId: 1, Capacity: 20}).Error
+	if err != nil {
+		t.Fatalf("Failed to create test entity: %v", err)
+	}
+
+	world0 := byte(0)
+	world1 := byte(1)
+	buddies := []buddy.Entity{
+		{CharacterId: 2, ListId: ownerListId, Group: "Friends", CharacterName: "OnChannel", WorldId: &world0, ChannelId: 1, InShop: true},
+		{CharacterId: 3, ListId: ownerListId, Group: "Friends", CharacterName: "OtherChannel", WorldId: &world0, ChannelId: 2},
+		{CharacterId: 4, ListId: ownerListId, Group: "Friends", CharacterName: "OtherWorld", WorldId: &world1, ChannelId: 1},
+		{CharacterId: 5, ListId: otherListId, Group: "Friends", CharacterName: "OtherTenant", WorldId: &world0, ChannelId: 1},
+		{CharacterId: 6, ListId: ownerListId, Group: "Friends", CharacterName: "Legacy", ChannelId: 1},
+	}
+	for _, b := range buddies {
+		if err = db.Create(&b).Error; err != nil {
+			t.Fatalf("Failed to create buddy: %v", err)
+		}
+	}
+
+	now := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	ps, err := clearChannelPresence(db, tenantId, 0, 1, now)
+	if err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+	if len(ps) != 2 {
+		t.Fatalf("Expected presence of buddies 2 and 6 on list of 1 to be cleared, got %+v", ps)
+	}
+	for _, p := range ps {
+		if p.OwnerId != 1 || (p.CharacterId != 2 && p.CharacterId != 6) {
+			t.Errorf("Expected presence of buddies 2 and 6 on list of 1 to be cleared, got %+v", p)
+		}
+	}
+
+	var results []buddy.Entity
+	if err = db.Order("character_id").Find(&results).Error; err != nil {
+		t.Fatalf("Failed to retrieve buddies: %v", err)
+	}
+	expected := map[uint32]int8{2: -1, 3: 2, 4: 1, 5: 1, 6: -1}
+	for _, r := range results {
+		if r.ChannelId != expected[r.CharacterId] {
+			t.Errorf("Expected buddy %d on channel %d, got %d", r.CharacterId, expected[r.CharacterId], r.ChannelId)
+		}
+		if r.CharacterId == 2 && r.InShop {
+			t.Errorf("Expected buddy 2 to no longer be in shop")
+		}
+	}
+}
+
+func TestUpdateWorld(t *testing.T) {
+	db, err := setupTestDB()
+	if err != nil {
+		t.Fatalf("Failed to setup test database: %v", err)
+	}
+
+	tenantId := uuid.New()
+	characterId := uint32(12345)
+	err = db.Create(&Entity{TenantId: tenantId, Id: uuid.New(), CharacterId: characterId, Capacity: 20}).Error
+	if err != nil {
+		t.Fatalf("Failed to create test entity: %v", err)
+	}
+
+	e, err := byCharacterIdEntityProvider(tenantId, characterId)(db)()
+	if err != nil {
+		t.Fatalf("Failed to retrieve entity: %v", err)
+	}
+	m, err := Make(e)
+	if err != nil {
+		t.Fatalf("Failed to make model: %v", err)
+	}
+	if m.WorldKnown() {
+		t.Fatalf("Expected world to be unknown before backfill")
+	}
+
+	err = updateWorld(db, tenantId, characterId, 3)
+	if err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+
+	e, err = byCharacterIdEntityProvider(tenantId, characterId)(db)()
+	if err != nil {
+		t.Fatalf("Failed to retrieve entity: %v", err)
+	}
+	m, err = Make(e)
+	if err != nil {
+		t.Fatalf("Failed to make model: %v", err)
+	}
+	if !m.WorldKnown() || m.WorldId() != 3 {
+		t.Errorf("Expected world 3 after backfill, got known [%t] world [%d]", m.WorldKnown(), m.WorldId())
+	}
+}
+
+func TestRemoveNewestPendingBuddies(t *testing.T) {
+	db, err := setupTestDB()
+	if err != nil {
+		t.Fatalf("Failed to setup test database: %v", err)
+	}
+
+	listId := uuid.New()
+	now := time.Now()
+	buddies := []buddy.Entity{
+		{CharacterId: 2, ListId: listId, CharacterName: "Old", Pending: true, CreatedAt: now.Add(-3 * time.Hour)},
+		{CharacterId: 3, ListId: listId, CharacterName: "Confirmed", Pending: false, CreatedAt: now},
+		{CharacterId: 4, ListId: listId, CharacterName: "Newest", Pending: true, CreatedAt: now.Add(-1 * time.Hour)},
+		{CharacterId: 5, ListId: listId, CharacterName: "Newer", Pending: true, CreatedAt: now.Add(-2 * time.Hour)},
+		{CharacterId: 6, ListId: listId, CharacterName: "Legacy", Pending: true},
+	}
+	for _, b := range buddies {
+		if err = db.Create(&b).Error; err != nil {
+			t.Fatalf("Failed to create buddy: %v", err)
+		}
+	}
+	err = db.Model(&buddy.Entity{}).Where("character_id = ?", 6).Update("created_at", nil).Error
+	if err != nil {
+		t.Fatalf("Failed to clear creation time: %v", err)
+	}
+
+	removed, err := removeNewestPendingBuddies(db, listId, 2)
+	if err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+	if len(removed) != 2 || removed[0] != 4 || removed[1] != 5 {
+		t.Fatalf("Expected buddies [4 5] to be removed, got %v", removed)
+	}
+
+	var remaining []buddy.Entity
+	if err = db.Order("character_id").Find(&remaining).Error; err != nil {
+		t.Fatalf("Failed to retrieve buddies: %v", err)
+	}
+	if len(remaining) != 3 || remaining[0].CharacterId != 2 || remaining[1].CharacterId != 3 || remaining[2].CharacterId != 6 {
+		t.Errorf("Expected buddies 2, 3 and 6 to remain, got %+v", remaining)
+	}
+}
+
+func TestSetCapacity(t *testing.T) {
+	db, err := setupTestDB()
+	if err != nil {
+		t.Fatalf("Failed to setup test database: %v", err)
+	}
+
+	tenantId := uuid.New()
+	characterId := uint32(12345)
+	err = db.Create(&Entity{TenantId: tenantId, Id: uuid.New(), CharacterId: characterId, Capacity: 50}).Error
+	if err != nil {
+		t.Fatalf("Failed to create test entity: %v", err)
+	}
+
+	err = setCapacity(db, tenantId, characterId, 20)
+	if err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+
+	e, err := byCharacterIdEntityProvider(tenantId, characterId)(db)()
+	if err != nil {
+		t.Fatalf("Failed to retrieve entity: %v", err)
+	}
+	if e.Capacity != 20 {
+		t.Errorf("Expected capacity 20, got %d", e.Capacity)
+	}
+}
+
+func TestIncrementVersion(t *testing.T) {
+	db, err := setupTestDB()
+	if err != nil {
+		t.Fatalf("Failed to setup test database: %v", err)
+	}
+
+	tenantId := uuid.New()
+	characterId := uint32(12345)
+	err = db.Create(&Entity{TenantId: tenantId, Id: uuid.New(), CharacterId: characterId, Capacity: 20, Version: 1}).Error
+	if err != nil {
+		t.Fatalf("Failed to create test entity: %v", err)
+	}
+
+	v, err := incrementVersion(db, tenantId, characterId, nil)
+	if err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+	if v != 2 {
+		t.Errorf("Expected version 2, got %d", v)
+	}
+
+	stale := uint32(1)
+	_, err = incrementVersion(db, tenantId, characterId, &stale)
+	if !errors.Is(err, ErrVersionConflict) {
+		t.Errorf("Expected version conflict, got %v", err)
+	}
+
+	current := uint32(2)
+	v, err = incrementVersion(db, tenantId, characterId, &current)
+	if err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+	if v != 3 {
+		t.Errorf("Expected version 3, got %d", v)
+	}
+
+	_, err = incrementVersion(db, tenantId, 99999, nil)
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("Expected record not found, got %v", err)
+	}
+}
+
+func TestRecordGrant(t *testing.T) {
+	db, err := setupTestDB()
+	if err != nil {
+		t.Fatalf("Failed to setup test database: %v", err)
+	}
+
+	tenantId := uuid.New()
+	transactionId := uuid.New().String()
+
+	_, err = grantByTransactionIdEntityProvider(tenantId, transactionId)(db)()
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Fatalf("Expected grant to not exist, got: %v", err)
+	}
+
+	err = recordGrant(db, tenantId, transactionId, 12345, 5140000, 5)
+	if err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+
+	g, err := grantByTransactionIdEntityProvider(tenantId, transactionId)(db)()
+	if err != nil {
+		t.Fatalf("Expected grant to exist, got: %v", err)
+	}
+	if g.CharacterId != 12345 || g.ItemId != 5140000 || g.Increment != 5 {
+		t.Errorf("Unexpected grant recorded: %+v", g)
+	}
+
+	err = recordGrant(db, tenantId, transactionId, 12345, 5140000, 5)
+	if err == nil {
+		t.Errorf("Expected replayed transaction to be rejected")
+	}
+
+	_, err = grantByTransactionIdEntityProvider(uuid.New(), transactionId)(db)()
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("Expected grant of another tenant to not exist, got: %v", err)
+	}
+}
+
+func TestRecordCapacityChange(t *testing.T) {
+	db, err := setupTestDB()
+	if err != nil {
+		t.Fatalf("Failed to setup test database: %v", err)
+	}
+
+	tenantId := uuid.New()
+	characterId := uint32(12345)
+	changes := []struct {
+		oldCapacity byte
+		newCapacity byte
+		source      string
+	}{
+		{0, 20, ledger.SourceDefault},
+		{20, 25, ledger.SourceCashItem},
+		{25, 15, ledger.SourceAdmin},
+	}
+	for _, c := range changes {
+		if err = recordCapacityChange(db, tenantId, characterId, c.oldCapacity, c.newCapacity, c.source); err != nil {
+			t.Fatalf("Expected no error, but got: %v", err)
+		}
+	}
+	if err = recordCapacityChange(db, uuid.New(), characterId, 0, 30, ledger.SourceDefault); err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+
+	results, err := capacityChangesByCharacterIdEntityProvider(tenantId, characterId, time.Time{}, time.Time{})(db)()
+	if err != nil {
+		t.Fatalf("Failed to retrieve capacity changes: %v", err)
+	}
+	if len(results) != len(changes) {
+		t.Fatalf("Expected %d capacity changes, got %d", len(changes), len(results))
+	}
+	for i, r := range results {
+		if r.OldCapacity != changes[i].oldCapacity || r.NewCapacity != changes[i].newCapacity || r.Source != changes[i].source {
+			t.Errorf("Expected change %d to be %+v, got %+v", i, changes[i], r)
+		}
+	}
+}
+
+func TestRecordAudit(t *testing.T) {
+	db, err := setupTestDB()
+	if err != nil {
+		t.Fatalf("Failed to setup test database: %v", err)
+	}
+
+	tenantId := uuid.New()
+	start := time.Now().UTC()
+	err = recordAudit(db, tenantId, 1, 2, audit.ActionBuddyRemoved, buddy.RestModel{CharacterId: 1, CharacterName: "Actor"}, nil, "REQUEST_DELETE", "abc")
+	if err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+	err = recordAudit(db, tenantId, 3, 3, audit.ActionCapacityChanged, nil, nil, "", "")
+	if err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+
+	results, err := auditsByCharacterIdEntityProvider(tenantId, 2, time.Time{}, time.Time{})(db)()
+	if err != nil {
+		t.Fatalf("Failed to retrieve audits: %v", err)
+	}
+	if len(results) != 1 {
+		t.Fatalf("Expected 1 audit for subject 2, got %d", len(results))
+	}
+	r := results[0]
+	if r.ActorId != 1 || r.Action != audit.ActionBuddyRemoved || r.CommandType != "REQUEST_DELETE" || r.TraceId != "abc" {
+		t.Errorf("Unexpected audit recorded: %+v", r)
+	}
+	if r.Before == "" || r.After != "" {
+		t.Errorf("Expected only a before snapshot, got before [%s] after [%s]", r.Before, r.After)
+	}
+
+	results, err = auditsByCharacterIdEntityProvider(tenantId, 1, time.Time{}, time.Time{})(db)()
+	if err != nil || len(results) != 1 {
+		t.Errorf("Expected 1 audit for actor 1, got %d (%v)", len(results), err)
+	}
+
+	results, err = auditsByCharacterIdEntityProvider(tenantId, 2, start.Add(time.Hour), time.Time{})(db)()
+	if err != nil || len(results) != 0 {
+		t.Errorf("Expected no audits after range start, got %d (%v)", len(results), err)
+	}
+
+	results, err = auditsByCharacterIdEntityProvider(uuid.New(), 2, time.Time{}, time.Time{})(db)()
+	if err != nil || len(results) != 0 {
+		t.Errorf("Expected no audits for another tenant, got %d (%v)", len(results), err)
+	}
+}
+
+func TestRecordChange(t *testing.T) {
+	db, err := setupTestDB()
+	if err != nil {
+		t.Fatalf("Failed to setup test database: %v", err)
+	}
+
+	tenantId := uuid.New()
+	characterId := uint32(12345)
+	for v := uint32(1); v <= change.Retention+2; v++ {
+		err = recordChange(db, tenantId, characterId, v, change.TypePresence, 67890, map[string]int8{"channelId": 1})
+		if err != nil {
+			t.Fatalf("Expected no error, but got: %v", err)
+		}
+	}
+
+	es, err := changesSinceEntityProvider(tenantId, characterId, 0)(db)()
+	if err != nil {
+		t.Fatalf("Failed to retrieve changes: %v", err)
+	}
+	if len(es) != change.Retention {
+		t.Fatalf("Expected %d retained changes, got %d", change.Retention, len(es))
+	}
+	if es[0].Version != 3 {
+		t.Errorf("Expected oldest retained version 3, got %d", es[0].Version)
+	}
+	if es[len(es)-1].Data != `{"channelId":1}` {
+		t.Errorf("Expected data snapshot, got %s", es[len(es)-1].Data)
+	}
+
+	es, err = changesSinceEntityProvider(tenantId, characterId, change.Retention+1)(db)()
+	if err != nil {
+		t.Fatalf("Failed to retrieve changes: %v", err)
+	}
+	if len(es) != 1 || es[0].Version != change.Retention+2 {
+		t.Errorf("Expected only the newest change, got %d changes", len(es))
+	}
+}
+
+func TestMigrationWithDuplicateLists(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to open test database: %v", err)
+	}
+
+	// Lists as created by versions without idx_lists_tenant_character.
+	err = db.Exec(`
+		CREATE TABLE lists (
+			tenant_id TEXT NOT NULL,
+			id TEXT PRIMARY KEY,
+			character_id INTEGER NOT NULL,
+			world_id INTEGER,
+			capacity INTEGER NOT NULL,
+			version INTEGER NOT NULL DEFAULT 0
+		)
+	`).Error
+	if err != nil {
+		t.Fatalf("Failed to create lists table: %v", err)
+	}
+	tenantId := uuid.New()
+	for i := 0; i < 2; i++ {
+		err = db.Exec("INSERT INTO lists (tenant_id, id, character_id, capacity) VALUES (?, ?, ?, ?)", tenantId, uuid.New(), 1, 20).Error
+		if err != nil {
+			t.Fatalf("Failed to insert list: %v", err)
+		}
+	}
+
+	err = Migration(db)
+	if err == nil {
+		t.Fatalf("Expected the migration to refuse duplicate lists")
+	}
+	if db.Migrator().HasIndex(&Entity{}, "idx_lists_tenant_character") {
+		t.Errorf("Expected idx_lists_tenant_character not to be created")
+	}
+}
@@ -2,19 +2,51 @@ package list
 
 import (
 	"atlas-buddies/buddy"
+	"fmt"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
 func Migration(db *gorm.DB) error {
+	err := checkDuplicateLists(db)
+	if err != nil {
+		return err
+	}
 	return db.AutoMigrate(&Entity{})
 }
 
+// checkDuplicateLists guards the creation of idx_lists_tenant_character. Earlier versions did not prevent a character
+// from having more than one buddy list, and which of them to keep is not for the migration to decide, so the extra
+// lists must be removed before the index can be built.
+func checkDuplicateLists(db *gorm.DB) error {
+	if !db.Migrator().HasTable(&Entity{}) || db.Migrator().HasIndex(&Entity{}, "idx_lists_tenant_character") {
+		return nil
+	}
+	var duplicates []struct {
+		TenantId    uuid.UUID
+		CharacterId uint32
+	}
+	err := db.Model(&Entity{}).
+		Select("tenant_id, character_id").
+		Group("tenant_id, character_id").
+		Having("COUNT(*) > 1").
+		Scan(&duplicates).Error
+	if err != nil {
+		return err
+	}
+	if len(duplicates) > 0 {
+		return fmt.Errorf("%d characters have more than one buddy list, e.g. character [%d] of tenant [%s], which must be removed before migrating", len(duplicates), duplicates[0].CharacterId, duplicates[0].TenantId)
+	}
+	return nil
+}
+
 type Entity struct {
-	TenantId    uuid.UUID      `gorm:"not null"`
+	TenantId    uuid.UUID      `gorm:"not null;uniqueIndex:idx_lists_tenant_character"`
 	Id          uuid.UUID      `gorm:"type:uuid;default:uuid_generate_v4()"`
-	CharacterId uint32         `gorm:"not null"`
+	CharacterId uint32         `gorm:"not null;uniqueIndex:idx_lists_tenant_character"`
+	WorldId     *byte          `gorm:"default:null"` // nil for lists created before the world was recorded.
 	Capacity    byte           `gorm:"not null"`
+	Version     uint32         `gorm:"not null;default:0"`
 	Buddies     []buddy.Entity `gorm:"foreignkey:ListId"`
 }
 
@@ -32,11 +64,19 @@ func Make(e Entity) (Model, error) {
 		buddies = append(buddies, b)
 	}
 
+	var worldId byte
+	if e.WorldId != nil {
+		worldId = *e.WorldId
+	}
+
 	return Model{
 		tenantId:    e.TenantId,
 		id:          e.Id,
 		characterId: e.CharacterId,
+		worldId:     worldId,
+		worldKnown:  e.WorldId != nil,
 		capacity:    e.Capacity,
+		version:     e.Version,
 		buddies:     buddies,
 	}, nil
 }
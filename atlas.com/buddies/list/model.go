@@ -9,7 +9,10 @@ type Model struct {
 	tenantId    uuid.UUID
 	id          uuid.UUID
 	characterId uint32
+	worldId     byte
+	worldKnown  bool
 	capacity    byte
+	version     uint32
 	buddies     []buddy.Model
 }
 
@@ -17,6 +20,34 @@ func (m Model) Buddies() []buddy.Model {
 	return m.buddies
 }
 
+// Buddy returns the entry of the given character on the buddy list, if present.
+func (m Model) Buddy(characterId uint32) (buddy.Model, bool) {
+	for _, b := range m.buddies {
+		if b.CharacterId() == characterId {
+			return b, true
+		}
+	}
+	return buddy.Model{}, false
+}
+
 func (m Model) Capacity() byte {
 	return m.capacity
 }
+
+func (m Model) CharacterId() uint32 {
+	return m.characterId
+}
+
+// WorldId is the world of the buddy list owner. Only meaningful when WorldKnown is true.
+func (m Model) WorldId() byte {
+	return m.worldId
+}
+
+func (m Model) WorldKnown() bool {
+	return m.worldKnown
+}
+
+// Version increases with every change to the buddy list.
+func (m Model) Version() uint32 {
+	return m.version
+}
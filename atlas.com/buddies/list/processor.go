@@ -1,29 +1,70 @@
 package list
 
 import (
+	"atlas-buddies/audit"
 	"atlas-buddies/buddy"
+	"atlas-buddies/change"
 	"atlas-buddies/character"
+	"atlas-buddies/configuration"
 	"atlas-buddies/database"
 	"atlas-buddies/invite"
 	"atlas-buddies/kafka/message"
 	list2 "atlas-buddies/kafka/message/list"
 	"atlas-buddies/kafka/producer"
 	list3 "atlas-buddies/kafka/producer/list"
+	"atlas-buddies/ledger"
+	presence2 "atlas-buddies/presence"
+	"atlas-buddies/settings"
 	"context"
 	"errors"
 	"github.com/Chronicle20/atlas-model/model"
 	"github.com/Chronicle20/atlas-tenant"
+	"github.com/segmentio/kafka-go"
 	"github.com/sirupsen/logrus"
 	"gorm.io/gorm"
+	"sort"
+	"time"
 )
 
+var ErrNotMutualBuddy = errors.New("characters are not mutual buddies")
+var ErrInvalidCapacity = errors.New("capacity outside of policy")
+var ErrCapacityOverflow = errors.New("buddy list holds more entries than capacity")
+var ErrVersionConflict = errors.New("buddy list version conflict")
+var ErrListExists = errors.New("buddy list already exists")
+var errListNotFound = errors.New("buddy list does not exist")
+var ErrChangesUnavailable = errors.New("buddy list changes are no longer available")
+
+// ErrRequestDeclined is returned when a buddy request is made to a character which declines all requests.
+var ErrRequestDeclined = errors.New("buddy requests are declined")
+
+// DefaultGroup is the group of buddies added without one, such as when accepting an invite.
+const DefaultGroup = "Default Group"
+
 type Processor interface {
 	WithTransaction(*gorm.DB) Processor
+	// IfMatch returns a processor which only applies commands to a buddy list at the given version. A nil version
+	// applies commands regardless of version.
+	IfMatch(version *uint32) Processor
+	// Correlate returns a processor whose status events carry the given correlation id. An empty correlation id leaves
+	// status events uncorrelated.
+	Correlate(correlationId string) Processor
 	ByCharacterIdProvider(characterId uint32) model.Provider[Model]
 	GetByCharacterId(characterId uint32) (Model, error)
-	Create(characterId uint32, capacity byte) (Model, error)
+	GetByCharacterIds(characterIds []uint32) ([]Model, error)
+	GetRelationship(characterId uint32, otherId uint32) (RelationshipModel, error)
+	GetRelationships(characterId uint32, otherIds []uint32) ([]RelationshipModel, error)
+	GetBuddies(characterId uint32, q buddy.Query) ([]buddy.Model, error)
+	GetVersion(characterId uint32) (uint32, error)
+	GetCapacityHistory(characterId uint32) ([]ledger.Model, error)
+	GetAudits(characterId uint32, from time.Time, to time.Time) ([]audit.Model, error)
+	GetChangesSince(characterId uint32, since uint32) ([]change.Model, error)
+	CreateAndEmit(characterId uint32, worldId byte, capacity byte) (Model, error)
+	Create(mb *message.Buffer) func(characterId uint32, worldId byte, capacity byte) (Model, error)
+	CreateDefault(characterId uint32, worldId byte) (Model, error)
 	DeleteAndEmit(characterId uint32, worldId byte) error
 	Delete(mb *message.Buffer) func(characterId uint32, worldId byte) error
+	PurgeAndEmit(characterId uint32, worldId byte) error
+	Purge(mb *message.Buffer) func(characterId uint32, worldId byte) error
 	RequestAddBuddyAndEmit(characterId uint32, worldId byte, targetId uint32, group string) error
 	RequestAddBuddy(mb *message.Buffer) func(characterId uint32, worldId byte, targetId uint32, group string) error
 	RequestDeleteBuddyAndEmit(characterId uint32, worldId byte, targetId uint32) error
@@ -32,10 +73,21 @@ type Processor interface {
 	AcceptInvite(mb *message.Buffer) func(characterId uint32, worldId byte, targetId uint32) error
 	DeleteBuddyAndEmit(characterId uint32, worldId byte, targetId uint32) error
 	DeleteBuddy(mb *message.Buffer) func(characterId uint32, worldId byte, targetId uint32) error
-	UpdateBuddyChannelAndEmit(characterId uint32, worldId byte, channelId int8) error
-	UpdateBuddyChannel(mb *message.Buffer) func(characterId uint32, worldId byte, channelId int8) error
+	UpdateBuddyChannelAndEmit(characterId uint32, worldId byte, channelId int8, mapId uint32) error
+	UpdateBuddyChannel(mb *message.Buffer) func(characterId uint32, worldId byte, channelId int8, mapId uint32) error
+	UpdateBuddyMap(characterId uint32, mapId uint32) error
+	ClearChannelPresenceAndEmit(worldId byte, channelId byte) error
+	ClearChannelPresence(mb *message.Buffer) func(worldId byte, channelId byte) error
+	SnapshotAndEmit(characterId uint32, worldId byte) error
+	Snapshot(mb *message.Buffer) func(characterId uint32, worldId byte) error
+	GetBuddyLocation(characterId uint32, buddyId uint32) (buddy.Model, error)
 	UpdateBuddyShopStatusAndEmit(characterId uint32, worldId byte, inShop bool) error
 	UpdateBuddyShopStatus(mb *message.Buffer) func(characterId uint32, worldId byte, inShop bool) error
+	// UpdateSettingsAndEmit changes the settings of a character, and shows or hides their presence from each buddy whose
+	// view of it changes as a result.
+	UpdateSettingsAndEmit(characterId uint32, worldId byte, c settings.Change) error
+	// UpdateSettings is the message buffer version of UpdateSettingsAndEmit.
+	UpdateSettings(mb *message.Buffer) func(characterId uint32, worldId byte, c settings.Change) error
 	// IncreaseCapacityAndEmit increases buddy list capacity and emits appropriate status events.
 	// This method validates the new capacity and updates the database in a transaction.
 	// On success, emits a CAPACITY_CHANGE event. On failure, emits an ERROR event.
@@ -44,6 +96,14 @@ type Processor interface {
 	// for transactional event emission. Use this when you need to coordinate multiple
 	// operations within a single transaction.
 	IncreaseCapacity(mb *message.Buffer) func(characterId uint32, worldId byte, newCapacity byte) error
+	// SetCapacityAndEmit sets buddy list capacity, which may lower it, and emits appropriate status events.
+	SetCapacityAndEmit(characterId uint32, worldId byte, capacity byte, overflowPolicy string) error
+	// SetCapacity is the message buffer version of SetCapacityAndEmit.
+	SetCapacity(mb *message.Buffer) func(characterId uint32, worldId byte, capacity byte, overflowPolicy string) error
+	// ApplyCapacityItemAndEmit increases buddy list capacity by the amount the tenant configures for a cash item.
+	ApplyCapacityItemAndEmit(characterId uint32, worldId byte, itemId uint32, transactionId string) error
+	// ApplyCapacityItem is the message buffer version of ApplyCapacityItemAndEmit.
+	ApplyCapacityItem(mb *message.Buffer) func(characterId uint32, worldId byte, itemId uint32, transactionId string) error
 }
 
 type ProcessorImpl struct {
@@ -54,30 +114,59 @@ type ProcessorImpl struct {
 	p   producer.Provider
 	cp  character.Processor
 	ip  invite.Processor
+	sp  settings.Processor
+	c   configuration.Model
+	// ifMatch is the buddy list version commands are required to apply to, if any.
+	ifMatch *uint32
 }
 
 func NewProcessor(l logrus.FieldLogger, ctx context.Context, db *gorm.DB) Processor {
+	t := tenant.MustFromContext(ctx)
 	return &ProcessorImpl{
 		l:   l,
 		ctx: ctx,
 		db:  db,
-		t:   tenant.MustFromContext(ctx),
+		t:   t,
 		p:   producer.ProviderImpl(l)(ctx),
 		cp:  character.NewProcessor(l, ctx),
 		ip:  invite.NewProcessor(l, ctx),
+		sp:  settings.NewProcessor(l, ctx, db),
+		c:   configuration.ForTenant(l, t),
 	}
 }
 
 func (p *ProcessorImpl) WithTransaction(tx *gorm.DB) Processor {
+	return p.withTransaction(tx)
+}
+
+func (p *ProcessorImpl) withTransaction(tx *gorm.DB) *ProcessorImpl {
 	return &ProcessorImpl{
 		l:   p.l,
 		ctx: p.ctx,
 		db:  tx,
 		t:   p.t,
 		p:   p.p,
+		cp:  p.cp,
+		ip:  p.ip,
+		sp:  p.sp.WithTransaction(tx),
+		c:   p.c,
+		// ifMatch is carried so the requirement survives into the transaction.
+		ifMatch: p.ifMatch,
 	}
 }
 
+func (p *ProcessorImpl) IfMatch(version *uint32) Processor {
+	np := p.withTransaction(p.db)
+	np.ifMatch = version
+	return np
+}
+
+func (p *ProcessorImpl) Correlate(correlationId string) Processor {
+	np := p.withTransaction(p.db)
+	np.p = producer.CorrelatedProvider(p.p, correlationId)
+	return np
+}
+
 func (p *ProcessorImpl) ByCharacterIdProvider(characterId uint32) model.Provider[Model] {
 	return model.Map(Make)(byCharacterIdEntityProvider(p.t.Id(), characterId)(p.db))
 }
@@ -86,47 +175,379 @@ func (p *ProcessorImpl) GetByCharacterId(characterId uint32) (Model, error) {
 	return p.ByCharacterIdProvider(characterId)()
 }
 
-func (p *ProcessorImpl) Create(characterId uint32, capacity byte) (Model, error) {
-	p.l.Debugf("Creating buddy list for character [%d] with a capacity of [%d].", characterId, capacity)
-	m, err := create(p.db, p.t, characterId, capacity)
+// GetByCharacterIds retrieves the buddy lists of many characters at once, in the order requested. Characters without a
+// buddy list are omitted, as are repeated characters.
+func (p *ProcessorImpl) GetByCharacterIds(characterIds []uint32) ([]Model, error) {
+	es, err := byCharacterIdsEntityProvider(p.t.Id(), characterIds)(p.db)()
+	if err != nil {
+		return nil, err
+	}
+	byCharacterId := make(map[uint32]Model, len(es))
+	for _, e := range es {
+		m, err := Make(e)
+		if err != nil {
+			return nil, err
+		}
+		byCharacterId[m.CharacterId()] = m
+	}
+
+	results := make([]Model, 0, len(byCharacterId))
+	for _, id := range characterIds {
+		if m, ok := byCharacterId[id]; ok {
+			results = append(results, m)
+			delete(byCharacterId, id)
+		}
+	}
+	return results, nil
+}
+
+// GetRelationship retrieves how a character relates to another. A character without a buddy list relates to no one.
+func (p *ProcessorImpl) GetRelationship(characterId uint32, otherId uint32) (RelationshipModel, error) {
+	rs, err := p.GetRelationships(characterId, []uint32{otherId})
+	if err != nil {
+		return RelationshipModel{}, err
+	}
+	return rs[0], nil
+}
+
+// GetRelationships retrieves how a character relates to each of the others, in the order requested. The relationships
+// are answered from the entries the characters hold of each other, without loading their buddy lists, and carry the
+// whisper policy of the character.
+func (p *ProcessorImpl) GetRelationships(characterId uint32, otherIds []uint32) ([]RelationshipModel, error) {
+	es, err := edgesEntityProvider(p.t.Id(), characterId, otherIds)(p.db)()
+	if err != nil {
+		return nil, err
+	}
+	s, err := p.sp.GetByCharacterId(characterId)
+	if err != nil {
+		return nil, err
+	}
+	outbound := make(map[uint32]*bool)
+	inbound := make(map[uint32]*bool)
+	for _, e := range es {
+		pending := e.Pending
+		if e.OwnerId == characterId {
+			outbound[e.BuddyId] = &pending
+		} else {
+			inbound[e.OwnerId] = &pending
+		}
+	}
+
+	results := make([]RelationshipModel, 0, len(otherIds))
+	for _, id := range otherIds {
+		results = append(results, RelationshipModel{characterId: characterId, otherId: id, relationship: relationshipOf(outbound[id], inbound[id]), settings: s})
+	}
+	return results, nil
+}
+
+// GetBuddies retrieves the buddies on a character's buddy list matching the query. Filtering, ordering and paging are
+// applied by the database.
+func (p *ProcessorImpl) GetBuddies(characterId uint32, q buddy.Query) ([]buddy.Model, error) {
+	e, err := byCharacterIdWithoutBuddiesEntityProvider(p.t.Id(), characterId)(p.db)()
 	if err != nil {
-		p.l.WithError(err).Errorf("Unable to create initial buddy list for character [%d].", characterId)
-		return Model{}, err
+		return nil, err
+	}
+	return model.SliceMap(buddy.Make)(buddiesByListIdEntityProvider(e.Id, q)(p.db))()()
+}
+
+// GetVersion retrieves the version of a character's buddy list, without loading its buddies.
+func (p *ProcessorImpl) GetVersion(characterId uint32) (uint32, error) {
+	e, err := byCharacterIdWithoutBuddiesEntityProvider(p.t.Id(), characterId)(p.db)()
+	if err != nil {
+		return 0, err
+	}
+	return e.Version, nil
+}
+
+// GetCapacityHistory retrieves the capacity change ledger of a character's buddy list, oldest first.
+func (p *ProcessorImpl) GetCapacityHistory(characterId uint32) ([]ledger.Model, error) {
+	_, err := byCharacterIdWithoutBuddiesEntityProvider(p.t.Id(), characterId)(p.db)()
+	if err != nil {
+		return nil, err
+	}
+	return model.SliceMap(ledger.Make)(capacityChangesByCharacterIdEntityProvider(p.t.Id(), characterId, time.Time{}, time.Time{})(p.db))()()
+}
+
+// GetAudits retrieves the audit log entries in which the character is either the actor or the subject, within the
+// [from, to) time range, together with the capacity ledger entries of the character as CAPACITY_RECORDED entries, oldest
+// first. Zero times leave the range open. Entries outlive the buddy list itself.
+func (p *ProcessorImpl) GetAudits(characterId uint32, from time.Time, to time.Time) ([]audit.Model, error) {
+	as, err := auditsByCharacterIdEntityProvider(p.t.Id(), characterId, from, to)(p.db)()
+	if err != nil {
+		return nil, err
+	}
+	ls, err := capacityChangesByCharacterIdEntityProvider(p.t.Id(), characterId, from, to)(p.db)()
+	if err != nil {
+		return nil, err
+	}
+	for _, le := range ls {
+		ae, err := capacityRecordedAudit(le)
+		if err != nil {
+			return nil, err
+		}
+		as = append(as, ae)
+	}
+	sort.SliceStable(as, func(i, j int) bool {
+		return as[i].CreatedAt.Before(as[j].CreatedAt)
+	})
+	return model.SliceMap(audit.Make)(model.FixedProvider(as))()()
+}
+
+// capacityRecordedAudit presents a capacity ledger entry as an entry of the audit log, so audit exports carry the ledger.
+func capacityRecordedAudit(e ledger.Entity) (audit.Entity, error) {
+	before, err := auditSnapshot(capacitySnapshot{Capacity: e.OldCapacity})
+	if err != nil {
+		return audit.Entity{}, err
+	}
+	after, err := auditSnapshot(capacitySnapshot{Capacity: e.NewCapacity, Source: e.Source})
+	if err != nil {
+		return audit.Entity{}, err
+	}
+	return audit.Entity{
+		Id:        e.Id,
+		TenantId:  e.TenantId,
+		ActorId:   e.CharacterId,
+		SubjectId: e.CharacterId,
+		Action:    audit.ActionCapacityRecorded,
+		Before:    before,
+		After:     after,
+		CreatedAt: e.CreatedAt,
+	}, nil
+}
+
+// GetChangesSince retrieves the changes of a character's buddy list after the given version, in version order. When the
+// changes are no longer complete, because they were compacted away or the version belongs to a list which has since
+// been recreated, ErrChangesUnavailable is returned and the client must reload the list.
+func (p *ProcessorImpl) GetChangesSince(characterId uint32, since uint32) ([]change.Model, error) {
+	var results []change.Model
+	txErr := database.ExecuteTransaction(p.db, func(tx *gorm.DB) error {
+		e, err := byCharacterIdWithoutBuddiesEntityProvider(p.t.Id(), characterId)(tx)()
+		if err != nil {
+			return err
+		}
+		if since > e.Version {
+			return ErrChangesUnavailable
+		}
+		cs, err := model.SliceMap(change.Make)(changesSinceEntityProvider(p.t.Id(), characterId, since)(tx))()()
+		if err != nil {
+			return err
+		}
+		if uint32(len(cs)) != e.Version-since || (len(cs) > 0 && cs[0].Version() != since+1) {
+			return ErrChangesUnavailable
+		}
+		results = cs
+		return nil
+	})
+	if txErr != nil {
+		return nil, txErr
+	}
+	return results, nil
+}
+
+// CreateDefault creates a buddy list with the default capacity of the tenant capacity policy.
+func (p *ProcessorImpl) CreateDefault(characterId uint32, worldId byte) (Model, error) {
+	return p.create(characterId, worldId, p.c.Capacity().Default(), ledger.SourceDefault)
+}
+
+// CreateAndEmit creates a buddy list with the given capacity and emits status events. See Create.
+func (p *ProcessorImpl) CreateAndEmit(characterId uint32, worldId byte, capacity byte) (Model, error) {
+	var m Model
+	var err error
+	emitErr := message.Emit(p.p)(func(buf *message.Buffer) error {
+		m, err = p.Create(buf)(characterId, worldId, capacity)
+		if errors.Is(err, ErrInvalidCapacity) {
+			// The rejection is reported through the buffered INVALID_CAPACITY error.
+			return nil
+		}
+		return err
+	})
+	if emitErr != nil {
+		return Model{}, emitErr
+	}
+	return m, err
+}
+
+// Create returns a curried function that creates a buddy list with the given capacity. A capacity of zero uses the
+// tenant default. A capacity beyond the tenant maximum is rejected with ErrInvalidCapacity, and an INVALID_CAPACITY
+// error is emitted.
+func (p *ProcessorImpl) Create(mb *message.Buffer) func(characterId uint32, worldId byte, capacity byte) (Model, error) {
+	return func(characterId uint32, worldId byte, capacity byte) (Model, error) {
+		if capacity == 0 {
+			return p.CreateDefault(characterId, worldId)
+		}
+		m, err := p.create(characterId, worldId, capacity, ledger.SourceCommand)
+		if errors.Is(err, ErrInvalidCapacity) {
+			_ = mb.Put(list2.EnvStatusEventTopic, list3.ErrorStatusEventProvider(characterId, worldId, list2.StatusEventErrorInvalidCapacity))
+		}
+		return m, err
+	}
+}
+
+func (p *ProcessorImpl) create(characterId uint32, worldId byte, capacity byte, source string) (Model, error) {
+	if !p.c.Capacity().Allows(capacity) {
+		p.l.Infof("Capacity [%d] for character [%d] buddy list exceeds maximum [%d].", capacity, characterId, p.c.Capacity().Maximum())
+		return Model{}, ErrInvalidCapacity
+	}
+	p.l.Debugf("Creating buddy list for character [%d] in world [%d] with a capacity of [%d].", characterId, worldId, capacity)
+	var m Model
+	txErr := database.ExecuteTransaction(p.db, func(tx *gorm.DB) error {
+		var err error
+		m, err = create(tx, p.t, characterId, worldId, capacity)
+		if err != nil {
+			return err
+		}
+		err = recordCapacityChange(tx, p.t.Id(), characterId, 0, capacity, source)
+		if err != nil {
+			return err
+		}
+		return p.auditMutation(tx, characterId, characterId, audit.ActionListCreated, nil, listSnapshot(m))
+	})
+	if errors.Is(txErr, gorm.ErrDuplicatedKey) {
+		p.l.Infof("Character [%d] already has a buddy list.", characterId)
+		return Model{}, ErrListExists
+	}
+	if txErr != nil {
+		p.l.WithError(txErr).Errorf("Unable to create initial buddy list for character [%d].", characterId)
+		return Model{}, txErr
 	}
 	return m, nil
 }
 
+// worldOf returns the world of the buddy list owner, backfilling it from the character service for lists created
+// before the world was recorded.
+func (p *ProcessorImpl) worldOf(tx *gorm.DB, m Model) (byte, error) {
+	if m.WorldKnown() {
+		return m.WorldId(), nil
+	}
+	c, err := p.cp.GetById(m.CharacterId())
+	if err != nil {
+		return 0, err
+	}
+	p.l.Debugf("Backfilling world [%d] for buddy list of character [%d].", c.WorldId(), m.CharacterId())
+	err = updateWorld(tx, p.t.Id(), m.CharacterId(), c.WorldId())
+	if err != nil {
+		return 0, err
+	}
+	return c.WorldId(), nil
+}
+
+// sameWorld reports whether the owners of both buddy lists are in the same world.
+func (p *ProcessorImpl) sameWorld(tx *gorm.DB, a Model, b Model) (bool, error) {
+	aw, err := p.worldOf(tx, a)
+	if err != nil {
+		return false, err
+	}
+	bw, err := p.worldOf(tx, b)
+	if err != nil {
+		return false, err
+	}
+	return aw == bw, nil
+}
+
 func (p *ProcessorImpl) DeleteAndEmit(characterId uint32, worldId byte) error {
 	return message.Emit(p.p)(func(buf *message.Buffer) error {
 		return p.Delete(buf)(characterId, worldId)
 	})
 }
 
+// Delete deletes the buddy list of a character, removing the character from the lists of their buddies, each of which
+// receives a BUDDY_REMOVED event. The character receives a LIST_DELETED event carrying the version of the deleted list,
+// or an ERROR event when the list does not exist or is not at the version required by IfMatch.
 func (p *ProcessorImpl) Delete(mb *message.Buffer) func(characterId uint32, worldId byte) error {
 	return func(characterId uint32, worldId byte) error {
-		txErr := database.ExecuteTransaction(p.db, func(tx *gorm.DB) error {
-			bl, err := p.WithTransaction(tx).GetByCharacterId(characterId)
+		err := p.delete(mb, characterId, worldId)
+		if errors.Is(err, errListNotFound) {
+			p.l.Infof("Character [%d] has no buddy list to delete.", characterId)
+			return mb.Put(list2.EnvStatusEventTopic, list3.ErrorStatusEventProvider(characterId, worldId, list2.StatusEventErrorCharacterNotFound))
+		}
+		if err != nil {
+			return mb.Put(list2.EnvStatusEventTopic, list3.ErrorStatusEventProvider(characterId, worldId, versionError(err)))
+		}
+		return nil
+	}
+}
+
+func (p *ProcessorImpl) PurgeAndEmit(characterId uint32, worldId byte) error {
+	return message.Emit(p.p)(func(buf *message.Buffer) error {
+		return p.Purge(buf)(characterId, worldId)
+	})
+}
+
+// Purge deletes the buddy list of a character which no longer exists, the same way as Delete. A character without a
+// buddy list has nothing to purge, which is not reported as there is no one left to report it to.
+func (p *ProcessorImpl) Purge(mb *message.Buffer) func(characterId uint32, worldId byte) error {
+	return func(characterId uint32, worldId byte) error {
+		err := p.delete(mb, characterId, worldId)
+		if errors.Is(err, errListNotFound) {
+			p.l.Debugf("Deleted character [%d] had no buddy list.", characterId)
+			return nil
+		}
+		return err
+	}
+}
+
+// delete deletes the buddy list of a character and buffers the resulting events. Buddies which no longer hold the
+// character, because they removed the character on their side or have no buddy list, are skipped. errListNotFound is
+// returned when the character has no buddy list.
+func (p *ProcessorImpl) delete(mb *message.Buffer, characterId uint32, worldId byte) error {
+	// Events are only put once the transaction commits, as none must be sent for a list which was not deleted.
+	var events []model.Provider[[]kafka.Message]
+	var version uint32
+	txErr := database.ExecuteTransaction(p.db, func(tx *gorm.DB) error {
+		bl, err := p.WithTransaction(tx).GetByCharacterId(characterId)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errListNotFound
+		}
+		if err != nil {
+			return err
+		}
+		version = bl.Version()
+		if p.ifMatch != nil && *p.ifMatch != bl.Version() {
+			p.l.Debugf("Buddy list of character [%d] is at version [%d], not the required [%d].", characterId, bl.Version(), *p.ifMatch)
+			return ErrVersionConflict
+		}
+
+		// Remove deleted character for all of their buddies.
+		for _, b := range bl.Buddies() {
+			rb, err := removeBuddy(tx, p.t.Id(), b.CharacterId(), characterId)
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				p.l.Debugf("Character [%d] is not on the buddy list of character [%d].", characterId, b.CharacterId())
+				continue
+			}
 			if err != nil {
+				p.l.WithError(err).Errorf("Unable to remove buddy from buddy list for character [%d].", b.CharacterId())
 				return err
 			}
-
-			// Remove deleted character for all of their buddies.
-			for _, b := range bl.Buddies() {
-				err = removeBuddy(tx, p.t.Id(), b.CharacterId(), characterId)
-				if err != nil {
-					p.l.WithError(err).Errorf("Unable to remove buddy from buddy list for character [%d].", b.CharacterId())
-					return err
-				}
-
-				_ = mb.Put(list2.EnvStatusEventTopic, list3.BuddyRemovedStatusEventProvider(b.CharacterId(), worldId, characterId))
+			err = p.auditMutation(tx, characterId, b.CharacterId(), audit.ActionBuddyRemoved, buddyEntitySnapshot(rb), nil)
+			if err != nil {
+				return err
 			}
-			return deleteEntityWithBuddies(tx, p.t.Id(), characterId)
-		})
-		if txErr != nil {
-			return txErr
+			v, err := incrementVersion(tx, p.t.Id(), b.CharacterId(), nil)
+			if err == nil {
+				err = p.recordChange(tx, b.CharacterId(), v, change.TypeBuddyRemoved, characterId, nil)
+			}
+			if err != nil {
+				return err
+			}
+
+			events = append(events, list3.BuddyRemovedStatusEventProvider(b.CharacterId(), worldId, v, characterId))
 		}
-		return nil
+		err = p.auditMutation(tx, characterId, characterId, audit.ActionListDeleted, listSnapshot(bl), nil)
+		if err != nil {
+			return err
+		}
+		return deleteEntityWithBuddies(tx, p.t.Id(), characterId)
+	})
+	if txErr != nil {
+		if !errors.Is(txErr, errListNotFound) {
+			p.l.WithError(txErr).Errorf("Unable to delete buddy list of character [%d].", characterId)
+		}
+		return txErr
 	}
+	for _, e := range events {
+		_ = mb.Put(list2.EnvStatusEventTopic, e)
+	}
+	return mb.Put(list2.EnvStatusEventTopic, list3.ListDeletedStatusEventProvider(characterId, worldId, version))
 }
 
 func (p *ProcessorImpl) RequestAddBuddyAndEmit(characterId uint32, worldId byte, targetId uint32, group string) error {
@@ -184,6 +605,21 @@ func (p *ProcessorImpl) RequestAddBuddy(mb *message.Buffer) func(characterId uin
 				return err
 			}
 
+			if !p.c.AllowCrossWorld() {
+				var same bool
+				same, err = p.sameWorld(tx, cbl, obl)
+				if err != nil {
+					p.l.WithError(err).Errorf("Unable to determine worlds of character [%d] and target [%d].", characterId, targetId)
+					_ = mb.Put(list2.EnvStatusEventTopic, list3.ErrorStatusEventProvider(characterId, worldId, list2.StatusEventErrorUnknownError))
+					return err
+				}
+				if !same {
+					p.l.Infof("Character [%d] attempting to buddy [%d] in a different world.", characterId, targetId)
+					_ = mb.Put(list2.EnvStatusEventTopic, list3.ErrorStatusEventProvider(characterId, worldId, list2.StatusEventErrorDifferentWorld))
+					return errors.New("cannot buddy a character in a different world")
+				}
+			}
+
 			if byte(len(obl.Buddies()))+1 > obl.Capacity() {
 				p.l.Infof("Buddy list for character [%d] is at capacity.", targetId)
 				_ = mb.Put(list2.EnvStatusEventTopic, list3.ErrorStatusEventProvider(characterId, worldId, list2.StatusEventErrorListFull))
@@ -197,23 +633,69 @@ func (p *ProcessorImpl) RequestAddBuddy(mb *message.Buffer) func(characterId uin
 					break
 				}
 			}
+
+			// a request, rather than the acceptance of one, is declined silently when the target declines all requests.
+			if mbe == nil {
+				ts, err := p.sp.WithTransaction(tx).GetByCharacterId(targetId)
+				if err != nil {
+					p.l.WithError(err).Errorf("Unable to retrieve settings of character [%d].", targetId)
+					_ = mb.Put(list2.EnvStatusEventTopic, list3.ErrorStatusEventProvider(characterId, worldId, list2.StatusEventErrorUnknownError))
+					return err
+				}
+				if ts.DeclineRequests() {
+					return ErrRequestDeclined
+				}
+			}
+
+			v, err := p.claimVersion(tx, cbl)
+			if err != nil {
+				_ = mb.Put(list2.EnvStatusEventTopic, list3.ErrorStatusEventProvider(characterId, worldId, versionError(err)))
+				return err
+			}
+
 			if mbe != nil {
 				p.l.Infof("Character [%d] is already on target characters [%d] buddy list.", characterId, targetId)
 				err = addBuddy(tx, p.t.Id(), characterId, targetId, tc.Name(), group, false)
+				if err == nil {
+					err = p.auditMutation(tx, characterId, characterId, audit.ActionBuddyAdded, nil, newBuddySnapshot(targetId, tc.Name(), group, false))
+				}
+				if err == nil {
+					err = p.recordChange(tx, characterId, v, change.TypeBuddyAdded, targetId, newBuddySnapshot(targetId, tc.Name(), group, false))
+				}
 				if err != nil {
 					p.l.WithError(err).Errorf("Unable to add buddy to buddy list for character [%d].", characterId)
 					_ = mb.Put(list2.EnvStatusEventTopic, list3.ErrorStatusEventProvider(characterId, worldId, list2.StatusEventErrorUnknownError))
 					return err
 				}
 
-				_ = mb.Put(list2.EnvStatusEventTopic, list3.BuddyAddedStatusEventProvider(characterId, worldId, targetId, tc.Name(), -1, group))
-				_ = mb.Put(list2.EnvStatusEventTopic, list3.BuddyAddedStatusEventProvider(targetId, worldId, characterId, mbe.Name(), mbe.ChannelId(), mbe.Group()))
+				// the target is told of the pair too, which advances their list like any other event.
+				tv, err := incrementVersion(tx, p.t.Id(), targetId, nil)
+				if err == nil {
+					err = p.auditMutation(tx, characterId, targetId, audit.ActionBuddyAdded, nil, buddySnapshot(*mbe))
+				}
+				if err == nil {
+					err = p.recordChange(tx, targetId, tv, change.TypeBuddyAdded, characterId, buddySnapshot(*mbe))
+				}
+				if err != nil {
+					p.l.WithError(err).Errorf("Unable to advance buddy list version of character [%d].", targetId)
+					_ = mb.Put(list2.EnvStatusEventTopic, list3.ErrorStatusEventProvider(characterId, worldId, list2.StatusEventErrorUnknownError))
+					return err
+				}
+
+				_ = mb.Put(list2.EnvStatusEventTopic, list3.BuddyAddedStatusEventProvider(characterId, worldId, v, targetId, tc.Name(), -1, group, time.Time{}))
+				_ = mb.Put(list2.EnvStatusEventTopic, list3.BuddyAddedStatusEventProvider(targetId, worldId, tv, characterId, mbe.Name(), mbe.ChannelId(), mbe.Group(), mbe.LastSeen()))
 				// TODO need to trigger a channel request for target.
 				return nil
 			}
 
 			// soft allocate buddy for character
 			err = addPendingBuddy(tx, p.t.Id(), characterId, targetId, tc.Name(), group)
+			if err == nil {
+				err = p.auditMutation(tx, characterId, characterId, audit.ActionBuddyAdded, nil, newBuddySnapshot(targetId, tc.Name(), group, true))
+			}
+			if err == nil {
+				err = p.recordChange(tx, characterId, v, change.TypeBuddyAdded, targetId, newBuddySnapshot(targetId, tc.Name(), group, true))
+			}
 			if err != nil {
 				p.l.WithError(err).Errorf("Unable to add buddy to buddy list for character [%d].", characterId)
 				_ = mb.Put(list2.EnvStatusEventTopic, list3.ErrorStatusEventProvider(characterId, worldId, list2.StatusEventErrorUnknownError))
@@ -225,9 +707,13 @@ func (p *ProcessorImpl) RequestAddBuddy(mb *message.Buffer) func(characterId uin
 				_ = mb.Put(list2.EnvStatusEventTopic, list3.ErrorStatusEventProvider(characterId, worldId, list2.StatusEventErrorUnknownError))
 				return err
 			}
-			_ = mb.Put(list2.EnvStatusEventTopic, list3.BuddyAddedStatusEventProvider(characterId, worldId, targetId, tc.Name(), -1, group))
+			_ = mb.Put(list2.EnvStatusEventTopic, list3.BuddyAddedStatusEventProvider(characterId, worldId, v, targetId, tc.Name(), -1, group, time.Time{}))
 			return nil
 		})
+		if errors.Is(txErr, ErrRequestDeclined) {
+			p.l.Infof("Character [%d] declines buddy requests, declining request of character [%d].", targetId, characterId)
+			return nil
+		}
 		if txErr != nil {
 			p.l.WithError(txErr).Errorf("Unable to add buddy to buddy list for character [%d].", characterId)
 			return nil
@@ -270,7 +756,19 @@ func (p *ProcessorImpl) RequestDeleteBuddy(mb *message.Buffer) func(characterId
 				return nil
 			}
 
-			err = removeBuddy(tx, p.t.Id(), characterId, targetId)
+			v, err := p.claimVersion(tx, cbl)
+			if err != nil {
+				_ = mb.Put(list2.EnvStatusEventTopic, list3.ErrorStatusEventProvider(characterId, worldId, versionError(err)))
+				return err
+			}
+
+			rb, err := removeBuddy(tx, p.t.Id(), characterId, targetId)
+			if err == nil {
+				err = p.auditMutation(tx, characterId, characterId, audit.ActionBuddyRemoved, buddyEntitySnapshot(rb), nil)
+			}
+			if err == nil {
+				err = p.recordChange(tx, characterId, v, change.TypeBuddyRemoved, targetId, nil)
+			}
 			if err != nil {
 				p.l.WithError(err).Errorf("Unable to remove buddy from buddy list for character [%d].", characterId)
 				_ = mb.Put(list2.EnvStatusEventTopic, list3.ErrorStatusEventProvider(characterId, worldId, list2.StatusEventErrorUnknownError))
@@ -278,17 +776,27 @@ func (p *ProcessorImpl) RequestDeleteBuddy(mb *message.Buffer) func(characterId
 			}
 
 			var update bool
-			update, err = updateBuddyChannel(tx, p.t.Id(), characterId, targetId, -1)
+			update, err = updateBuddyChannel(tx, p.t.Id(), characterId, targetId, worldId, -1, 0, time.Time{})
 			if err != nil {
 				p.l.WithError(err).Errorf("Unable to update character [%d] channel to [%d] in [%d] buddy list.", characterId, -1, targetId)
 				_ = mb.Put(list2.EnvStatusEventTopic, list3.ErrorStatusEventProvider(characterId, worldId, list2.StatusEventErrorUnknownError))
 				return err
 			}
 
-			_ = mb.Put(list2.EnvStatusEventTopic, list3.BuddyRemovedStatusEventProvider(characterId, worldId, targetId))
+			_ = mb.Put(list2.EnvStatusEventTopic, list3.BuddyRemovedStatusEventProvider(characterId, worldId, v, targetId))
 
 			if update {
-				_ = mb.Put(list2.EnvStatusEventTopic, list3.BuddyChannelChangeStatusEventProvider(targetId, worldId, characterId, -1))
+				tv, err := incrementVersion(tx, p.t.Id(), targetId, nil)
+				if err == nil {
+					err = p.recordChange(tx, targetId, tv, change.TypePresence, characterId, newPresenceSnapshot(-1, time.Time{}))
+				}
+				if err == nil {
+					err = p.auditMutation(tx, characterId, targetId, audit.ActionPresenceChanged, nil, newPresenceSnapshot(-1, time.Time{}))
+				}
+				if err != nil {
+					return err
+				}
+				_ = mb.Put(list2.EnvStatusEventTopic, list3.BuddyChannelChangeStatusEventProvider(targetId, worldId, tv, characterId, -1, time.Time{}))
 			}
 			return nil
 		})
@@ -341,6 +849,21 @@ func (p *ProcessorImpl) AcceptInvite(mb *message.Buffer) func(characterId uint32
 				_ = mb.Put(list2.EnvStatusEventTopic, list3.ErrorStatusEventProvider(characterId, worldId, list2.StatusEventErrorUnknownError))
 				return err
 			}
+
+			if !p.c.AllowCrossWorld() {
+				var same bool
+				same, err = p.sameWorld(tx, cbl, obl)
+				if err != nil {
+					p.l.WithError(err).Errorf("Unable to determine worlds of character [%d] and target [%d].", characterId, targetId)
+					_ = mb.Put(list2.EnvStatusEventTopic, list3.ErrorStatusEventProvider(characterId, worldId, list2.StatusEventErrorUnknownError))
+					return err
+				}
+				if !same {
+					p.l.Infof("Character [%d] attempting to accept buddy [%d] in a different world.", characterId, targetId)
+					_ = mb.Put(list2.EnvStatusEventTopic, list3.ErrorStatusEventProvider(characterId, worldId, list2.StatusEventErrorDifferentWorld))
+					return errors.New("cannot buddy a character in a different world")
+				}
+			}
 			var ob buddy.Model
 			for _, b := range obl.Buddies() {
 				if b.CharacterId() == characterId {
@@ -362,12 +885,25 @@ func (p *ProcessorImpl) AcceptInvite(mb *message.Buffer) func(characterId uint32
 				return err
 			}
 
-			err = removeBuddy(tx, p.t.Id(), targetId, characterId)
+			v, err := p.claimVersion(tx, cbl)
+			if err != nil {
+				_ = mb.Put(list2.EnvStatusEventTopic, list3.ErrorStatusEventProvider(characterId, worldId, versionError(err)))
+				return err
+			}
+
+			rb, err := removeBuddy(tx, p.t.Id(), targetId, characterId)
 			if err != nil {
 				return err
 			}
 
-			err = addBuddy(tx, p.t.Id(), characterId, targetId, oc.Name(), "Default Group", false)
+			err = addBuddy(tx, p.t.Id(), characterId, targetId, oc.Name(), DefaultGroup, false)
+			if err != nil {
+				return err
+			}
+			err = p.auditMutation(tx, characterId, characterId, audit.ActionBuddyAdded, nil, newBuddySnapshot(targetId, oc.Name(), DefaultGroup, false))
+			if err == nil {
+				err = p.recordChange(tx, characterId, v, change.TypeBuddyAdded, targetId, newBuddySnapshot(targetId, oc.Name(), DefaultGroup, false))
+			}
 			if err != nil {
 				return err
 			}
@@ -376,8 +912,19 @@ func (p *ProcessorImpl) AcceptInvite(mb *message.Buffer) func(characterId uint32
 			if err != nil {
 				return err
 			}
+			err = p.auditMutation(tx, characterId, targetId, audit.ActionBuddyUpdated, buddyEntitySnapshot(rb), newBuddySnapshot(characterId, c.Name(), ob.Group(), false))
+			if err != nil {
+				return err
+			}
+			tv, err := incrementVersion(tx, p.t.Id(), targetId, nil)
+			if err == nil {
+				err = p.recordChange(tx, targetId, tv, change.TypeBuddyUpdated, characterId, newBuddySnapshot(characterId, c.Name(), ob.Group(), false))
+			}
+			if err != nil {
+				return err
+			}
 
-			_ = mb.Put(list2.EnvStatusEventTopic, list3.BuddyAddedStatusEventProvider(characterId, worldId, targetId, oc.Name(), -1, "Default Group"))
+			_ = mb.Put(list2.EnvStatusEventTopic, list3.BuddyAddedStatusEventProvider(characterId, worldId, v, targetId, oc.Name(), -1, DefaultGroup, time.Time{}))
 			// TODO need to trigger a channel request for target.
 			return nil
 		})
@@ -398,22 +945,42 @@ func (p *ProcessorImpl) DeleteBuddyAndEmit(characterId uint32, worldId byte, tar
 func (p *ProcessorImpl) DeleteBuddy(mb *message.Buffer) func(characterId uint32, worldId byte, targetId uint32) error {
 	return func(characterId uint32, worldId byte, targetId uint32) error {
 		txErr := database.ExecuteTransaction(p.db, func(tx *gorm.DB) error {
-			err := removeBuddy(tx, p.t.Id(), characterId, targetId)
+			rb, err := removeBuddy(tx, p.t.Id(), characterId, targetId)
+			if err == nil {
+				err = p.auditMutation(tx, characterId, characterId, audit.ActionBuddyRemoved, buddyEntitySnapshot(rb), nil)
+			}
 			if err != nil {
 				p.l.WithError(err).Errorf("Unable to remove buddy from buddy list for character [%d].", characterId)
 				return err
 			}
 			var update bool
-			update, err = updateBuddyChannel(tx, p.t.Id(), characterId, targetId, -1)
+			update, err = updateBuddyChannel(tx, p.t.Id(), characterId, targetId, worldId, -1, 0, time.Time{})
 			if err != nil {
 				p.l.WithError(err).Errorf("Unable to update character [%d] channel to [%d] in [%d] buddy list.", characterId, -1, targetId)
 				return err
 			}
+			v, err := incrementVersion(tx, p.t.Id(), characterId, nil)
+			if err == nil {
+				err = p.recordChange(tx, characterId, v, change.TypeBuddyRemoved, targetId, nil)
+			}
+			if err != nil {
+				return err
+			}
 
-			_ = mb.Put(list2.EnvStatusEventTopic, list3.BuddyRemovedStatusEventProvider(characterId, worldId, targetId))
+			_ = mb.Put(list2.EnvStatusEventTopic, list3.BuddyRemovedStatusEventProvider(characterId, worldId, v, targetId))
 
 			if update {
-				_ = mb.Put(list2.EnvStatusEventTopic, list3.BuddyChannelChangeStatusEventProvider(targetId, worldId, characterId, -1))
+				tv, err := incrementVersion(tx, p.t.Id(), targetId, nil)
+				if err == nil {
+					err = p.recordChange(tx, targetId, tv, change.TypePresence, characterId, newPresenceSnapshot(-1, time.Time{}))
+				}
+				if err == nil {
+					err = p.auditMutation(tx, characterId, targetId, audit.ActionPresenceChanged, nil, newPresenceSnapshot(-1, time.Time{}))
+				}
+				if err != nil {
+					return err
+				}
+				_ = mb.Put(list2.EnvStatusEventTopic, list3.BuddyChannelChangeStatusEventProvider(targetId, worldId, tv, characterId, -1, time.Time{}))
 			}
 			return nil
 		})
@@ -425,30 +992,54 @@ func (p *ProcessorImpl) DeleteBuddy(mb *message.Buffer) func(characterId uint32,
 	}
 }
 
-func (p *ProcessorImpl) UpdateBuddyChannelAndEmit(characterId uint32, worldId byte, channelId int8) error {
+func (p *ProcessorImpl) UpdateBuddyChannelAndEmit(characterId uint32, worldId byte, channelId int8, mapId uint32) error {
 	return message.Emit(p.p)(func(buf *message.Buffer) error {
-		return p.UpdateBuddyChannel(buf)(characterId, worldId, channelId)
+		return p.UpdateBuddyChannel(buf)(characterId, worldId, channelId, mapId)
 	})
 }
 
-func (p *ProcessorImpl) UpdateBuddyChannel(mb *message.Buffer) func(characterId uint32, worldId byte, channelId int8) error {
-	return func(characterId uint32, worldId byte, channelId int8) error {
+func (p *ProcessorImpl) UpdateBuddyChannel(mb *message.Buffer) func(characterId uint32, worldId byte, channelId int8, mapId uint32) error {
+	return func(characterId uint32, worldId byte, channelId int8, mapId uint32) error {
+		now := time.Now().UTC()
 		txErr := database.ExecuteTransaction(p.db, func(tx *gorm.DB) error {
 			bl, err := byCharacterIdEntityProvider(p.t.Id(), characterId)(tx)()
 			if err != nil {
 				p.l.WithError(err).Errorf("Unable to locate buddy list for character [%d].", characterId)
 				return err
 			}
+			s, err := p.sp.WithTransaction(tx).GetByCharacterId(characterId)
+			if err != nil {
+				return err
+			}
+			err = recordPresence(tx, p.t.Id(), characterId, worldId, channelId, mapId)
+			if err != nil {
+				return err
+			}
 			for _, b := range bl.Buddies {
+				// buddies the character is hidden from keep seeing them offline.
+				if s.HiddenFrom(b.Group) {
+					continue
+				}
 				var update bool
-				update, err = updateBuddyChannel(tx, p.t.Id(), characterId, b.CharacterId, channelId)
+				update, err = updateBuddyChannel(tx, p.t.Id(), characterId, b.CharacterId, worldId, channelId, mapId, now)
 				if err != nil {
 					p.l.WithError(err).Errorf("Unable to update character [%d] channel to [%d] in [%d] buddy list.", characterId, channelId, b.CharacterId)
 					return err
 				}
 
 				if update {
-					_ = mb.Put(list2.EnvStatusEventTopic, list3.BuddyChannelChangeStatusEventProvider(b.CharacterId, worldId, characterId, channelId))
+					var v uint32
+					v, err = incrementVersion(tx, p.t.Id(), b.CharacterId, nil)
+					if err == nil {
+						err = p.recordChange(tx, b.CharacterId, v, change.TypePresence, characterId, newPresenceSnapshot(channelId, now))
+					}
+					if err == nil {
+						err = p.auditMutation(tx, characterId, b.CharacterId, audit.ActionPresenceChanged, nil, newPresenceSnapshot(channelId, now))
+					}
+					if err != nil {
+						return err
+					}
+					_ = mb.Put(list2.EnvStatusEventTopic, list3.BuddyChannelChangeStatusEventProvider(b.CharacterId, worldId, v, characterId, channelId, now))
 				}
 			}
 			return nil
@@ -461,25 +1052,184 @@ func (p *ProcessorImpl) UpdateBuddyChannel(mb *message.Buffer) func(characterId
 	}
 }
 
-func (p *ProcessorImpl) UpdateBuddyShopStatusAndEmit(characterId uint32, worldId byte, inShop bool) error {
+func (p *ProcessorImpl) UpdateBuddyMap(characterId uint32, mapId uint32) error {
+	txErr := database.ExecuteTransaction(p.db, func(tx *gorm.DB) error {
+		bl, err := byCharacterIdEntityProvider(p.t.Id(), characterId)(tx)()
+		if err != nil {
+			p.l.WithError(err).Errorf("Unable to locate buddy list for character [%d].", characterId)
+			return err
+		}
+		s, err := p.sp.WithTransaction(tx).GetByCharacterId(characterId)
+		if err != nil {
+			return err
+		}
+		err = recordMap(tx, p.t.Id(), characterId, mapId)
+		if err != nil {
+			return err
+		}
+		for _, b := range bl.Buddies {
+			if s.HiddenFrom(b.Group) {
+				continue
+			}
+			_, err = updateBuddyMap(tx, p.t.Id(), characterId, b.CharacterId, mapId)
+			if err != nil {
+				p.l.WithError(err).Errorf("Unable to update character [%d] map to [%d] in [%d] buddy list.", characterId, mapId, b.CharacterId)
+				return err
+			}
+		}
+		return nil
+	})
+	if txErr != nil {
+		p.l.WithError(txErr).Errorf("Unable to update buddy map for character [%d].", characterId)
+		return txErr
+	}
+	return nil
+}
+
+func (p *ProcessorImpl) ClearChannelPresenceAndEmit(worldId byte, channelId byte) error {
 	return message.Emit(p.p)(func(buf *message.Buffer) error {
-		return p.UpdateBuddyShopStatus(buf)(characterId, worldId, inShop)
+		return p.ClearChannelPresence(buf)(worldId, channelId)
 	})
 }
 
-func (p *ProcessorImpl) UpdateBuddyShopStatus(mb *message.Buffer) func(characterId uint32, worldId byte, inShop bool) error {
-	return func(characterId uint32, worldId byte, inShop bool) error {
+// ClearChannelPresence marks every character present on a stopped channel as offline in the buddy lists of the tenant,
+// and notifies the owners of those buddy lists.
+func (p *ProcessorImpl) ClearChannelPresence(mb *message.Buffer) func(worldId byte, channelId byte) error {
+	return func(worldId byte, channelId byte) error {
+		now := time.Now().UTC()
 		txErr := database.ExecuteTransaction(p.db, func(tx *gorm.DB) error {
-			bl, err := byCharacterIdEntityProvider(p.t.Id(), characterId)(tx)()
+			err := clearRecordedPresence(tx, p.t.Id(), worldId, channelId)
 			if err != nil {
-				p.l.WithError(err).Errorf("Unable to locate buddy list for character [%d].", characterId)
 				return err
 			}
-			for _, b := range bl.Buddies {
-				var update bool
-				update, err = updateBuddyShopStatus(tx, p.t.Id(), characterId, b.CharacterId, inShop)
+			ps, err := clearChannelPresence(tx, p.t.Id(), worldId, channelId, now)
+			if err != nil {
+				return err
+			}
+			p.l.Infof("Marked [%d] buddy entries offline for world [%d] channel [%d].", len(ps), worldId, channelId)
+			for _, pr := range ps {
+				var v uint32
+				v, err = incrementVersion(tx, p.t.Id(), pr.OwnerId, nil)
+				if err == nil {
+					err = p.recordChange(tx, pr.OwnerId, v, change.TypePresence, pr.CharacterId, newPresenceSnapshot(-1, now))
+				}
+				if err == nil {
+					err = p.auditMutation(tx, pr.CharacterId, pr.OwnerId, audit.ActionPresenceChanged, nil, newPresenceSnapshot(-1, now))
+				}
 				if err != nil {
-					p.l.WithError(err).Errorf("Unable to update character [%d] shop status to [%t] in [%d] buddy list.", characterId, inShop, b.CharacterId)
+					return err
+				}
+				_ = mb.Put(list2.EnvStatusEventTopic, list3.BuddyChannelChangeStatusEventProvider(pr.OwnerId, worldId, v, pr.CharacterId, -1, now))
+			}
+			return nil
+		})
+		if txErr != nil {
+			p.l.WithError(txErr).Errorf("Unable to clear buddy presence for world [%d] channel [%d].", worldId, channelId)
+			return txErr
+		}
+		return nil
+	}
+}
+
+func (p *ProcessorImpl) SnapshotAndEmit(characterId uint32, worldId byte) error {
+	return message.Emit(p.p)(func(buf *message.Buffer) error {
+		return p.Snapshot(buf)(characterId, worldId)
+	})
+}
+
+// Snapshot buffers a LIST_SNAPSHOT event containing the capacity and every buddy (with presence) of the character's buddy list.
+func (p *ProcessorImpl) Snapshot(mb *message.Buffer) func(characterId uint32, worldId byte) error {
+	return func(characterId uint32, worldId byte) error {
+		bl, err := p.GetByCharacterId(characterId)
+		if err != nil {
+			p.l.WithError(err).Errorf("Unable to retrieve buddy list for character [%d] to snapshot.", characterId)
+			return err
+		}
+
+		buddies := make([]list2.ListSnapshotBuddy, 0)
+		for _, b := range bl.Buddies() {
+			var lastSeen *time.Time
+			if ls := b.LastSeen(); !ls.IsZero() {
+				lastSeen = &ls
+			}
+			buddies = append(buddies, list2.ListSnapshotBuddy{
+				CharacterId:   b.CharacterId(),
+				Group:         b.Group(),
+				CharacterName: b.Name(),
+				ChannelId:     b.ChannelId(),
+				MapId:         b.MapId(),
+				InShop:        b.InShop(),
+				Pending:       b.Pending(),
+				LastSeen:      lastSeen,
+			})
+		}
+		return mb.Put(list2.EnvStatusEventTopic, list3.ListSnapshotStatusEventProvider(characterId, worldId, bl.Version(), bl.Capacity(), buddies))
+	}
+}
+
+// GetBuddyLocation retrieves the presence of buddyId as seen from characterId's buddy list.
+// Only mutual, confirmed buddies are located. ErrNotMutualBuddy is returned otherwise.
+func (p *ProcessorImpl) GetBuddyLocation(characterId uint32, buddyId uint32) (buddy.Model, error) {
+	cbl, err := p.GetByCharacterId(characterId)
+	if err != nil {
+		return buddy.Model{}, err
+	}
+	var tb *buddy.Model
+	for _, b := range cbl.Buddies() {
+		if b.CharacterId() == buddyId && !b.Pending() {
+			tb = &b
+			break
+		}
+	}
+	if tb == nil {
+		return buddy.Model{}, ErrNotMutualBuddy
+	}
+
+	obl, err := p.GetByCharacterId(buddyId)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return buddy.Model{}, ErrNotMutualBuddy
+	}
+	if err != nil {
+		return buddy.Model{}, err
+	}
+	for _, b := range obl.Buddies() {
+		if b.CharacterId() == characterId && !b.Pending() {
+			return *tb, nil
+		}
+	}
+	return buddy.Model{}, ErrNotMutualBuddy
+}
+
+func (p *ProcessorImpl) UpdateBuddyShopStatusAndEmit(characterId uint32, worldId byte, inShop bool) error {
+	return message.Emit(p.p)(func(buf *message.Buffer) error {
+		return p.UpdateBuddyShopStatus(buf)(characterId, worldId, inShop)
+	})
+}
+
+func (p *ProcessorImpl) UpdateBuddyShopStatus(mb *message.Buffer) func(characterId uint32, worldId byte, inShop bool) error {
+	return func(characterId uint32, worldId byte, inShop bool) error {
+		txErr := database.ExecuteTransaction(p.db, func(tx *gorm.DB) error {
+			bl, err := byCharacterIdEntityProvider(p.t.Id(), characterId)(tx)()
+			if err != nil {
+				p.l.WithError(err).Errorf("Unable to locate buddy list for character [%d].", characterId)
+				return err
+			}
+			s, err := p.sp.WithTransaction(tx).GetByCharacterId(characterId)
+			if err != nil {
+				return err
+			}
+			err = recordShopStatus(tx, p.t.Id(), characterId, inShop)
+			if err != nil {
+				return err
+			}
+			for _, b := range bl.Buddies {
+				if s.HiddenFrom(b.Group) {
+					continue
+				}
+				var update bool
+				update, err = updateBuddyShopStatus(tx, p.t.Id(), characterId, b.CharacterId, inShop)
+				if err != nil {
+					p.l.WithError(err).Errorf("Unable to update character [%d] shop status to [%t] in [%d] buddy list.", characterId, inShop, b.CharacterId)
 					return err
 				}
 
@@ -498,7 +1248,17 @@ func (p *ProcessorImpl) UpdateBuddyShopStatus(mb *message.Buffer) func(character
 						continue
 					}
 
-					_ = mb.Put(list2.EnvStatusEventTopic, list3.BuddyUpdatedStatusEventProvider(b.CharacterId, worldId, tbe.CharacterId, tbe.Group, tbe.CharacterName, b.ChannelId, inShop))
+					v, err := incrementVersion(tx, p.t.Id(), b.CharacterId, nil)
+					if err == nil {
+						err = p.recordChange(tx, b.CharacterId, v, change.TypeBuddyUpdated, tbe.CharacterId, buddyEntitySnapshot(*tbe))
+					}
+					if err == nil {
+						err = p.auditMutation(tx, characterId, b.CharacterId, audit.ActionPresenceChanged, nil, buddyEntitySnapshot(*tbe))
+					}
+					if err != nil {
+						return err
+					}
+					_ = mb.Put(list2.EnvStatusEventTopic, list3.BuddyUpdatedStatusEventProvider(b.CharacterId, worldId, v, tbe.CharacterId, tbe.Group, tbe.CharacterName, b.ChannelId, inShop, lastSeenOf(*tbe)))
 				}
 			}
 			return nil
@@ -511,6 +1271,132 @@ func (p *ProcessorImpl) UpdateBuddyShopStatus(mb *message.Buffer) func(character
 	}
 }
 
+func (p *ProcessorImpl) UpdateSettingsAndEmit(characterId uint32, worldId byte, c settings.Change) error {
+	return message.Emit(p.p)(func(buf *message.Buffer) error {
+		return p.UpdateSettings(buf)(characterId, worldId, c)
+	})
+}
+
+func (p *ProcessorImpl) UpdateSettings(mb *message.Buffer) func(characterId uint32, worldId byte, c settings.Change) error {
+	return func(characterId uint32, worldId byte, c settings.Change) error {
+		txErr := database.ExecuteTransaction(p.db, func(tx *gorm.DB) error {
+			sp := p.sp.WithTransaction(tx)
+			os, err := sp.GetByCharacterId(characterId)
+			if err != nil {
+				return err
+			}
+			// a character without a buddy list has no version to report.
+			var version uint32
+			e, err := byCharacterIdWithoutBuddiesEntityProvider(p.t.Id(), characterId)(tx)()
+			if err == nil {
+				version = e.Version
+			} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+				return err
+			}
+			err = sp.Update(mb)(characterId, worldId, version, c)
+			if err != nil {
+				return err
+			}
+			ns, err := sp.GetByCharacterId(characterId)
+			if err != nil {
+				return err
+			}
+			// invalid settings are rejected by the settings processor without change.
+			if c.Valid() {
+				err = p.auditMutation(tx, characterId, characterId, audit.ActionSettingsChanged, settingsSnapshot(os), settingsSnapshot(ns))
+				if err != nil {
+					return err
+				}
+			}
+			return p.withTransaction(tx).applyVisibility(mb, characterId, worldId, os, ns)
+		})
+		if txErr != nil {
+			p.l.WithError(txErr).Errorf("Unable to update settings of character [%d].", characterId)
+			return txErr
+		}
+		return nil
+	}
+}
+
+// applyVisibility shows the actual presence of a character to each buddy they are no longer hidden from, and shows the
+// character as offline to each buddy they are newly hidden from. An offline character looks the same either way.
+func (p *ProcessorImpl) applyVisibility(mb *message.Buffer, characterId uint32, worldId byte, os settings.Model, ns settings.Model) error {
+	bl, err := byCharacterIdEntityProvider(p.t.Id(), characterId)(p.db)()
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	pr, err := p.actualPresence(characterId, worldId)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if pr.ChannelId < 0 {
+		return nil
+	}
+
+	now := time.Now().UTC()
+	for _, b := range bl.Buddies {
+		hidden := ns.HiddenFrom(b.Group)
+		if hidden == os.HiddenFrom(b.Group) {
+			continue
+		}
+		channelId, mapId, inShop, lastSeen := pr.ChannelId, pr.MapId, pr.InShop, time.Time{}
+		if hidden {
+			channelId, mapId, inShop, lastSeen = -1, 0, false, now
+		}
+
+		update, err := updateBuddyPresence(p.db, p.t.Id(), characterId, b.CharacterId, pr.WorldId, channelId, mapId, inShop, lastSeen)
+		if err != nil {
+			p.l.WithError(err).Errorf("Unable to update character [%d] presence in [%d] buddy list.", characterId, b.CharacterId)
+			return err
+		}
+		if !update {
+			continue
+		}
+		v, err := incrementVersion(p.db, p.t.Id(), b.CharacterId, nil)
+		if err == nil {
+			err = p.recordChange(p.db, b.CharacterId, v, change.TypePresence, characterId, newPresenceSnapshot(channelId, lastSeen))
+		}
+		if err == nil {
+			err = p.auditMutation(p.db, characterId, b.CharacterId, audit.ActionPresenceChanged, nil, newPresenceSnapshot(channelId, lastSeen))
+		}
+		if err != nil {
+			return err
+		}
+		_ = mb.Put(list2.EnvStatusEventTopic, list3.BuddyChannelChangeStatusEventProvider(b.CharacterId, worldId, v, characterId, channelId, lastSeen))
+	}
+	return nil
+}
+
+// actualPresence provides the recorded presence of a character. Characters that came online before their presence was
+// recorded are derived from an entry in which another list shows them online, and the derived presence is recorded.
+func (p *ProcessorImpl) actualPresence(characterId uint32, worldId byte) (presence2.Entity, error) {
+	pr, err := presenceByCharacterIdEntityProvider(p.t.Id(), characterId)(p.db)()
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return pr, err
+	}
+	b, err := shownPresenceEntityProvider(p.t.Id(), characterId)(p.db)()
+	if err != nil {
+		return presence2.Entity{}, err
+	}
+	if b.WorldId != nil {
+		worldId = *b.WorldId
+	}
+	err = recordPresence(p.db, p.t.Id(), characterId, worldId, b.ChannelId, b.MapId)
+	if err == nil && b.InShop {
+		err = recordShopStatus(p.db, p.t.Id(), characterId, true)
+	}
+	if err != nil {
+		return presence2.Entity{}, err
+	}
+	return presence2.Entity{TenantId: p.t.Id(), CharacterId: characterId, WorldId: worldId, ChannelId: b.ChannelId, MapId: b.MapId, InShop: b.InShop}, nil
+}
+
 // IncreaseCapacityAndEmit increases the buddy list capacity for a character and emits status events.
 // This method handles the complete workflow: validation, database update, and event emission.
 //
@@ -524,13 +1410,14 @@ func (p *ProcessorImpl) UpdateBuddyShopStatus(mb *message.Buffer) func(character
 //
 // Events Emitted:
 //   - CAPACITY_CHANGE: On successful capacity increase with new capacity value
-//   - ERROR with INVALID_CAPACITY: If newCapacity <= currentCapacity
+//   - ERROR with INVALID_CAPACITY: If newCapacity <= currentCapacity or exceeds the tenant maximum
 //   - ERROR with CHARACTER_NOT_FOUND: If character's buddy list doesn't exist
 //   - ERROR with UNKNOWN_ERROR: For unexpected database errors
 //
 // Validation:
 //   - Character must have an existing buddy list
 //   - New capacity must be strictly greater than current capacity
+//   - New capacity must not exceed the maximum of the tenant capacity policy
 //   - All operations are performed within a database transaction
 func (p *ProcessorImpl) IncreaseCapacityAndEmit(characterId uint32, worldId byte, newCapacity byte) error {
 	return message.Emit(p.p)(func(buf *message.Buffer) error {
@@ -549,12 +1436,18 @@ func (p *ProcessorImpl) IncreaseCapacityAndEmit(characterId uint32, worldId byte
 //
 // Implementation Details:
 //   - Retrieves current buddy list to validate existing capacity
+//   - Validates newCapacity against the tenant capacity policy maximum (emits INVALID_CAPACITY error if exceeded)
 //   - Validates newCapacity > currentCapacity (emits INVALID_CAPACITY error if not)
 //   - Updates capacity using administrator function
 //   - Emits CAPACITY_CHANGE event on success
 //   - All operations are wrapped in a database transaction
 //   - Follows the Atlas pattern of pure functions with message buffer coordination
 func (p *ProcessorImpl) IncreaseCapacity(mb *message.Buffer) func(characterId uint32, worldId byte, newCapacity byte) error {
+	return p.increaseCapacity(mb, ledger.SourceCommand)
+}
+
+// increaseCapacity implements IncreaseCapacity, recording the change in the capacity ledger with the given source.
+func (p *ProcessorImpl) increaseCapacity(mb *message.Buffer, source string) func(characterId uint32, worldId byte, newCapacity byte) error {
 	return func(characterId uint32, worldId byte, newCapacity byte) error {
 		txErr := database.ExecuteTransaction(p.db, func(tx *gorm.DB) error {
 			// Get current buddy list to validate capacity
@@ -565,6 +1458,12 @@ func (p *ProcessorImpl) IncreaseCapacity(mb *message.Buffer) func(characterId ui
 				return err
 			}
 
+			if !p.c.Capacity().Allows(newCapacity) {
+				p.l.Debugf("Invalid capacity change attempt for character [%d]: new capacity [%d] exceeds maximum [%d].", characterId, newCapacity, p.c.Capacity().Maximum())
+				_ = mb.Put(list2.EnvStatusEventTopic, list3.ErrorStatusEventProvider(characterId, worldId, list2.StatusEventErrorInvalidCapacity))
+				return ErrInvalidCapacity
+			}
+
 			// Validate that new capacity is greater than current capacity
 			if newCapacity <= bl.Capacity() {
 				p.l.Debugf("Invalid capacity change attempt for character [%d]: new capacity [%d] must be greater than current capacity [%d].", characterId, newCapacity, bl.Capacity())
@@ -572,6 +1471,12 @@ func (p *ProcessorImpl) IncreaseCapacity(mb *message.Buffer) func(characterId ui
 				return errors.New("new capacity must be greater than current capacity")
 			}
 
+			v, err := p.claimVersion(tx, bl)
+			if err != nil {
+				_ = mb.Put(list2.EnvStatusEventTopic, list3.ErrorStatusEventProvider(characterId, worldId, versionError(err)))
+				return err
+			}
+
 			// Update the capacity using administrator function
 			err = updateCapacity(tx, p.t.Id(), characterId, newCapacity)
 			if err != nil {
@@ -579,9 +1484,21 @@ func (p *ProcessorImpl) IncreaseCapacity(mb *message.Buffer) func(characterId ui
 				_ = mb.Put(list2.EnvStatusEventTopic, list3.ErrorStatusEventProvider(characterId, worldId, list2.StatusEventErrorUnknownError))
 				return err
 			}
+			err = recordCapacityChange(tx, p.t.Id(), characterId, bl.Capacity(), newCapacity, source)
+			if err == nil {
+				err = p.auditMutation(tx, characterId, characterId, audit.ActionCapacityChanged, capacitySnapshot{Capacity: bl.Capacity()}, capacitySnapshot{Capacity: newCapacity, Source: source})
+			}
+			if err == nil {
+				err = p.recordChange(tx, characterId, v, change.TypeCapacityChanged, 0, capacitySnapshot{Capacity: newCapacity})
+			}
+			if err != nil {
+				p.l.WithError(err).Errorf("Unable to record capacity change for character [%d].", characterId)
+				_ = mb.Put(list2.EnvStatusEventTopic, list3.ErrorStatusEventProvider(characterId, worldId, list2.StatusEventErrorUnknownError))
+				return err
+			}
 
 			// Emit success event
-			_ = mb.Put(list2.EnvStatusEventTopic, list3.BuddyCapacityChangeStatusEventProvider(characterId, worldId, newCapacity))
+			_ = mb.Put(list2.EnvStatusEventTopic, list3.BuddyCapacityChangeStatusEventProvider(characterId, worldId, v, newCapacity))
 			p.l.Debugf("Successfully increased buddy list capacity for character [%d] to [%d].", characterId, newCapacity)
 			return nil
 		})
@@ -592,3 +1509,307 @@ func (p *ProcessorImpl) IncreaseCapacity(mb *message.Buffer) func(characterId ui
 		return nil
 	}
 }
+
+// SetCapacityAndEmit sets the buddy list capacity for a character and emits status events. See SetCapacity.
+func (p *ProcessorImpl) SetCapacityAndEmit(characterId uint32, worldId byte, capacity byte, overflowPolicy string) error {
+	return message.Emit(p.p)(func(buf *message.Buffer) error {
+		return p.SetCapacity(buf)(characterId, worldId, capacity, overflowPolicy)
+	})
+}
+
+// SetCapacity returns a curried function that sets buddy list capacity within a transaction. Unlike IncreaseCapacity
+// the capacity may be lowered, so long as it remains within the tenant capacity policy.
+//
+// When the list holds more entries than the new capacity, the overflow policy decides the outcome:
+//   - REJECT (or empty): nothing changes and a CAPACITY_OVERFLOW error is emitted
+//   - TRIM_PENDING: the newest pending entries are removed, each with a BUDDY_REMOVED event, and their outstanding
+//     invites are rejected. Confirmed buddies are never removed, so if trimming every pending entry is not enough
+//     nothing changes and a CAPACITY_OVERFLOW error is emitted
+//
+// An unsupported overflow policy is rejected with an INVALID_CAPACITY error.
+//
+// On success a CAPACITY_CHANGE event is emitted.
+func (p *ProcessorImpl) SetCapacity(mb *message.Buffer) func(characterId uint32, worldId byte, capacity byte, overflowPolicy string) error {
+	return func(characterId uint32, worldId byte, capacity byte, overflowPolicy string) error {
+		if overflowPolicy == "" {
+			overflowPolicy = list2.OverflowPolicyReject
+		}
+		if overflowPolicy != list2.OverflowPolicyReject && overflowPolicy != list2.OverflowPolicyTrimPending {
+			p.l.Debugf("Unsupported overflow policy [%s] setting capacity for character [%d].", overflowPolicy, characterId)
+			_ = mb.Put(list2.EnvStatusEventTopic, list3.ErrorStatusEventProvider(characterId, worldId, list2.StatusEventErrorInvalidCapacity))
+			return nil
+		}
+
+		txErr := database.ExecuteTransaction(p.db, func(tx *gorm.DB) error {
+			bl, err := p.WithTransaction(tx).GetByCharacterId(characterId)
+			if err != nil {
+				p.l.WithError(err).Errorf("Unable to retrieve buddy list for character [%d] to set capacity.", characterId)
+				_ = mb.Put(list2.EnvStatusEventTopic, list3.ErrorStatusEventProvider(characterId, worldId, list2.StatusEventErrorCharacterNotFound))
+				return err
+			}
+
+			if !p.c.Capacity().Allows(capacity) {
+				p.l.Debugf("Invalid capacity [%d] for character [%d]: must be between 1 and [%d].", capacity, characterId, p.c.Capacity().Maximum())
+				_ = mb.Put(list2.EnvStatusEventTopic, list3.ErrorStatusEventProvider(characterId, worldId, list2.StatusEventErrorInvalidCapacity))
+				return ErrInvalidCapacity
+			}
+
+			v, err := p.claimVersion(tx, bl)
+			if err != nil {
+				_ = mb.Put(list2.EnvStatusEventTopic, list3.ErrorStatusEventProvider(characterId, worldId, versionError(err)))
+				return err
+			}
+			// The claimed version belongs to the first event. Every further event advances the version again, so
+			// consumers observe one version per event.
+			claimed := true
+			nextVersion := func() (uint32, error) {
+				if claimed {
+					claimed = false
+					return v, nil
+				}
+				return incrementVersion(tx, p.t.Id(), characterId, nil)
+			}
+
+			overflow := len(bl.Buddies()) - int(capacity)
+			if overflow > 0 {
+				pending := 0
+				for _, b := range bl.Buddies() {
+					if b.Pending() {
+						pending++
+					}
+				}
+				if overflowPolicy == list2.OverflowPolicyReject || pending < overflow {
+					p.l.Debugf("Buddy list of character [%d] holds [%d] entries, which does not fit capacity [%d] under policy [%s].", characterId, len(bl.Buddies()), capacity, overflowPolicy)
+					_ = mb.Put(list2.EnvStatusEventTopic, list3.ErrorStatusEventProvider(characterId, worldId, list2.StatusEventErrorCapacityOverflow))
+					return ErrCapacityOverflow
+				}
+
+				removed, err := removeNewestPendingBuddies(tx, bl.id, overflow)
+				if err != nil {
+					p.l.WithError(err).Errorf("Unable to trim pending buddies of character [%d].", characterId)
+					_ = mb.Put(list2.EnvStatusEventTopic, list3.ErrorStatusEventProvider(characterId, worldId, list2.StatusEventErrorUnknownError))
+					return err
+				}
+				for _, id := range removed {
+					for _, b := range bl.Buddies() {
+						if b.CharacterId() == id {
+							err = p.auditMutation(tx, characterId, characterId, audit.ActionBuddyRemoved, buddySnapshot(b), nil)
+						}
+					}
+					if err != nil {
+						return err
+					}
+					rv, err := nextVersion()
+					if err == nil {
+						err = p.recordChange(tx, characterId, rv, change.TypeBuddyRemoved, id, nil)
+					}
+					if err != nil {
+						return err
+					}
+					// a trimmed pending entry is an invite which is still outstanding, so it is withdrawn as well.
+					err = p.ip.Reject(id, worldId, characterId)
+					if err != nil {
+						p.l.WithError(err).Errorf("Unable to reject invite of character [%d] to buddy character [%d].", characterId, id)
+						_ = mb.Put(list2.EnvStatusEventTopic, list3.ErrorStatusEventProvider(characterId, worldId, list2.StatusEventErrorUnknownError))
+						return err
+					}
+					_ = mb.Put(list2.EnvStatusEventTopic, list3.BuddyRemovedStatusEventProvider(characterId, worldId, rv, id))
+				}
+			}
+
+			err = setCapacity(tx, p.t.Id(), characterId, capacity)
+			if err != nil {
+				p.l.WithError(err).Errorf("Unable to set capacity for character [%d] to [%d].", characterId, capacity)
+				_ = mb.Put(list2.EnvStatusEventTopic, list3.ErrorStatusEventProvider(characterId, worldId, list2.StatusEventErrorUnknownError))
+				return err
+			}
+			err = recordCapacityChange(tx, p.t.Id(), characterId, bl.Capacity(), capacity, ledger.SourceAdmin)
+			if err == nil {
+				err = p.auditMutation(tx, characterId, characterId, audit.ActionCapacityChanged, capacitySnapshot{Capacity: bl.Capacity()}, capacitySnapshot{Capacity: capacity, Source: ledger.SourceAdmin})
+			}
+			if err != nil {
+				p.l.WithError(err).Errorf("Unable to record capacity change for character [%d].", characterId)
+				_ = mb.Put(list2.EnvStatusEventTopic, list3.ErrorStatusEventProvider(characterId, worldId, list2.StatusEventErrorUnknownError))
+				return err
+			}
+			cv, err := nextVersion()
+			if err == nil {
+				err = p.recordChange(tx, characterId, cv, change.TypeCapacityChanged, 0, capacitySnapshot{Capacity: capacity})
+			}
+			if err != nil {
+				return err
+			}
+
+			_ = mb.Put(list2.EnvStatusEventTopic, list3.BuddyCapacityChangeStatusEventProvider(characterId, worldId, cv, capacity))
+			p.l.Debugf("Set buddy list capacity for character [%d] to [%d].", characterId, capacity)
+			return nil
+		})
+		if txErr != nil {
+			// the outcome is reported by the buffered ERROR event.
+			p.l.WithError(txErr).Errorf("Transaction failed while setting capacity for character [%d].", characterId)
+			return nil
+		}
+		return nil
+	}
+}
+
+// ApplyCapacityItemAndEmit applies a used cash item to the buddy list capacity and emits status events. See
+// ApplyCapacityItem.
+func (p *ProcessorImpl) ApplyCapacityItemAndEmit(characterId uint32, worldId byte, itemId uint32, transactionId string) error {
+	return message.Emit(p.p)(func(buf *message.Buffer) error {
+		return p.ApplyCapacityItem(buf)(characterId, worldId, itemId, transactionId)
+	})
+}
+
+// ApplyCapacityItem returns a curried function that applies a used cash item to the buddy list capacity.
+//
+// Items which the tenant capacity policy does not map to an increment are ignored. Otherwise the capacity is increased
+// by the increment, limited to the tenant maximum, through IncreaseCapacity. A list already at the tenant maximum is
+// left unchanged, without an event. The transaction id is recorded in the same transaction, and a transaction id which
+// has already been applied is ignored, so replayed events cannot grant capacity twice.
+func (p *ProcessorImpl) ApplyCapacityItem(mb *message.Buffer) func(characterId uint32, worldId byte, itemId uint32, transactionId string) error {
+	return func(characterId uint32, worldId byte, itemId uint32, transactionId string) error {
+		inc, ok := p.c.Capacity().ItemIncrement(itemId)
+		if !ok {
+			return nil
+		}
+
+		txErr := database.ExecuteTransaction(p.db, func(tx *gorm.DB) error {
+			_, err := grantByTransactionIdEntityProvider(p.t.Id(), transactionId)(tx)()
+			if err == nil {
+				p.l.Debugf("Capacity item transaction [%s] for character [%d] has already been applied.", transactionId, characterId)
+				return nil
+			}
+			if !errors.Is(err, gorm.ErrRecordNotFound) {
+				return err
+			}
+
+			bl, err := p.WithTransaction(tx).GetByCharacterId(characterId)
+			if err != nil {
+				p.l.WithError(err).Errorf("Unable to retrieve buddy list for character [%d] to apply capacity item [%d].", characterId, itemId)
+				_ = mb.Put(list2.EnvStatusEventTopic, list3.ErrorStatusEventProvider(characterId, worldId, list2.StatusEventErrorCharacterNotFound))
+				return err
+			}
+
+			newCapacity := int(bl.Capacity()) + int(inc)
+			if newCapacity > int(p.c.Capacity().Maximum()) {
+				newCapacity = int(p.c.Capacity().Maximum())
+			}
+
+			err = recordGrant(tx, p.t.Id(), transactionId, characterId, itemId, inc)
+			if err != nil {
+				p.l.WithError(err).Errorf("Unable to record capacity item transaction [%s] for character [%d].", transactionId, characterId)
+				return err
+			}
+			if newCapacity == int(bl.Capacity()) {
+				p.l.Infof("Buddy list of character [%d] is already at the maximum capacity [%d], capacity item [%d] has no effect.", characterId, bl.Capacity(), itemId)
+				return nil
+			}
+			return p.withTransaction(tx).increaseCapacity(mb, ledger.SourceCashItem)(characterId, worldId, byte(newCapacity))
+		})
+		if txErr != nil {
+			p.l.WithError(txErr).Errorf("Unable to apply capacity item [%d] for character [%d].", itemId, characterId)
+			return txErr
+		}
+		return nil
+	}
+}
+
+// claimVersion advances the version of the given buddy list, on the condition that it is still the version read, and
+// that it matches the version required by IfMatch when one is set. The new version is returned, or ErrVersionConflict.
+func (p *ProcessorImpl) claimVersion(tx *gorm.DB, bl Model) (uint32, error) {
+	expected := bl.Version()
+	if p.ifMatch != nil && *p.ifMatch != expected {
+		p.l.Debugf("Buddy list of character [%d] is at version [%d], not the required [%d].", bl.CharacterId(), expected, *p.ifMatch)
+		return 0, ErrVersionConflict
+	}
+	v, err := incrementVersion(tx, p.t.Id(), bl.CharacterId(), &expected)
+	if err != nil {
+		p.l.WithError(err).Errorf("Unable to advance buddy list version of character [%d].", bl.CharacterId())
+	}
+	return v, err
+}
+
+// versionError maps a claimVersion error to the status event error it is reported with.
+func versionError(err error) string {
+	if errors.Is(err, ErrVersionConflict) {
+		return list2.StatusEventErrorVersionConflict
+	}
+	return list2.StatusEventErrorUnknownError
+}
+
+// auditMutation records a mutation of the buddy list of subjectId in the audit log, within the transaction of the
+// mutation. The originating command type and trace id are taken from the processor context.
+func (p *ProcessorImpl) auditMutation(tx *gorm.DB, actorId uint32, subjectId uint32, action string, before interface{}, after interface{}) error {
+	err := recordAudit(tx, p.t.Id(), actorId, subjectId, action, before, after, audit.CommandType(p.ctx), audit.TraceId(p.ctx))
+	if err != nil {
+		p.l.WithError(err).Errorf("Unable to record [%s] audit of character [%d] buddy list.", action, subjectId)
+	}
+	return err
+}
+
+// recordChange appends the change which advanced the buddy list of characterId to version to the list change feed,
+// within the transaction of the change.
+func (p *ProcessorImpl) recordChange(tx *gorm.DB, characterId uint32, version uint32, changeType string, buddyId uint32, data interface{}) error {
+	err := recordChange(tx, p.t.Id(), characterId, version, changeType, buddyId, data)
+	if err != nil {
+		p.l.WithError(err).Errorf("Unable to record [%s] change of character [%d] buddy list.", changeType, characterId)
+	}
+	return err
+}
+
+type presenceSnapshot struct {
+	ChannelId int8       `json:"channelId"`
+	LastSeen  *time.Time `json:"lastSeen,omitempty"`
+}
+
+func newPresenceSnapshot(channelId int8, lastSeen time.Time) interface{} {
+	ps := presenceSnapshot{ChannelId: channelId}
+	if !lastSeen.IsZero() {
+		ps.LastSeen = &lastSeen
+	}
+	return ps
+}
+
+type capacitySnapshot struct {
+	Capacity byte `json:"capacity"`
+	// Source is the capacity ledger source of the change, recorded only in the audit log.
+	Source string `json:"source,omitempty"`
+}
+
+func listSnapshot(m Model) interface{} {
+	rm, _ := Transform(m)
+	return rm
+}
+
+func settingsSnapshot(m settings.Model) interface{} {
+	rm, _ := settings.Transform(m)
+	return rm
+}
+
+func buddySnapshot(m buddy.Model) interface{} {
+	rm, _ := buddy.Transform(m)
+	return rm
+}
+
+func buddyEntitySnapshot(e buddy.Entity) interface{} {
+	m, _ := buddy.Make(e)
+	return buddySnapshot(m)
+}
+
+func newBuddySnapshot(characterId uint32, name string, group string, pending bool) interface{} {
+	return buddy.RestModel{
+		CharacterId:   characterId,
+		Group:         group,
+		CharacterName: name,
+		ChannelId:     -1,
+		Pending:       pending,
+	}
+}
+
+func lastSeenOf(e buddy.Entity) time.Time {
+	if e.LastSeen == nil {
+		return time.Time{}
+	}
+	return *e.LastSeen
+}
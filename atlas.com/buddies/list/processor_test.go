@@ -1,10 +1,25 @@
 package list
 
 import (
+	"atlas-buddies/audit"
+	"atlas-buddies/buddy"
+	"atlas-buddies/character"
+	"atlas-buddies/configuration"
+	list2 "atlas-buddies/kafka/message/list"
+	"atlas-buddies/ledger"
+	"atlas-buddies/settings"
 	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
 	"testing"
+	"time"
 
+	kafkaproducer "github.com/Chronicle20/atlas-kafka/producer"
+	"github.com/Chronicle20/atlas-model/model"
+	"github.com/Chronicle20/atlas-tenant"
 	"github.com/google/uuid"
+	"github.com/segmentio/kafka-go"
 	"github.com/sirupsen/logrus"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
@@ -24,7 +39,9 @@ func TestIncreaseCapacityIntegration(t *testing.T) {
 			tenant_id TEXT NOT NULL,
 			id TEXT PRIMARY KEY,
 			character_id INTEGER NOT NULL,
-			capacity INTEGER NOT NULL
+			world_id INTEGER,
+			capacity INTEGER NOT NULL,
+			version INTEGER NOT NULL DEFAULT 0
 		)
 	`).Error
 	if err != nil {
@@ -136,7 +153,9 @@ func TestIncreaseCapacityValidationBoundaries(t *testing.T) {
 			tenant_id TEXT NOT NULL,
 			id TEXT PRIMARY KEY,
 			character_id INTEGER NOT NULL,
-			capacity INTEGER NOT NULL
+			world_id INTEGER,
+			capacity INTEGER NOT NULL,
+			version INTEGER NOT NULL DEFAULT 0
 		)
 	`).Error
 	if err != nil {
@@ -219,7 +238,9 @@ func TestIncreaseCapacityDatabaseIntegration(t *testing.T) {
 			tenant_id TEXT NOT NULL,
 			id TEXT PRIMARY KEY,
 			character_id INTEGER NOT NULL,
-			capacity INTEGER NOT NULL
+			world_id INTEGER,
+			capacity INTEGER NOT NULL,
+			version INTEGER NOT NULL DEFAULT 0
 		)
 	`).Error
 	if err != nil {
@@ -354,7 +375,9 @@ func TestIncreaseCapacityTransactionIntegration(t *testing.T) {
 			tenant_id TEXT NOT NULL,
 			id TEXT PRIMARY KEY,
 			character_id INTEGER NOT NULL,
-			capacity INTEGER NOT NULL
+			world_id INTEGER,
+			capacity INTEGER NOT NULL,
+			version INTEGER NOT NULL DEFAULT 0
 		)
 	`).Error
 	if err != nil {
@@ -453,7 +476,9 @@ func TestIncreaseCapacityConcurrentIntegration(t *testing.T) {
 			tenant_id TEXT NOT NULL,
 			id TEXT PRIMARY KEY,
 			character_id INTEGER NOT NULL,
-			capacity INTEGER NOT NULL
+			world_id INTEGER,
+			capacity INTEGER NOT NULL,
+			version INTEGER NOT NULL DEFAULT 0
 		)
 	`).Error
 	if err != nil {
@@ -558,4 +583,763 @@ func createMockTenantContext(tenantId uuid.UUID) context.Context {
 	// we'll create a context that should work with the MustFromContext function
 	// This may need to be adjusted based on the actual tenant package implementation
 	return context.WithValue(context.Background(), "tenant", mockTenantModel)
-}
\ No newline at end of file
+}
+// recordedEvent is a status event captured by an eventRecorder.
+type recordedEvent struct {
+	CharacterId uint32          `json:"characterId"`
+	Version     uint32          `json:"version"`
+	Type        string          `json:"type"`
+	Body        json.RawMessage `json:"body"`
+}
+
+// eventRecorder captures the messages a processor produces in place of a Kafka writer.
+type eventRecorder struct {
+	events []recordedEvent
+}
+
+func (r *eventRecorder) provider(_ string) kafkaproducer.MessageProducer {
+	return func(provider model.Provider[[]kafka.Message]) error {
+		ms, err := provider()
+		if err != nil {
+			return err
+		}
+		for _, m := range ms {
+			var e recordedEvent
+			if err = json.Unmarshal(m.Value, &e); err != nil {
+				return err
+			}
+			r.events = append(r.events, e)
+		}
+		return nil
+	}
+}
+
+// ofType returns the recorded events of the given type, in the order they were produced.
+func (r *eventRecorder) ofType(eventType string) []recordedEvent {
+	results := make([]recordedEvent, 0)
+	for _, e := range r.events {
+		if e.Type == eventType {
+			results = append(results, e)
+		}
+	}
+	return results
+}
+
+// errorOf returns the error code carried by an ERROR event.
+func errorOf(t *testing.T, e recordedEvent) string {
+	var body list2.ErrorStatusEventBody
+	if err := json.Unmarshal(e.Body, &body); err != nil {
+		t.Fatalf("Failed to decode error body: %v", err)
+	}
+	return body.Error
+}
+
+type mockCharacterProcessor struct {
+	characters map[uint32]character.Model
+}
+
+func (m mockCharacterProcessor) GetById(characterId uint32) (character.Model, error) {
+	c, ok := m.characters[characterId]
+	if !ok {
+		return character.Model{}, errors.New("character not found")
+	}
+	return c, nil
+}
+
+// mockInviteProcessor records the invite commands a processor produces.
+type mockInviteProcessor struct {
+	created  [][2]uint32
+	rejected [][2]uint32
+}
+
+func (m *mockInviteProcessor) Create(actorId uint32, _ byte, targetId uint32) error {
+	m.created = append(m.created, [2]uint32{actorId, targetId})
+	return nil
+}
+
+func (m *mockInviteProcessor) Reject(actorId uint32, _ byte, originatorId uint32) error {
+	m.rejected = append(m.rejected, [2]uint32{actorId, originatorId})
+	return nil
+}
+
+// newTestProcessor builds a processor over db for a new tenant, resolving the given configuration. Status events are
+// captured by the returned recorder and invite commands by the returned invite processor.
+func newTestProcessor(db *gorm.DB, c configuration.Configuration, characters ...character.RestModel) (*ProcessorImpl, *eventRecorder, *mockInviteProcessor) {
+	l := logrus.New()
+	l.SetLevel(logrus.DebugLevel)
+	t, _ := tenant.Create(uuid.New(), "GMS", 83, 1)
+	ctx := tenant.WithContext(context.Background(), t)
+
+	cs := make(map[uint32]character.Model)
+	for _, rm := range characters {
+		cm, _ := character.Extract(rm)
+		cs[rm.Id] = cm
+	}
+	r := &eventRecorder{}
+	ip := &mockInviteProcessor{}
+	return &ProcessorImpl{
+		l:   l,
+		ctx: ctx,
+		db:  db,
+		t:   t,
+		p:   r.provider,
+		cp:  mockCharacterProcessor{characters: cs},
+		ip:  ip,
+		sp:  settings.NewProcessor(l, ctx, db),
+		c:   c.Resolve(t.Id(), t.Region(), t.MajorVersion()),
+	}, r, ip
+}
+
+// createTestList creates the buddy list of a character in world 0, with the given entries.
+func createTestList(t *testing.T, p *ProcessorImpl, characterId uint32, capacity byte, buddies ...buddy.Entity) uuid.UUID {
+	worldId := byte(0)
+	listId := uuid.New()
+	err := p.db.Create(&Entity{TenantId: p.t.Id(), Id: listId, CharacterId: characterId, WorldId: &worldId, Capacity: capacity, Version: 1}).Error
+	if err != nil {
+		t.Fatalf("Failed to create buddy list: %v", err)
+	}
+	for _, b := range buddies {
+		b.ListId = listId
+		if err = p.db.Create(&b).Error; err != nil {
+			t.Fatalf("Failed to create buddy: %v", err)
+		}
+	}
+	return listId
+}
+
+func TestSetCapacityUnsupportedOverflowPolicy(t *testing.T) {
+	db, err := setupTestDB()
+	if err != nil {
+		t.Fatalf("Failed to setup test database: %v", err)
+	}
+	p, r, _ := newTestProcessor(db, configuration.Configuration{})
+	createTestList(t, p, 1, 20)
+
+	err = p.SetCapacityAndEmit(1, 0, 10, "TRIM_ALL")
+	if err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+	es := r.ofType(list2.StatusEventTypeError)
+	if len(es) != 1 || errorOf(t, es[0]) != list2.StatusEventErrorInvalidCapacity {
+		t.Fatalf("Expected a single INVALID_CAPACITY error, got %+v", r.events)
+	}
+
+	bl, err := p.GetByCharacterId(1)
+	if err != nil {
+		t.Fatalf("Failed to retrieve buddy list: %v", err)
+	}
+	if bl.Capacity() != 20 {
+		t.Errorf("Expected capacity to remain 20, got %d", bl.Capacity())
+	}
+}
+
+func TestSetCapacityTrimPendingRejectsInvites(t *testing.T) {
+	db, err := setupTestDB()
+	if err != nil {
+		t.Fatalf("Failed to setup test database: %v", err)
+	}
+	p, r, ip := newTestProcessor(db, configuration.Configuration{})
+	now := time.Now()
+	createTestList(t, p, 1, 20,
+		buddy.Entity{CharacterId: 2, CharacterName: "Confirmed", CreatedAt: now.Add(-3 * time.Hour)},
+		buddy.Entity{CharacterId: 3, CharacterName: "Older", Pending: true, CreatedAt: now.Add(-2 * time.Hour)},
+		buddy.Entity{CharacterId: 4, CharacterName: "Newer", Pending: true, CreatedAt: now.Add(-1 * time.Hour)},
+	)
+
+	err = p.SetCapacityAndEmit(1, 0, 2, list2.OverflowPolicyTrimPending)
+	if err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+
+	removed := r.ofType(list2.StatusEventTypeBuddyRemoved)
+	if len(removed) != 1 {
+		t.Fatalf("Expected a single BUDDY_REMOVED event, got %+v", r.events)
+	}
+	if len(r.ofType(list2.StatusEventTypeBuddyCapacityUpdate)) != 1 {
+		t.Errorf("Expected a CAPACITY_CHANGE event, got %+v", r.events)
+	}
+	if len(ip.rejected) != 1 || ip.rejected[0] != [2]uint32{4, 1} {
+		t.Errorf("Expected the invite of character 1 to character 4 to be rejected, got %v", ip.rejected)
+	}
+
+	bl, err := p.GetByCharacterId(1)
+	if err != nil {
+		t.Fatalf("Failed to retrieve buddy list: %v", err)
+	}
+	if bl.Capacity() != 2 || len(bl.Buddies()) != 2 {
+		t.Errorf("Expected capacity 2 holding 2 buddies, got capacity %d holding %d", bl.Capacity(), len(bl.Buddies()))
+	}
+}
+
+func TestCreateExistingList(t *testing.T) {
+	db, err := setupTestDB()
+	if err != nil {
+		t.Fatalf("Failed to setup test database: %v", err)
+	}
+	p, _, _ := newTestProcessor(db, configuration.Configuration{})
+	createTestList(t, p, 1, 20)
+
+	_, err = p.CreateAndEmit(1, 0, 30)
+	if !errors.Is(err, ErrListExists) {
+		t.Fatalf("Expected ErrListExists, got: %v", err)
+	}
+
+	bl, err := p.GetByCharacterId(1)
+	if err != nil {
+		t.Fatalf("Failed to retrieve buddy list: %v", err)
+	}
+	if bl.Capacity() != 20 {
+		t.Errorf("Expected the existing list to keep capacity 20, got %d", bl.Capacity())
+	}
+}
+
+func TestDelete(t *testing.T) {
+	stale := uint32(0)
+	tests := []struct {
+		name           string
+		ifMatch        *uint32
+		createList     bool
+		expectedType   string
+		expectedError  string
+		expectedExists bool
+	}{
+		{name: "empty list", createList: true, expectedType: list2.StatusEventTypeListDeleted},
+		{name: "stale If-Match", ifMatch: &stale, createList: true, expectedType: list2.StatusEventTypeError, expectedError: list2.StatusEventErrorVersionConflict, expectedExists: true},
+		{name: "no list", expectedType: list2.StatusEventTypeError, expectedError: list2.StatusEventErrorCharacterNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, err := setupTestDB()
+			if err != nil {
+				t.Fatalf("Failed to setup test database: %v", err)
+			}
+			p, r, _ := newTestProcessor(db, configuration.Configuration{})
+			if tt.createList {
+				createTestList(t, p, 1, 20)
+			}
+
+			err = p.IfMatch(tt.ifMatch).DeleteAndEmit(1, 0)
+			if err != nil {
+				t.Fatalf("Expected no error, but got: %v", err)
+			}
+
+			if len(r.events) != 1 || r.events[0].CharacterId != 1 || r.events[0].Type != tt.expectedType {
+				t.Fatalf("Expected a single %s event to character 1, got %+v", tt.expectedType, r.events)
+			}
+			if tt.expectedError != "" && errorOf(t, r.events[0]) != tt.expectedError {
+				t.Errorf("Expected error %s, got %s", tt.expectedError, errorOf(t, r.events[0]))
+			}
+			_, err = p.GetByCharacterId(1)
+			if exists := err == nil; exists != tt.expectedExists {
+				t.Errorf("Expected list to exist [%t], got error: %v", tt.expectedExists, err)
+			}
+		})
+	}
+}
+
+func TestDeleteRemovesCharacterFromBuddies(t *testing.T) {
+	db, err := setupTestDB()
+	if err != nil {
+		t.Fatalf("Failed to setup test database: %v", err)
+	}
+	p, r, _ := newTestProcessor(db, configuration.Configuration{})
+	createTestList(t, p, 1, 20, buddy.Entity{CharacterId: 2, CharacterName: "Other"})
+	createTestList(t, p, 2, 20, buddy.Entity{CharacterId: 1, CharacterName: "Deleted"})
+
+	err = p.DeleteAndEmit(1, 0)
+	if err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+
+	removed := r.ofType(list2.StatusEventTypeBuddyRemoved)
+	if len(removed) != 1 || removed[0].CharacterId != 2 || removed[0].Version != 2 {
+		t.Errorf("Expected a BUDDY_REMOVED event to character 2 at version 2, got %+v", r.events)
+	}
+	deleted := r.ofType(list2.StatusEventTypeListDeleted)
+	if len(deleted) != 1 || deleted[0].CharacterId != 1 || deleted[0].Version != 1 {
+		t.Errorf("Expected a LIST_DELETED event to character 1 at version 1, got %+v", r.events)
+	}
+
+	obl, err := p.GetByCharacterId(2)
+	if err != nil {
+		t.Fatalf("Failed to retrieve buddy list: %v", err)
+	}
+	if len(obl.Buddies()) != 0 {
+		t.Errorf("Expected character 1 to be removed from the buddy list of character 2, got %+v", obl.Buddies())
+	}
+}
+
+func TestDeleteWithOneSidedBuddies(t *testing.T) {
+	db, err := setupTestDB()
+	if err != nil {
+		t.Fatalf("Failed to setup test database: %v", err)
+	}
+	p, r, _ := newTestProcessor(db, configuration.Configuration{})
+	// 2 removed 1 on their side, and 3 has no buddy list.
+	createTestList(t, p, 1, 20, buddy.Entity{CharacterId: 2, CharacterName: "Two"}, buddy.Entity{CharacterId: 3, CharacterName: "Three"})
+	createTestList(t, p, 2, 20)
+
+	err = p.DeleteAndEmit(1, 0)
+	if err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+
+	if len(r.events) != 1 || r.events[0].Type != list2.StatusEventTypeListDeleted || r.events[0].CharacterId != 1 {
+		t.Errorf("Expected only a LIST_DELETED event to character 1, got %+v", r.events)
+	}
+	if _, err = p.GetByCharacterId(1); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("Expected the buddy list to be deleted, got error: %v", err)
+	}
+	obl, err := p.GetByCharacterId(2)
+	if err != nil || obl.Version() != 1 {
+		t.Errorf("Expected the buddy list of 2 to be left at version 1, got %+v, %v", obl, err)
+	}
+}
+
+func TestPurge(t *testing.T) {
+	db, err := setupTestDB()
+	if err != nil {
+		t.Fatalf("Failed to setup test database: %v", err)
+	}
+	p, r, _ := newTestProcessor(db, configuration.Configuration{})
+	createTestList(t, p, 1, 20)
+
+	for _, characterId := range []uint32{1, 2} {
+		if err = p.PurgeAndEmit(characterId, 0); err != nil {
+			t.Fatalf("Expected no error, but got: %v", err)
+		}
+	}
+
+	if len(r.events) != 1 || r.events[0].Type != list2.StatusEventTypeListDeleted || r.events[0].CharacterId != 1 {
+		t.Errorf("Expected only a LIST_DELETED event to character 1, got %+v", r.events)
+	}
+}
+
+func TestApplyCapacityItem(t *testing.T) {
+	maximum := byte(20)
+	c := configuration.Configuration{Defaults: configuration.Settings{Capacity: &configuration.CapacitySettings{
+		Maximum: &maximum,
+		Items:   map[uint32]byte{5000: 5},
+	}}}
+
+	tests := []struct {
+		name             string
+		capacity         byte
+		expectedCapacity byte
+		expectedEvents   int
+	}{
+		{"Item used below maximum", 10, 15, 1},
+		{"Item used near maximum", 18, 20, 1},
+		{"Item used at maximum", 20, 20, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, err := setupTestDB()
+			if err != nil {
+				t.Fatalf("Failed to setup test database: %v", err)
+			}
+			p, r, _ := newTestProcessor(db, c)
+			createTestList(t, p, 1, tt.capacity)
+
+			err = p.ApplyCapacityItemAndEmit(1, 0, 5000, "txn-1")
+			if err != nil {
+				t.Fatalf("Expected no error, but got: %v", err)
+			}
+			if len(r.ofType(list2.StatusEventTypeBuddyCapacityUpdate)) != tt.expectedEvents || len(r.ofType(list2.StatusEventTypeError)) != 0 {
+				t.Errorf("Expected %d CAPACITY_CHANGE events and no errors, got %+v", tt.expectedEvents, r.events)
+			}
+
+			bl, err := p.GetByCharacterId(1)
+			if err != nil {
+				t.Fatalf("Failed to retrieve buddy list: %v", err)
+			}
+			if bl.Capacity() != tt.expectedCapacity {
+				t.Errorf("Expected capacity %d, got %d", tt.expectedCapacity, bl.Capacity())
+			}
+			if _, err = grantByTransactionIdEntityProvider(p.t.Id(), "txn-1")(db)(); err != nil {
+				t.Errorf("Expected the transaction to be recorded, but got: %v", err)
+			}
+
+			// a replayed transaction grants nothing further.
+			err = p.ApplyCapacityItemAndEmit(1, 0, 5000, "txn-1")
+			if err != nil {
+				t.Fatalf("Expected no error, but got: %v", err)
+			}
+			if len(r.ofType(list2.StatusEventTypeBuddyCapacityUpdate)) != tt.expectedEvents {
+				t.Errorf("Expected a replay to emit no event, got %+v", r.events)
+			}
+		})
+	}
+}
+
+func TestAuditedPairSettingsPresenceAndCapacityChanges(t *testing.T) {
+	db, err := setupTestDB()
+	if err != nil {
+		t.Fatalf("Failed to setup test database: %v", err)
+	}
+	p, _, _ := newTestProcessor(db, configuration.Configuration{},
+		character.RestModel{Id: 3, Name: "Three"},
+		character.RestModel{Id: 4, Name: "Four"},
+	)
+	createTestList(t, p, 1, 20, buddy.Entity{CharacterId: 2, Group: "Friends", CharacterName: "Two"})
+	createTestList(t, p, 2, 20, buddy.Entity{CharacterId: 1, Group: "Friends", CharacterName: "One"})
+	createTestList(t, p, 3, 20, buddy.Entity{CharacterId: 4, Group: "Friends", CharacterName: "Four"})
+	createTestList(t, p, 4, 20)
+
+	if err = p.RequestAddBuddyAndEmit(4, 0, 3, "Friends"); err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+	for _, subjectId := range []uint32{3, 4} {
+		as, err := p.GetAudits(subjectId, time.Time{}, time.Time{})
+		if err != nil {
+			t.Fatalf("Failed to retrieve audits: %v", err)
+		}
+		audited := false
+		for _, a := range as {
+			audited = audited || (a.Action() == audit.ActionBuddyAdded && a.ActorId() == 4 && a.SubjectId() == subjectId)
+		}
+		if !audited {
+			t.Errorf("Expected the completed pair to be audited on the list of %d", subjectId)
+		}
+	}
+
+	if err = p.UpdateBuddyChannelAndEmit(1, 0, 3, 100000000); err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+	decline := true
+	if err = p.UpdateSettingsAndEmit(1, 0, settings.Change{DeclineRequests: &decline}); err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+	if err = p.IncreaseCapacityAndEmit(1, 0, 30); err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+
+	as, err := p.GetAudits(1, time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatalf("Failed to retrieve audits: %v", err)
+	}
+	byAction := make(map[string]audit.RestModel)
+	for _, a := range as {
+		rm, _ := audit.Transform(a)
+		byAction[a.Action()] = rm
+	}
+
+	if a, ok := byAction[audit.ActionPresenceChanged]; !ok || a.ActorId != 1 || a.SubjectId != 2 {
+		t.Errorf("Expected presence change of 1 on the list of 2 to be audited, got %+v", a)
+	}
+	if a, ok := byAction[audit.ActionSettingsChanged]; !ok || a.Before == nil || a.After == nil {
+		t.Errorf("Expected settings change to be audited with both snapshots, got %+v", a)
+	} else {
+		var after settings.RestModel
+		if err = json.Unmarshal(a.After, &after); err != nil || after.DeclineRequests == nil || !*after.DeclineRequests {
+			t.Errorf("Expected settings snapshot to decline requests, got %s", a.After)
+		}
+	}
+	if a, ok := byAction[audit.ActionCapacityChanged]; !ok {
+		t.Errorf("Expected capacity change to be audited")
+	} else {
+		var after capacitySnapshot
+		if err = json.Unmarshal(a.After, &after); err != nil || after.Capacity != 30 || after.Source != ledger.SourceCommand {
+			t.Errorf("Expected capacity 30 from source COMMAND, got %s", a.After)
+		}
+	}
+}
+
+func TestSettingsChangeVersion(t *testing.T) {
+	db, err := setupTestDB()
+	if err != nil {
+		t.Fatalf("Failed to setup test database: %v", err)
+	}
+	p, r, _ := newTestProcessor(db, configuration.Configuration{})
+	createTestList(t, p, 1, 20)
+
+	decline := true
+	for _, characterId := range []uint32{1, 2} {
+		if err = p.UpdateSettingsAndEmit(characterId, 0, settings.Change{DeclineRequests: &decline}); err != nil {
+			t.Fatalf("Expected no error, but got: %v", err)
+		}
+	}
+
+	es := r.ofType(list2.StatusEventTypeSettingsChange)
+	if len(es) != 2 || es[0].CharacterId != 1 || es[0].Version != 1 || es[1].CharacterId != 2 || es[1].Version != 0 {
+		t.Errorf("Expected SETTINGS_CHANGE at version 1 for the list of 1 and without a version for 2, got %+v", es)
+	}
+}
+
+func TestAuditsIncludeCapacityLedger(t *testing.T) {
+	db, err := setupTestDB()
+	if err != nil {
+		t.Fatalf("Failed to setup test database: %v", err)
+	}
+	p, _, _ := newTestProcessor(db, configuration.Configuration{})
+	if _, err = p.CreateAndEmit(1, 0, 20); err != nil {
+		t.Fatalf("Failed to create buddy list: %v", err)
+	}
+	if err = p.IncreaseCapacityAndEmit(1, 0, 30); err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+
+	as, err := p.GetAudits(1, time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatalf("Failed to retrieve audits: %v", err)
+	}
+	var recorded []capacitySnapshot
+	for i, a := range as {
+		if i > 0 && a.CreatedAt().Before(as[i-1].CreatedAt()) {
+			t.Errorf("Expected audits oldest first, got %s before %s", as[i-1].CreatedAt(), a.CreatedAt())
+		}
+		if a.Action() != audit.ActionCapacityRecorded {
+			continue
+		}
+		rm, _ := audit.Transform(a)
+		var after capacitySnapshot
+		if err = json.Unmarshal(rm.After, &after); err != nil {
+			t.Fatalf("Failed to decode ledger snapshot: %v", err)
+		}
+		recorded = append(recorded, after)
+	}
+	expected := []capacitySnapshot{{Capacity: 20, Source: ledger.SourceCommand}, {Capacity: 30, Source: ledger.SourceCommand}}
+	if !reflect.DeepEqual(recorded, expected) {
+		t.Errorf("Expected ledger entries %+v in the audits, got %+v", expected, recorded)
+	}
+}
+
+func TestRequestAddBuddyDeclined(t *testing.T) {
+	db, err := setupTestDB()
+	if err != nil {
+		t.Fatalf("Failed to setup test database: %v", err)
+	}
+	p, r, ip := newTestProcessor(db, configuration.Configuration{},
+		character.RestModel{Id: 1, Name: "One"},
+		character.RestModel{Id: 2, Name: "Two"},
+	)
+	createTestList(t, p, 1, 20)
+	createTestList(t, p, 2, 20)
+	decline := true
+	err = p.UpdateSettingsAndEmit(2, 0, settings.Change{DeclineRequests: &decline})
+	if err != nil {
+		t.Fatalf("Failed to update settings: %v", err)
+	}
+	r.events = nil
+
+	err = p.RequestAddBuddyAndEmit(1, 0, 2, "Friends")
+	if err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+
+	if len(r.events) != 0 {
+		t.Errorf("Expected no event for a declined request, got %+v", r.events)
+	}
+	if len(ip.created) != 0 {
+		t.Errorf("Expected no invite for a declined request, got %v", ip.created)
+	}
+	bl, err := p.GetByCharacterId(1)
+	if err != nil {
+		t.Fatalf("Failed to retrieve buddy list: %v", err)
+	}
+	if len(bl.Buddies()) != 0 || bl.Version() != 1 {
+		t.Errorf("Expected the requester list unchanged at version 1, got %+v at version %d", bl.Buddies(), bl.Version())
+	}
+}
+
+func TestRequestAddBuddyCompletingPairAdvancesTargetVersion(t *testing.T) {
+	db, err := setupTestDB()
+	if err != nil {
+		t.Fatalf("Failed to setup test database: %v", err)
+	}
+	p, r, _ := newTestProcessor(db, configuration.Configuration{},
+		character.RestModel{Id: 1, Name: "One"},
+		character.RestModel{Id: 2, Name: "Two"},
+	)
+	createTestList(t, p, 1, 20)
+	createTestList(t, p, 2, 20, buddy.Entity{CharacterId: 1, Group: "Friends", CharacterName: "One"})
+
+	err = p.RequestAddBuddyAndEmit(1, 0, 2, "Friends")
+	if err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+
+	versions := make(map[uint32]uint32)
+	for _, e := range r.ofType(list2.StatusEventTypeBuddyAdded) {
+		versions[e.CharacterId] = e.Version
+	}
+	if len(versions) != 2 || versions[1] != 2 || versions[2] != 2 {
+		t.Fatalf("Expected BUDDY_ADDED at version 2 to both characters, got %+v", r.events)
+	}
+
+	tbl, err := p.GetByCharacterId(2)
+	if err != nil {
+		t.Fatalf("Failed to retrieve buddy list: %v", err)
+	}
+	if tbl.Version() != 2 {
+		t.Errorf("Expected the target list at version 2, got %d", tbl.Version())
+	}
+	cs, err := p.GetChangesSince(2, 1)
+	if err != nil {
+		t.Fatalf("Failed to retrieve changes: %v", err)
+	}
+	if len(cs) != 1 || cs[0].Version() != 2 {
+		t.Errorf("Expected the change feed of the target to hold version 2, got %+v", cs)
+	}
+}
+
+func TestPresenceChangeConflictsWithIfMatch(t *testing.T) {
+	db, err := setupTestDB()
+	if err != nil {
+		t.Fatalf("Failed to setup test database: %v", err)
+	}
+	p, r, _ := newTestProcessor(db, configuration.Configuration{})
+	createTestList(t, p, 1, 20, buddy.Entity{CharacterId: 2, Group: "Friends", CharacterName: "Two"})
+	createTestList(t, p, 2, 20, buddy.Entity{CharacterId: 1, Group: "Friends", CharacterName: "One"})
+
+	bl, err := p.GetByCharacterId(1)
+	if err != nil {
+		t.Fatalf("Failed to retrieve buddy list: %v", err)
+	}
+	read := bl.Version()
+
+	// a buddy logging in changes the list of character 1, so the version read is stale.
+	if err = p.UpdateBuddyChannelAndEmit(2, 0, 1, 100000000); err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+	if err = p.IfMatch(&read).SetCapacityAndEmit(1, 0, 25, ""); err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+
+	es := r.ofType(list2.StatusEventTypeError)
+	if len(es) != 1 || errorOf(t, es[0]) != list2.StatusEventErrorVersionConflict {
+		t.Fatalf("Expected a VERSION_CONFLICT error, got %+v", r.events)
+	}
+	bl, err = p.GetByCharacterId(1)
+	if err != nil {
+		t.Fatalf("Failed to retrieve buddy list: %v", err)
+	}
+	if bl.Capacity() != 20 {
+		t.Errorf("Expected capacity to remain 20, got %d", bl.Capacity())
+	}
+}
+
+// channelChangesOf returns the channels carried by the recorded BUDDY_CHANNEL_CHANGE events, keyed by recipient.
+func channelChangesOf(t *testing.T, r *eventRecorder) map[uint32][]int8 {
+	results := make(map[uint32][]int8)
+	for _, e := range r.ofType(list2.StatusEventTypeBuddyChannelChange) {
+		var body list2.BuddyChannelChangeStatusEventBody
+		if err := json.Unmarshal(e.Body, &body); err != nil {
+			t.Fatalf("Failed to decode channel change body: %v", err)
+		}
+		results[e.CharacterId] = append(results[e.CharacterId], body.ChannelId)
+	}
+	return results
+}
+
+// shownChannel returns the channel the list of the owner shows the buddy on.
+func shownChannel(t *testing.T, p *ProcessorImpl, ownerId uint32, characterId uint32) int8 {
+	bl, err := p.GetByCharacterId(ownerId)
+	if err != nil {
+		t.Fatalf("Failed to retrieve buddy list: %v", err)
+	}
+	b, ok := bl.Buddy(characterId)
+	if !ok {
+		t.Fatalf("Expected [%d] on the list of [%d]", characterId, ownerId)
+	}
+	return b.ChannelId()
+}
+
+func TestHiddenChannelChange(t *testing.T) {
+	db, err := setupTestDB()
+	if err != nil {
+		t.Fatalf("Failed to setup test database: %v", err)
+	}
+	p, r, _ := newTestProcessor(db, configuration.Configuration{})
+	createTestList(t, p, 1, 20, buddy.Entity{CharacterId: 2, Group: "Friends", CharacterName: "Two"})
+	createTestList(t, p, 2, 20, buddy.Entity{CharacterId: 1, Group: "Friends", CharacterName: "One"})
+
+	if err = p.UpdateBuddyChannelAndEmit(1, 0, 3, 100000000); err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+	hidden := settings.VisibilityHidden
+	if err = p.UpdateSettingsAndEmit(1, 0, settings.Change{Visibility: &hidden}); err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+	if cs := channelChangesOf(t, r); len(cs[2]) == 0 || cs[2][len(cs[2])-1] != -1 {
+		t.Fatalf("Expected 2 to be shown 1 offline once hidden, got %v", cs)
+	}
+	r.events = nil
+
+	if err = p.UpdateBuddyChannelAndEmit(1, 0, 5, 100000000); err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+	if cs := channelChangesOf(t, r); len(cs) != 0 {
+		t.Errorf("Expected no channel change for a hidden character, got %v", cs)
+	}
+	if c := shownChannel(t, p, 2, 1); c != -1 {
+		t.Errorf("Expected 1 to remain offline on the list of 2, got channel %d", c)
+	}
+}
+
+func TestHiddenFromGroupsRoundTrip(t *testing.T) {
+	db, err := setupTestDB()
+	if err != nil {
+		t.Fatalf("Failed to setup test database: %v", err)
+	}
+	p, r, _ := newTestProcessor(db, configuration.Configuration{})
+	createTestList(t, p, 1, 20, buddy.Entity{CharacterId: 2, Group: "Guild", CharacterName: "Two"})
+	createTestList(t, p, 2, 20, buddy.Entity{CharacterId: 1, Group: "Friends", CharacterName: "One"})
+	if err = p.UpdateBuddyChannelAndEmit(1, 0, 3, 100000000); err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		visibility string
+		groups     []string
+		expected   []int8
+		shown      int8
+	}{
+		{"Other group hidden", settings.VisibilityHiddenFromGroups, []string{"Friends"}, nil, 3},
+		{"Own group hidden", settings.VisibilityHiddenFromGroups, []string{"Guild"}, []int8{-1}, -1},
+		{"Visible again", settings.VisibilityVisible, nil, []int8{3}, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r.events = nil
+			if err = p.UpdateSettingsAndEmit(1, 0, settings.Change{Visibility: &tt.visibility, HiddenGroups: tt.groups}); err != nil {
+				t.Fatalf("Expected no error, but got: %v", err)
+			}
+			if cs := channelChangesOf(t, r); !reflect.DeepEqual(cs[2], tt.expected) || len(cs) > 1 {
+				t.Errorf("Expected channel changes %v for 2, got %v", tt.expected, cs)
+			}
+			if c := shownChannel(t, p, 2, 1); c != tt.shown {
+				t.Errorf("Expected 1 on channel %d on the list of 2, got %d", tt.shown, c)
+			}
+		})
+	}
+}
+
+func TestVisibilityWithoutRecordedPresence(t *testing.T) {
+	db, err := setupTestDB()
+	if err != nil {
+		t.Fatalf("Failed to setup test database: %v", err)
+	}
+	p, r, _ := newTestProcessor(db, configuration.Configuration{})
+	createTestList(t, p, 1, 20, buddy.Entity{CharacterId: 2, Group: "Friends", CharacterName: "Two"})
+	createTestList(t, p, 2, 20, buddy.Entity{CharacterId: 1, Group: "Friends", CharacterName: "One", ChannelId: 4, MapId: 100000000})
+
+	hidden := settings.VisibilityHidden
+	if err = p.UpdateSettingsAndEmit(1, 0, settings.Change{Visibility: &hidden}); err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+	visible := settings.VisibilityVisible
+	if err = p.UpdateSettingsAndEmit(1, 0, settings.Change{Visibility: &visible}); err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+
+	if cs := channelChangesOf(t, r); !reflect.DeepEqual(cs[2], []int8{-1, 4}) {
+		t.Errorf("Expected 2 to be shown 1 offline and back on channel 4, got %v", cs)
+	}
+	if c := shownChannel(t, p, 2, 1); c != 4 {
+		t.Errorf("Expected 1 on channel 4 on the list of 2, got %d", c)
+	}
+}
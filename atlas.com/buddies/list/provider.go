@@ -1,10 +1,18 @@
 package list
 
 import (
+	"atlas-buddies/audit"
+	"atlas-buddies/buddy"
+	"atlas-buddies/change"
 	"atlas-buddies/database"
+	"atlas-buddies/grant"
+	"atlas-buddies/ledger"
+	presence2 "atlas-buddies/presence"
 	"github.com/Chronicle20/atlas-model/model"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
 )
 
 func byCharacterIdEntityProvider(tenantId uuid.UUID, characterId uint32) database.EntityProvider[Entity] {
@@ -17,3 +25,170 @@ func byCharacterIdEntityProvider(tenantId uuid.UUID, characterId uint32) databas
 		return model.FixedProvider[Entity](result)
 	}
 }
+
+// byCharacterIdsEntityProvider provides the buddy lists of the given characters with their buddies preloaded.
+// Characters without a buddy list are omitted.
+func byCharacterIdsEntityProvider(tenantId uuid.UUID, characterIds []uint32) database.EntityProvider[[]Entity] {
+	return func(db *gorm.DB) model.Provider[[]Entity] {
+		var results []Entity
+		err := db.Where("tenant_id = ? AND character_id IN ?", tenantId, characterIds).Preload("Buddies").Find(&results).Error
+		if err != nil {
+			return model.ErrorProvider[[]Entity](err)
+		}
+		return model.FixedProvider(results)
+	}
+}
+
+// edgeEntity is an entry one character holds of another on its buddy list.
+type edgeEntity struct {
+	OwnerId uint32
+	BuddyId uint32
+	Pending bool
+}
+
+// edgesEntityProvider provides the entries the character holds of the others, and the entries the others hold of the
+// character, without loading either buddy list.
+func edgesEntityProvider(tenantId uuid.UUID, characterId uint32, otherIds []uint32) database.EntityProvider[[]edgeEntity] {
+	return func(db *gorm.DB) model.Provider[[]edgeEntity] {
+		var results []edgeEntity
+		err := db.Table("buddies").
+			Select("lists.character_id AS owner_id, buddies.character_id AS buddy_id, buddies.pending").
+			Joins("JOIN lists ON lists.id = buddies.list_id").
+			Where("lists.tenant_id = ? AND ((lists.character_id = ? AND buddies.character_id IN ?) OR (lists.character_id IN ? AND buddies.character_id = ?))", tenantId, characterId, otherIds, otherIds, characterId).
+			Scan(&results).Error
+		if err != nil {
+			return model.ErrorProvider[[]edgeEntity](err)
+		}
+		return model.FixedProvider(results)
+	}
+}
+
+func byCharacterIdWithoutBuddiesEntityProvider(tenantId uuid.UUID, characterId uint32) database.EntityProvider[Entity] {
+	return func(db *gorm.DB) model.Provider[Entity] {
+		return database.Query[Entity](db, &Entity{TenantId: tenantId, CharacterId: characterId})
+	}
+}
+
+func grantByTransactionIdEntityProvider(tenantId uuid.UUID, transactionId string) database.EntityProvider[grant.Entity] {
+	return func(db *gorm.DB) model.Provider[grant.Entity] {
+		return database.Query[grant.Entity](db, &grant.Entity{TenantId: tenantId, TransactionId: transactionId})
+	}
+}
+
+func presenceByCharacterIdEntityProvider(tenantId uuid.UUID, characterId uint32) database.EntityProvider[presence2.Entity] {
+	return func(db *gorm.DB) model.Provider[presence2.Entity] {
+		return database.Query[presence2.Entity](db, &presence2.Entity{TenantId: tenantId, CharacterId: characterId})
+	}
+}
+
+// shownPresenceEntityProvider provides an entry another list holds of the character in which the character is shown
+// online, for when the actual presence of the character was never recorded.
+func shownPresenceEntityProvider(tenantId uuid.UUID, characterId uint32) database.EntityProvider[buddy.Entity] {
+	return func(db *gorm.DB) model.Provider[buddy.Entity] {
+		var result buddy.Entity
+		err := db.Table("buddies").
+			Select("buddies.*").
+			Joins("JOIN lists ON lists.id = buddies.list_id").
+			Where("lists.tenant_id = ? AND buddies.character_id = ? AND buddies.pending = ? AND buddies.channel_id >= 0", tenantId, characterId, false).
+			First(&result).Error
+		if err != nil {
+			return model.ErrorProvider[buddy.Entity](err)
+		}
+		return model.FixedProvider(result)
+	}
+}
+
+// capacityChangesByCharacterIdEntityProvider provides the capacity ledger entries of a character, oldest first. Zero from
+// and to times leave the range open.
+func capacityChangesByCharacterIdEntityProvider(tenantId uuid.UUID, characterId uint32, from time.Time, to time.Time) database.EntityProvider[[]ledger.Entity] {
+	return func(db *gorm.DB) model.Provider[[]ledger.Entity] {
+		q := db.Where(&ledger.Entity{TenantId: tenantId, CharacterId: characterId})
+		if !from.IsZero() {
+			q = q.Where("created_at >= ?", from)
+		}
+		if !to.IsZero() {
+			q = q.Where("created_at < ?", to)
+		}
+		var results []ledger.Entity
+		err := q.Order("created_at").Find(&results).Error
+		if err != nil {
+			return model.ErrorProvider[[]ledger.Entity](err)
+		}
+		return model.FixedProvider(results)
+	}
+}
+
+// changesSinceEntityProvider provides the retained changes of a character's buddy list after the given version, in
+// version order.
+func changesSinceEntityProvider(tenantId uuid.UUID, characterId uint32, since uint32) database.EntityProvider[[]change.Entity] {
+	return func(db *gorm.DB) model.Provider[[]change.Entity] {
+		var results []change.Entity
+		err := db.Where("tenant_id = ? AND character_id = ? AND version > ?", tenantId, characterId, since).Order("version").Find(&results).Error
+		if err != nil {
+			return model.ErrorProvider[[]change.Entity](err)
+		}
+		return model.FixedProvider(results)
+	}
+}
+
+// auditsByCharacterIdEntityProvider provides the audit log entries in which the character is either the actor or the
+// subject, oldest first. Zero from and to times leave the range open.
+func auditsByCharacterIdEntityProvider(tenantId uuid.UUID, characterId uint32, from time.Time, to time.Time) database.EntityProvider[[]audit.Entity] {
+	return func(db *gorm.DB) model.Provider[[]audit.Entity] {
+		q := db.Where("tenant_id = ? AND (actor_id = ? OR subject_id = ?)", tenantId, characterId, characterId)
+		if !from.IsZero() {
+			q = q.Where("created_at >= ?", from)
+		}
+		if !to.IsZero() {
+			q = q.Where("created_at < ?", to)
+		}
+		var results []audit.Entity
+		err := q.Order("created_at").Find(&results).Error
+		if err != nil {
+			return model.ErrorProvider[[]audit.Entity](err)
+		}
+		return model.FixedProvider(results)
+	}
+}
+
+func buddiesByListIdEntityProvider(listId uuid.UUID, q buddy.Query) database.EntityProvider[[]buddy.Entity] {
+	return func(db *gorm.DB) model.Provider[[]buddy.Entity] {
+		var results []buddy.Entity
+		err := applyBuddyQuery(db.Where("list_id = ?", listId), q).Find(&results).Error
+		if err != nil {
+			return model.ErrorProvider[[]buddy.Entity](err)
+		}
+		return model.FixedProvider(results)
+	}
+}
+
+func applyBuddyQuery(db *gorm.DB, q buddy.Query) *gorm.DB {
+	f := q.Filter
+	if f.Online != nil {
+		if *f.Online {
+			db = db.Where("channel_id >= ?", 0)
+		} else {
+			db = db.Where("channel_id < ?", 0)
+		}
+	}
+	if f.Group != nil {
+		db = db.Where(map[string]interface{}{"group": *f.Group})
+	}
+	if f.Pending != nil {
+		db = db.Where(map[string]interface{}{"pending": *f.Pending})
+	}
+	if f.InShop != nil {
+		db = db.Where(map[string]interface{}{"in_shop": *f.InShop})
+	}
+	if f.NotSeenSince != nil {
+		db = db.Where("channel_id < ? AND (last_seen < ? OR last_seen IS NULL)", 0, *f.NotSeenSince)
+	}
+	for _, s := range q.Sort {
+		db = db.Order(clause.OrderByColumn{Column: clause.Column{Name: buddy.SortColumns[s.Field]}, Desc: s.Descending})
+	}
+	if q.Limit > 0 {
+		// Tie-break on the buddy id so pages are stable across requests.
+		db = db.Order("character_id").Limit(q.Limit).Offset(q.Offset)
+	}
+	return db
+}
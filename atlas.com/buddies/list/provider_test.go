@@ -0,0 +1,193 @@
+package list
+
+import (
+	"atlas-buddies/buddy"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestBuddiesByListIdEntityProvider(t *testing.T) {
+	db, err := setupTestDB()
+	if err != nil {
+		t.Fatalf("Failed to setup test database: %v", err)
+	}
+
+	listId := uuid.New()
+	longAgo := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	recently := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	cutoff := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	buddies := []buddy.Entity{
+		{CharacterId: 1, ListId: listId, Group: "Friends", CharacterName: "Charlie", ChannelId: 1},
+		{CharacterId: 2, ListId: listId, Group: "Friends", CharacterName: "Alice", ChannelId: 2},
+		{CharacterId: 3, ListId: listId, Group: "Guild", CharacterName: "Bob", ChannelId: 3},
+		{CharacterId: 4, ListId: listId, Group: "Friends", CharacterName: "Dave", ChannelId: -1, LastSeen: &longAgo},
+		{CharacterId: 5, ListId: listId, Group: "Friends", CharacterName: "Eve", ChannelId: -1, Pending: true, LastSeen: &recently},
+		{CharacterId: 6, ListId: uuid.New(), Group: "Friends", CharacterName: "Other", ChannelId: 1},
+		{CharacterId: 7, ListId: listId, Group: "Guild", CharacterName: "Frank", ChannelId: -1},
+	}
+	for _, b := range buddies {
+		if err = db.Create(&b).Error; err != nil {
+			t.Fatalf("Failed to create buddy: %v", err)
+		}
+	}
+
+	online := true
+	offline := false
+	pending := true
+	friends := "Friends"
+
+	tests := []struct {
+		name     string
+		query    buddy.Query
+		expected []uint32
+	}{
+		{"Unfiltered", buddy.Query{Sort: []buddy.Sort{{Field: "characterId"}}}, []uint32{1, 2, 3, 4, 5, 7}},
+		{"Online in group sorted by name", buddy.Query{Filter: buddy.Filter{Online: &online, Group: &friends}, Sort: []buddy.Sort{{Field: "characterName"}}}, []uint32{2, 1}},
+		{"Offline", buddy.Query{Filter: buddy.Filter{Online: &offline}, Sort: []buddy.Sort{{Field: "characterId"}}}, []uint32{4, 5, 7}},
+		{"Pending", buddy.Query{Filter: buddy.Filter{Pending: &pending}}, []uint32{5}},
+		{"Descending channel", buddy.Query{Filter: buddy.Filter{Online: &online}, Sort: []buddy.Sort{{Field: "channelId", Descending: true}}}, []uint32{3, 2, 1}},
+		{"Not seen since", buddy.Query{Filter: buddy.Filter{NotSeenSince: &cutoff}, Sort: []buddy.Sort{{Field: "characterId"}}}, []uint32{4, 7}},
+		{"Second page", buddy.Query{Sort: []buddy.Sort{{Field: "characterName"}}, Offset: 2, Limit: 2}, []uint32{1, 4}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results, err := buddiesByListIdEntityProvider(listId, tt.query)(db)()
+			if err != nil {
+				t.Fatalf("Expected no error, but got: %v", err)
+			}
+			if len(results) != len(tt.expected) {
+				t.Fatalf("Expected %d buddies, but got %d", len(tt.expected), len(results))
+			}
+			for i, r := range results {
+				if r.CharacterId != tt.expected[i] {
+					t.Errorf("Expected buddy %d at position %d, but got %d", tt.expected[i], i, r.CharacterId)
+				}
+			}
+		})
+	}
+}
+
+func TestByCharacterIdsEntityProvider(t *testing.T) {
+	db, err := setupTestDB()
+	if err != nil {
+		t.Fatalf("Failed to setup test database: %v", err)
+	}
+
+	tenantId := uuid.New()
+	lists := []Entity{
+		{TenantId: tenantId, Id: uuid.New(), CharacterId: 1, Capacity: 20},
+		{TenantId: tenantId, Id: uuid.New(), CharacterId: 2, Capacity: 20},
+		{TenantId: tenantId, Id: uuid.New(), CharacterId: 3, Capacity: 20},
+		{TenantId: uuid.New(), Id: uuid.New(), CharacterId: 2, Capacity: 20},
+	}
+	for _, l := range lists {
+		if err = db.Create(&l).Error; err != nil {
+			t.Fatalf("Failed to create buddy list: %v", err)
+		}
+	}
+	b := buddy.Entity{CharacterId: 9, ListId: lists[1].Id, Group: "Friends", CharacterName: "Alice", ChannelId: -1}
+	if err = db.Create(&b).Error; err != nil {
+		t.Fatalf("Failed to create buddy: %v", err)
+	}
+
+	results, err := byCharacterIdsEntityProvider(tenantId, []uint32{2, 3, 4})(db)()
+	if err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("Expected 2 buddy lists, but got %d", len(results))
+	}
+	for _, r := range results {
+		if r.TenantId != tenantId {
+			t.Errorf("Expected buddy list of tenant %s, but got %s", tenantId, r.TenantId)
+		}
+		expected := 0
+		if r.CharacterId == 2 {
+			expected = 1
+		}
+		if len(r.Buddies) != expected {
+			t.Errorf("Expected %d buddies for character %d, but got %d", expected, r.CharacterId, len(r.Buddies))
+		}
+	}
+}
+
+func TestEdgesEntityProvider(t *testing.T) {
+	db, err := setupTestDB()
+	if err != nil {
+		t.Fatalf("Failed to setup test database: %v", err)
+	}
+
+	tenantId := uuid.New()
+	otherTenantId := uuid.New()
+	lists := map[uint32]Entity{
+		1: {TenantId: tenantId, Id: uuid.New(), CharacterId: 1, Capacity: 20},
+		2: {TenantId: tenantId, Id: uuid.New(), CharacterId: 2, Capacity: 20},
+		5: {TenantId: tenantId, Id: uuid.New(), CharacterId: 5, Capacity: 20},
+		6: {TenantId: tenantId, Id: uuid.New(), CharacterId: 6, Capacity: 20},
+	}
+	otherTenantList := Entity{TenantId: otherTenantId, Id: uuid.New(), CharacterId: 5, Capacity: 20}
+	for _, l := range append([]Entity{otherTenantList}, lists[1], lists[2], lists[5], lists[6]) {
+		if err = db.Create(&l).Error; err != nil {
+			t.Fatalf("Failed to create buddy list: %v", err)
+		}
+	}
+	buddies := []buddy.Entity{
+		// 1 and 2 are mutual buddies.
+		{CharacterId: 2, ListId: lists[1].Id, Group: "Friends", CharacterName: "Two"},
+		{CharacterId: 1, ListId: lists[2].Id, Group: "Friends", CharacterName: "One"},
+		// 1 has an outbound request pending with 3, who has no list.
+		{CharacterId: 3, ListId: lists[1].Id, Group: "Friends", CharacterName: "Three", Pending: true},
+		// 5 holds 4 one sided.
+		{CharacterId: 4, ListId: lists[5].Id, Group: "Friends", CharacterName: "Four"},
+		// 6 has a request pending with 5, which is inbound to 5.
+		{CharacterId: 5, ListId: lists[6].Id, Group: "Friends", CharacterName: "Five", Pending: true},
+		// 5 of another tenant holds 9.
+		{CharacterId: 9, ListId: otherTenantList.Id, Group: "Friends", CharacterName: "Nine"},
+	}
+	for _, b := range buddies {
+		if err = db.Create(&b).Error; err != nil {
+			t.Fatalf("Failed to create buddy: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name        string
+		characterId uint32
+		otherIds    []uint32
+		expected    []edgeEntity
+	}{
+		{name: "mutual and outbound pending", characterId: 1, otherIds: []uint32{2, 3}, expected: []edgeEntity{
+			{OwnerId: 1, BuddyId: 2},
+			{OwnerId: 2, BuddyId: 1},
+			{OwnerId: 1, BuddyId: 3, Pending: true},
+		}},
+		{name: "one sided and inbound pending", characterId: 5, otherIds: []uint32{4, 6, 9}, expected: []edgeEntity{
+			{OwnerId: 5, BuddyId: 4},
+			{OwnerId: 6, BuddyId: 5, Pending: true},
+		}},
+		{name: "none", characterId: 1, otherIds: []uint32{4, 5}, expected: []edgeEntity{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results, err := edgesEntityProvider(tenantId, tt.characterId, tt.otherIds)(db)()
+			if err != nil {
+				t.Fatalf("Expected no error, but got: %v", err)
+			}
+			if len(results) != len(tt.expected) {
+				t.Fatalf("Expected edges %+v, but got %+v", tt.expected, results)
+			}
+			for _, e := range tt.expected {
+				found := false
+				for _, r := range results {
+					found = found || r == e
+				}
+				if !found {
+					t.Errorf("Expected edge %+v, but got %+v", e, results)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,71 @@
+package list
+
+import "atlas-buddies/settings"
+
+const (
+	// RelationshipNone is held by characters on neither buddy list.
+	RelationshipNone = "NONE"
+	// RelationshipPendingOutbound is held by a character awaiting the answer to its own buddy request.
+	RelationshipPendingOutbound = "PENDING_OUTBOUND"
+	// RelationshipPendingInbound is held by a character yet to answer a buddy request from the other.
+	RelationshipPendingInbound = "PENDING_INBOUND"
+	// RelationshipOneSided is held by characters where only one has confirmed the other as a buddy.
+	RelationshipOneSided = "ONE_SIDED"
+	// RelationshipMutual is held by characters who have confirmed each other as buddies.
+	RelationshipMutual = "MUTUAL"
+)
+
+// RelationshipModel describes how a character relates to another, as seen from the character.
+type RelationshipModel struct {
+	characterId  uint32
+	otherId      uint32
+	relationship string
+	// settings are the settings of the character.
+	settings settings.Model
+}
+
+func (m RelationshipModel) CharacterId() uint32 {
+	return m.characterId
+}
+
+func (m RelationshipModel) OtherId() uint32 {
+	return m.otherId
+}
+
+func (m RelationshipModel) Relationship() string {
+	return m.relationship
+}
+
+// Mutual reports whether the characters are mutual, confirmed buddies.
+func (m RelationshipModel) Mutual() bool {
+	return m.relationship == RelationshipMutual
+}
+
+func (m RelationshipModel) WhisperPolicy() string {
+	return m.settings.WhisperPolicy()
+}
+
+// WhisperAllowed reports whether the other character may whisper the character, under the whisper policy of the
+// character.
+func (m RelationshipModel) WhisperAllowed() bool {
+	return m.settings.AllowsWhisper(m.Mutual())
+}
+
+// relationshipOf classifies a relationship from the entry each character holds of the other. A nil entry is absent,
+// otherwise it tells whether the entry is pending.
+func relationshipOf(outbound *bool, inbound *bool) string {
+	outboundConfirmed := outbound != nil && !*outbound
+	inboundConfirmed := inbound != nil && !*inbound
+	switch {
+	case outboundConfirmed && inboundConfirmed:
+		return RelationshipMutual
+	case outboundConfirmed || inboundConfirmed:
+		return RelationshipOneSided
+	case outbound != nil:
+		return RelationshipPendingOutbound
+	case inbound != nil:
+		return RelationshipPendingInbound
+	default:
+		return RelationshipNone
+	}
+}
@@ -0,0 +1,30 @@
+package list
+
+import "testing"
+
+func TestRelationshipOf(t *testing.T) {
+	pending := true
+	confirmed := false
+
+	tests := []struct {
+		name     string
+		outbound *bool
+		inbound  *bool
+		expected string
+	}{
+		{"Neither", nil, nil, RelationshipNone},
+		{"Requested", &pending, nil, RelationshipPendingOutbound},
+		{"Requested by other", nil, &pending, RelationshipPendingInbound},
+		{"Confirmed by character", &confirmed, nil, RelationshipOneSided},
+		{"Confirmed by other", &pending, &confirmed, RelationshipOneSided},
+		{"Confirmed by both", &confirmed, &confirmed, RelationshipMutual},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if r := relationshipOf(tt.outbound, tt.inbound); r != tt.expected {
+				t.Errorf("Expected relationship %s, but got %s", tt.expected, r)
+			}
+		})
+	}
+}
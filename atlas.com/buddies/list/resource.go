@@ -1,37 +1,91 @@
 package list
 
 import (
+	"atlas-buddies/audit"
 	"atlas-buddies/buddy"
+	"atlas-buddies/change"
+	"atlas-buddies/character"
 	list2 "atlas-buddies/kafka/message/list"
 	"atlas-buddies/kafka/producer"
 	list3 "atlas-buddies/kafka/producer/list"
+	"atlas-buddies/ledger"
 	"atlas-buddies/rest"
+	"atlas-buddies/settings"
+	"atlas-buddies/stream"
+	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"github.com/Chronicle20/atlas-model/model"
 	"github.com/Chronicle20/atlas-rest/server"
+	"github.com/Chronicle20/atlas-tenant"
+	"github.com/google/uuid"
 	"github.com/gorilla/mux"
 	"github.com/jtumidanski/api2go/jsonapi"
 	"github.com/sirupsen/logrus"
 	"gorm.io/gorm"
 	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	// StreamEventChange is the event stream event carrying a change missed while disconnected.
+	StreamEventChange = "CHANGE"
+	// StreamEventResync is the event stream event telling the client to reload the list, as the changes it missed are no
+	// longer available.
+	StreamEventResync = "RESYNC"
 )
 
 const (
 	GetBuddyList          = "get_buddy_list"
 	CreateBuddyList       = "create_buddy_list"
+	UpdateBuddyList       = "update_buddy_list"
+	DeleteBuddyList       = "delete_buddy_list"
 	GetBuddiesInBuddyList = "get_buddies_in_buddy_list"
+	GetBuddyInBuddyList   = "get_buddy_in_buddy_list"
 	AddBuddyToBuddyList   = "add_buddy_to_buddy_list"
+	RemoveBuddyFromList   = "remove_buddy_from_list"
+	GetBuddyLocation      = "get_buddy_location"
+	GetCapacityHistory    = "get_capacity_history"
+	GetAudits             = "get_audits"
+	GetChanges            = "get_changes"
+	GetEvents             = "get_events"
+	GetBuddyLists         = "get_buddy_lists"
+	QueryBuddyLists       = "query_buddy_lists"
+	GetRelationship       = "get_relationship"
+	GetRelationships      = "get_relationships"
+	GetSettings           = "get_settings"
+	UpdateSettings        = "update_settings"
 )
 
-func InitResource(si jsonapi.ServerInformation) func(db *gorm.DB) server.RouteInitializer {
-	return func(db *gorm.DB) server.RouteInitializer {
-		return func(router *mux.Router, l logrus.FieldLogger) {
-			registerGet := rest.RegisterHandler(l)(si)
-			r := router.PathPrefix("/characters/{characterId}/buddy-list").Subrouter()
-			r.HandleFunc("", registerGet(GetBuddyList, handleGetBuddyList(db))).Methods(http.MethodGet)
-			r.HandleFunc("", rest.RegisterInputHandler[RestModel](l)(si)(CreateBuddyList, handleCreateBuddyList)).Methods(http.MethodPost)
-			r.HandleFunc("/buddies", registerGet(GetBuddiesInBuddyList, handleGetBuddiesInBuddyList(db))).Methods(http.MethodGet)
-			r.HandleFunc("/buddies", rest.RegisterInputHandler[buddy.RestModel](l)(si)(AddBuddyToBuddyList, handleAddBuddyToBuddyList)).Methods(http.MethodPost)
+// InitResource registers the buddy list routes. Long-lived responses, such as the event stream, end when ctx is done.
+func InitResource(si jsonapi.ServerInformation) func(ctx context.Context) func(db *gorm.DB) server.RouteInitializer {
+	return func(ctx context.Context) func(db *gorm.DB) server.RouteInitializer {
+		return func(db *gorm.DB) server.RouteInitializer {
+			return func(router *mux.Router, l logrus.FieldLogger) {
+				registerGet := rest.RegisterHandler(l)(si)
+				router.HandleFunc("/buddy-lists", registerGet(GetBuddyLists, handleGetBuddyLists(db))).Methods(http.MethodGet).Name(GetBuddyLists)
+				router.HandleFunc("/buddy-lists/queries", rest.RegisterInputHandler[QueryRestModel](l)(si)(QueryBuddyLists, rest.Validate(queryRules...)(handleQueryBuddyLists(db)))).Methods(http.MethodPost).Name(QueryBuddyLists)
+				r := router.PathPrefix("/characters/{characterId}/buddy-list").Subrouter()
+				r.HandleFunc("", registerGet(GetBuddyList, handleGetBuddyList(db))).Methods(http.MethodGet).Name(GetBuddyList)
+				r.HandleFunc("", rest.RegisterInputHandler[RestModel](l)(si)(CreateBuddyList, rest.Validate(createBuddyListRules...)(handleCreateBuddyList(db)))).Methods(http.MethodPost).Name(CreateBuddyList)
+				r.HandleFunc("", rest.RegisterInputHandler[RestModel](l)(si)(UpdateBuddyList, rest.Validate(buddyListRules...)(handleUpdateBuddyList(db)))).Methods(http.MethodPatch).Name(UpdateBuddyList)
+				r.HandleFunc("", registerGet(DeleteBuddyList, handleDeleteBuddyList(db))).Methods(http.MethodDelete).Name(DeleteBuddyList)
+				r.HandleFunc("/buddies", registerGet(GetBuddiesInBuddyList, handleGetBuddiesInBuddyList(db))).Methods(http.MethodGet).Name(GetBuddiesInBuddyList)
+				r.HandleFunc("/buddies", rest.RegisterInputHandler[buddy.RestModel](l)(si)(AddBuddyToBuddyList, rest.Validate(buddyRules...)(handleAddBuddyToBuddyList(db)))).Methods(http.MethodPost).Name(AddBuddyToBuddyList)
+				r.HandleFunc("/buddies/{buddyId}", registerGet(GetBuddyInBuddyList, handleGetBuddyInBuddyList(db))).Methods(http.MethodGet).Name(GetBuddyInBuddyList)
+				r.HandleFunc("/buddies/{buddyId}", registerGet(RemoveBuddyFromList, handleRemoveBuddyFromList(db))).Methods(http.MethodDelete).Name(RemoveBuddyFromList)
+				r.HandleFunc("/buddies/{buddyId}/location", registerGet(GetBuddyLocation, handleGetBuddyLocation(db))).Methods(http.MethodGet).Name(GetBuddyLocation)
+				r.HandleFunc("/relationships", registerGet(GetRelationships, handleGetRelationships(db))).Methods(http.MethodGet).Name(GetRelationships)
+				r.HandleFunc("/relationships/{otherId}", registerGet(GetRelationship, handleGetRelationship(db))).Methods(http.MethodGet).Name(GetRelationship)
+				r.HandleFunc("/settings", registerGet(GetSettings, handleGetSettings(db))).Methods(http.MethodGet).Name(GetSettings)
+				r.HandleFunc("/settings", rest.RegisterInputHandler[settings.RestModel](l)(si)(UpdateSettings, rest.Validate(settingsRules...)(handleUpdateSettings(db)))).Methods(http.MethodPatch).Name(UpdateSettings)
+				r.HandleFunc("/capacity-history", registerGet(GetCapacityHistory, handleGetCapacityHistory(db))).Methods(http.MethodGet).Name(GetCapacityHistory)
+				r.HandleFunc("/audits", registerGet(GetAudits, handleGetAudits(db))).Methods(http.MethodGet).Name(GetAudits)
+				r.HandleFunc("/changes", registerGet(GetChanges, handleGetChanges(db))).Methods(http.MethodGet).Name(GetChanges)
+				r.HandleFunc("/events", registerGet(GetEvents, handleGetEvents(ctx, db))).Methods(http.MethodGet).Name(GetEvents)
+			}
 		}
 	}
 }
@@ -42,18 +96,21 @@ func handleGetBuddyList(db *gorm.DB) rest.GetHandler {
 			return func(w http.ResponseWriter, r *http.Request) {
 				bl, err := NewProcessor(d.Logger(), d.Context(), db).GetByCharacterId(characterId)
 				if errors.Is(err, gorm.ErrRecordNotFound) {
-					w.WriteHeader(http.StatusNotFound)
+					writeNoBuddyList(w, characterId)
 					return
 				}
 				if err != nil {
-					w.WriteHeader(http.StatusInternalServerError)
+					rest.WriteError(w, http.StatusInternalServerError, list2.StatusEventErrorUnknownError, "")
+					return
+				}
+				if rest.NotModified(w, r, rest.ETag(bl.Version())) {
 					return
 				}
 
 				res, err := model.Map(Transform)(model.FixedProvider(bl))()
 				if err != nil {
 					d.Logger().WithError(err).Errorf("Creating REST model.")
-					w.WriteHeader(http.StatusInternalServerError)
+					rest.WriteError(w, http.StatusInternalServerError, list2.StatusEventErrorUnknownError, "")
 					return
 				}
 
@@ -63,37 +120,258 @@ func handleGetBuddyList(db *gorm.DB) rest.GetHandler {
 	}
 }
 
-func handleCreateBuddyList(d *rest.HandlerDependency, _ *rest.HandlerContext, i RestModel) http.HandlerFunc {
-	return rest.ParseCharacterId(d.Logger(), func(characterId uint32) http.HandlerFunc {
+// handleGetBuddyLists retrieves the buddy lists of the characters named by filter[characterId], a comma separated list.
+// Characters without a buddy list are omitted from the collection.
+func handleGetBuddyLists(db *gorm.DB) rest.GetHandler {
+	return func(d *rest.HandlerDependency, c *rest.HandlerContext) http.HandlerFunc {
 		return func(w http.ResponseWriter, r *http.Request) {
-			err := producer.ProviderImpl(d.Logger())(d.Context())(list2.EnvCommandTopic)(list3.CreateCommandProvider(characterId, i.Capacity))
+			characterIds, err := rest.ParseUintListFilter(r, "characterId")
 			if err != nil {
-				w.WriteHeader(http.StatusInternalServerError)
+				rest.WriteError(w, http.StatusBadRequest, rest.ErrorCodeInvalidRequest, "filter[characterId] must be a comma separated list of numbers.")
+				return
+			}
+			if err = checkBatchSize(d, characterIds); err != nil {
+				rest.WriteError(w, http.StatusBadRequest, rest.ErrorCodeInvalidRequest, "filter[characterId]: "+err.Error()+".")
 				return
 			}
+			writeBuddyLists(d, c, w, db, characterIds)
+		}
+	}
+}
 
-			w.WriteHeader(http.StatusAccepted)
+// handleQueryBuddyLists retrieves the buddy lists of the characters in the request body. It serves sets of characters
+// too large for the query string of handleGetBuddyLists.
+func handleQueryBuddyLists(db *gorm.DB) rest.InputHandler[QueryRestModel] {
+	return func(d *rest.HandlerDependency, c *rest.HandlerContext, i QueryRestModel) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			writeBuddyLists(d, c, w, db, i.CharacterIds)
 		}
-	})
+	}
 }
 
-func handleGetBuddiesInBuddyList(db *gorm.DB) rest.GetHandler {
+func writeBuddyLists(d *rest.HandlerDependency, c *rest.HandlerContext, w http.ResponseWriter, db *gorm.DB, characterIds []uint32) {
+	bls, err := NewProcessor(d.Logger(), d.Context(), db).GetByCharacterIds(characterIds)
+	if err != nil {
+		d.Logger().WithError(err).Errorf("Unable to retrieve buddy lists.")
+		rest.WriteError(w, http.StatusInternalServerError, list2.StatusEventErrorUnknownError, "")
+		return
+	}
+
+	res, err := model.SliceMap(Transform)(model.FixedProvider(bls))()()
+	if err != nil {
+		d.Logger().WithError(err).Errorf("Creating REST model.")
+		rest.WriteError(w, http.StatusInternalServerError, list2.StatusEventErrorUnknownError, "")
+		return
+	}
+
+	server.Marshal[[]RestModel](d.Logger())(w)(c.ServerInformation())(res)
+}
+
+// handleCreateBuddyList requests the creation of a character's buddy list, by producing a CREATE command. With a
+// Prefer: wait header the list is instead created in-process, as creation emits no status event to wait for, and is
+// returned with 201 Created, or 409 Conflict when the character already has a buddy list.
+func handleCreateBuddyList(db *gorm.DB) rest.InputHandler[RestModel] {
+	return func(d *rest.HandlerDependency, c *rest.HandlerContext, i RestModel) http.HandlerFunc {
+		return rest.ParseCharacterId(d.Logger(), func(characterId uint32) http.HandlerFunc {
+			return func(w http.ResponseWriter, r *http.Request) {
+				ch, err := character.NewProcessor(d.Logger(), d.Context()).GetById(characterId)
+				if err != nil {
+					d.Logger().WithError(err).Errorf("Unable to retrieve character [%d] information.", characterId)
+					rest.WriteError(w, http.StatusNotFound, list2.StatusEventErrorCharacterNotFound, fmt.Sprintf("Character [%d] does not exist.", characterId))
+					return
+				}
+
+				if rest.PreferWait(w, r) > 0 {
+					p := NewProcessor(d.Logger(), audit.WithCommandType(d.Context(), list2.CommandTypeCreate), db)
+					bl, err := p.CreateAndEmit(characterId, ch.WorldId(), i.Capacity)
+					if errors.Is(err, ErrListExists) {
+						rest.WriteError(w, http.StatusConflict, rest.ErrorCodeAlreadyExists, fmt.Sprintf("Character [%d] already has a buddy list.", characterId))
+						return
+					}
+					if errors.Is(err, ErrInvalidCapacity) {
+						rest.WriteError(w, http.StatusUnprocessableEntity, list2.StatusEventErrorInvalidCapacity, "")
+						return
+					}
+					if err != nil {
+						rest.WriteError(w, http.StatusInternalServerError, list2.StatusEventErrorUnknownError, "")
+						return
+					}
+					writeBuddyList(d, c, w, bl, http.StatusCreated)
+					return
+				}
+
+				err = producer.ProviderImpl(d.Logger())(d.Context())(list2.EnvCommandTopic)(list3.CreateCommandProvider(characterId, ch.WorldId(), i.Capacity))
+				if err != nil {
+					rest.WriteError(w, http.StatusInternalServerError, list2.StatusEventErrorUnknownError, "")
+					return
+				}
+
+				w.WriteHeader(http.StatusAccepted)
+			}
+		})
+	}
+}
+
+// handleUpdateBuddyList sets the capacity of an existing buddy list, which may lower it. The optional overflowPolicy
+// query parameter (REJECT or TRIM_PENDING) decides how a list holding more entries than the new capacity is handled.
+// An If-Match header carrying a list version makes the update conditional on the list still being at that version.
+// With a Prefer: wait header the resulting list is returned once the capacity changed.
+func handleUpdateBuddyList(db *gorm.DB) rest.InputHandler[RestModel] {
+	return func(d *rest.HandlerDependency, c *rest.HandlerContext, i RestModel) http.HandlerFunc {
+		return rest.ParseCharacterId(d.Logger(), func(characterId uint32) http.HandlerFunc {
+			return func(w http.ResponseWriter, r *http.Request) {
+				op := r.URL.Query().Get("overflowPolicy")
+				if op != "" && op != list2.OverflowPolicyReject && op != list2.OverflowPolicyTrimPending {
+					rest.WriteError(w, http.StatusBadRequest, rest.ErrorCodeInvalidRequest, "The overflowPolicy query parameter must be REJECT or TRIM_PENDING.")
+					return
+				}
+				ifMatch, err := rest.ParseIfMatch(r)
+				if err != nil {
+					rest.WriteError(w, http.StatusBadRequest, rest.ErrorCodeInvalidRequest, "The If-Match header must be a buddy list version.")
+					return
+				}
+
+				bl, err := NewProcessor(d.Logger(), d.Context(), db).GetByCharacterId(characterId)
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					writeNoBuddyList(w, characterId)
+					return
+				}
+				if err != nil {
+					rest.WriteError(w, http.StatusInternalServerError, list2.StatusEventErrorUnknownError, "")
+					return
+				}
+				if ifMatch != nil && *ifMatch != bl.Version() {
+					rest.WriteError(w, http.StatusPreconditionFailed, list2.StatusEventErrorVersionConflict, fmt.Sprintf("The buddy list is at version [%d].", bl.Version()))
+					return
+				}
+
+				wait := rest.PreferWait(w, r)
+				events, cancel := subscribeOutcome(d, characterId, wait)
+				defer cancel()
+
+				correlationId := requestCorrelationId(r)
+				err = producer.ProviderImpl(d.Logger())(d.Context())(list2.EnvCommandTopic)(list3.SetCapacityCommandProvider(characterId, bl.WorldId(), i.Capacity, op, ifMatch, correlationId))
+				if err != nil {
+					rest.WriteError(w, http.StatusInternalServerError, list2.StatusEventErrorUnknownError, "")
+					return
+				}
+
+				w.Header().Set(CorrelationIdHeader, correlationId)
+				if e, ok := awaitOutcome(r.Context(), events, correlationId, wait, list2.StatusEventTypeBuddyCapacityUpdate); ok {
+					if e.Type == list2.StatusEventTypeError {
+						writeCommandError(w, e)
+						return
+					}
+					bl, err = NewProcessor(d.Logger(), d.Context(), db).GetByCharacterId(characterId)
+					if err != nil {
+						rest.WriteError(w, http.StatusInternalServerError, list2.StatusEventErrorUnknownError, "")
+						return
+					}
+					writeBuddyList(d, c, w, bl, http.StatusOK)
+					return
+				}
+				w.WriteHeader(http.StatusAccepted)
+			}
+		})
+	}
+}
+
+// handleDeleteBuddyList is an administrative endpoint which deletes the buddy list of a character, by producing a DELETE
+// command. The character is removed from the buddy lists of their buddies, each of which receives a BUDDY_REMOVED event,
+// and the character receives a LIST_DELETED event, all carrying the correlation id returned in the X-Correlation-Id
+// header. An If-Match header carrying a list version makes the deletion conditional on the list still being at that
+// version. With a Prefer: wait header 204 No Content is returned once the list was deleted.
+func handleDeleteBuddyList(db *gorm.DB) rest.GetHandler {
 	return func(d *rest.HandlerDependency, c *rest.HandlerContext) http.HandlerFunc {
 		return rest.ParseCharacterId(d.Logger(), func(characterId uint32) http.HandlerFunc {
 			return func(w http.ResponseWriter, r *http.Request) {
+				ifMatch, err := rest.ParseIfMatch(r)
+				if err != nil {
+					rest.WriteError(w, http.StatusBadRequest, rest.ErrorCodeInvalidRequest, "The If-Match header must be a buddy list version.")
+					return
+				}
+
 				bl, err := NewProcessor(d.Logger(), d.Context(), db).GetByCharacterId(characterId)
 				if errors.Is(err, gorm.ErrRecordNotFound) {
-					w.WriteHeader(http.StatusNotFound)
+					writeNoBuddyList(w, characterId)
 					return
 				}
 				if err != nil {
-					w.WriteHeader(http.StatusInternalServerError)
+					rest.WriteError(w, http.StatusInternalServerError, list2.StatusEventErrorUnknownError, "")
+					return
+				}
+				if ifMatch != nil && *ifMatch != bl.Version() {
+					rest.WriteError(w, http.StatusPreconditionFailed, list2.StatusEventErrorVersionConflict, fmt.Sprintf("The buddy list is at version [%d].", bl.Version()))
 					return
 				}
-				res, err := model.SliceMap(buddy.Transform)(model.FixedProvider(bl.buddies))()()
+
+				wait := rest.PreferWait(w, r)
+				events, cancel := subscribeOutcome(d, characterId, wait)
+				defer cancel()
+
+				correlationId := requestCorrelationId(r)
+				err = producer.ProviderImpl(d.Logger())(d.Context())(list2.EnvCommandTopic)(list3.DeleteCommandProvider(characterId, bl.WorldId(), ifMatch, correlationId))
+				if err != nil {
+					rest.WriteError(w, http.StatusInternalServerError, list2.StatusEventErrorUnknownError, "")
+					return
+				}
+
+				w.Header().Set(CorrelationIdHeader, correlationId)
+				if e, ok := awaitOutcome(r.Context(), events, correlationId, wait, list2.StatusEventTypeListDeleted); ok {
+					if e.Type == list2.StatusEventTypeError {
+						writeCommandError(w, e)
+						return
+					}
+					w.WriteHeader(http.StatusNoContent)
+					return
+				}
+				w.WriteHeader(http.StatusAccepted)
+			}
+		})
+	}
+}
+
+func handleGetBuddiesInBuddyList(db *gorm.DB) rest.GetHandler {
+	return func(d *rest.HandlerDependency, c *rest.HandlerContext) http.HandlerFunc {
+		return rest.ParseCharacterId(d.Logger(), func(characterId uint32) http.HandlerFunc {
+			return func(w http.ResponseWriter, r *http.Request) {
+				q, err := parseBuddyQuery(r)
+				if err != nil {
+					d.Logger().WithError(err).Errorf("Unable to parse buddy query.")
+					rest.WriteError(w, http.StatusBadRequest, rest.ErrorCodeInvalidRequest, err.Error())
+					return
+				}
+
+				p := NewProcessor(d.Logger(), d.Context(), db)
+				// The version is read before the buddies, so the entity tag is never newer than the buddies it describes.
+				v, err := p.GetVersion(characterId)
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					writeNoBuddyList(w, characterId)
+					return
+				}
+				if err != nil {
+					rest.WriteError(w, http.StatusInternalServerError, list2.StatusEventErrorUnknownError, "")
+					return
+				}
+				// filter[notSeenDays] is relative to the time of the request, so its results change while the list does not,
+				// and are not tagged.
+				if q.Filter.NotSeenSince == nil && rest.NotModified(w, r, rest.QueryETag(v, buddyQueryKey(q))) {
+					return
+				}
+
+				bs, err := p.GetBuddies(characterId, q)
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					writeNoBuddyList(w, characterId)
+					return
+				}
+				if err != nil {
+					rest.WriteError(w, http.StatusInternalServerError, list2.StatusEventErrorUnknownError, "")
+					return
+				}
+				res, err := model.SliceMap(buddy.Transform)(model.FixedProvider(bs))()()
 				if err != nil {
 					d.Logger().WithError(err).Errorf("Creating REST model.")
-					w.WriteHeader(http.StatusInternalServerError)
+					rest.WriteError(w, http.StatusInternalServerError, list2.StatusEventErrorUnknownError, "")
 					return
 				}
 
@@ -103,16 +381,717 @@ func handleGetBuddiesInBuddyList(db *gorm.DB) rest.GetHandler {
 	}
 }
 
-func handleAddBuddyToBuddyList(d *rest.HandlerDependency, _ *rest.HandlerContext, i buddy.RestModel) http.HandlerFunc {
-	return rest.ParseCharacterId(d.Logger(), func(characterId uint32) http.HandlerFunc {
-		return func(w http.ResponseWriter, r *http.Request) {
-			//err := producer.ProviderImpl(d.Logger())(d.Context())(EnvCommandTopic)(addBuddyCommandProvider(characterId, i.CharacterId, i.Group, i.CharacterName, i.ChannelId, i.Visible))
-			//if err != nil {
-			//	w.WriteHeader(http.StatusInternalServerError)
-			//	return
-			//}
+// handleGetBuddyInBuddyList returns a single buddy of a character's buddy list, tagged with the list version.
+func handleGetBuddyInBuddyList(db *gorm.DB) rest.GetHandler {
+	return func(d *rest.HandlerDependency, c *rest.HandlerContext) http.HandlerFunc {
+		return rest.ParseCharacterId(d.Logger(), func(characterId uint32) http.HandlerFunc {
+			return rest.ParseBuddyId(d.Logger(), func(buddyId uint32) http.HandlerFunc {
+				return func(w http.ResponseWriter, r *http.Request) {
+					bl, err := NewProcessor(d.Logger(), d.Context(), db).GetByCharacterId(characterId)
+					if errors.Is(err, gorm.ErrRecordNotFound) {
+						writeNoBuddyList(w, characterId)
+						return
+					}
+					if err != nil {
+						rest.WriteError(w, http.StatusInternalServerError, list2.StatusEventErrorUnknownError, "")
+						return
+					}
+					b, ok := bl.Buddy(buddyId)
+					if !ok {
+						rest.WriteError(w, http.StatusNotFound, rest.ErrorCodeNotFound, fmt.Sprintf("Character [%d] is not on the buddy list of character [%d].", buddyId, characterId))
+						return
+					}
+					if rest.NotModified(w, r, rest.ETag(bl.Version())) {
+						return
+					}
+
+					res, err := model.Map(buddy.Transform)(model.FixedProvider(b))()
+					if err != nil {
+						d.Logger().WithError(err).Errorf("Creating REST model.")
+						rest.WriteError(w, http.StatusInternalServerError, list2.StatusEventErrorUnknownError, "")
+						return
+					}
+
+					server.Marshal[buddy.RestModel](d.Logger())(w)(c.ServerInformation())(res)
+				}
+			})
+		})
+	}
+}
+
+// parseBuddyQuery reads the JSON:API filter, sort and page parameters supported by the buddies collection.
+func parseBuddyQuery(r *http.Request) (buddy.Query, error) {
+	var q buddy.Query
+	var err error
+	if q.Filter.Online, err = rest.ParseBoolFilter(r, "online"); err != nil {
+		return buddy.Query{}, err
+	}
+	if q.Filter.Pending, err = rest.ParseBoolFilter(r, "pending"); err != nil {
+		return buddy.Query{}, err
+	}
+	if q.Filter.InShop, err = rest.ParseBoolFilter(r, "inShop"); err != nil {
+		return buddy.Query{}, err
+	}
+	q.Filter.Group = rest.ParseStringFilter(r, "group")
+	days, err := rest.ParseUintFilter(r, "notSeenDays")
+	if err != nil {
+		return buddy.Query{}, err
+	}
+	if days != nil {
+		since := time.Now().UTC().AddDate(0, 0, -int(*days))
+		q.Filter.NotSeenSince = &since
+	}
+
+	for _, sf := range rest.ParseSort(r) {
+		if _, ok := buddy.SortColumns[sf.Field]; !ok {
+			return buddy.Query{}, fmt.Errorf("unsupported sort field [%s]", sf.Field)
+		}
+		q.Sort = append(q.Sort, buddy.Sort{Field: sf.Field, Descending: sf.Descending})
+	}
+
+	page, err := rest.ParsePage(r)
+	if err != nil {
+		return buddy.Query{}, err
+	}
+	q.Offset = page.Offset()
+	q.Limit = page.Size
+	return q, nil
+}
+
+// buddyQueryKey renders a buddy query in a normal form, so requests for the same buddies share an entity tag whatever
+// the order or spelling of their query parameters.
+func buddyQueryKey(q buddy.Query) string {
+	b, _ := json.Marshal(q)
+	return string(b)
+}
+
+// CorrelationIdHeader carries the correlation id of an accepted command. The status events the command results in carry
+// the same correlation id.
+const CorrelationIdHeader = "X-Correlation-Id"
+
+// handleAddBuddyToBuddyList requests that the buddy resource is added to the character's buddy list, by producing a
+// REQUEST_ADD command in the world of the buddy list. The command is correlated with the X-Correlation-Id request
+// header, or a generated id when absent, which is returned along with a Location of the list change feed from the
+// current version, so the caller can follow the outcome. With a Prefer: wait header the added buddy is returned with 201
+// Created once the command succeeded, or its error once it failed. A request the target declines has no outcome to wait
+// for, as declines are silent, so it is answered with 202 Accepted once the wait ends. An If-Match header carrying a
+// list version makes the addition conditional on the list still being at that version.
+func handleAddBuddyToBuddyList(db *gorm.DB) rest.InputHandler[buddy.RestModel] {
+	return func(d *rest.HandlerDependency, c *rest.HandlerContext, i buddy.RestModel) http.HandlerFunc {
+		return rest.ParseCharacterId(d.Logger(), func(characterId uint32) http.HandlerFunc {
+			return func(w http.ResponseWriter, r *http.Request) {
+				ifMatch, err := rest.ParseIfMatch(r)
+				if err != nil {
+					rest.WriteError(w, http.StatusBadRequest, rest.ErrorCodeInvalidRequest, "The If-Match header must be a buddy list version.")
+					return
+				}
+
+				group := i.Group
+				if group == "" {
+					group = DefaultGroup
+				}
+
+				bl, err := NewProcessor(d.Logger(), d.Context(), db).GetByCharacterId(characterId)
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					writeNoBuddyList(w, characterId)
+					return
+				}
+				if err != nil {
+					rest.WriteError(w, http.StatusInternalServerError, list2.StatusEventErrorUnknownError, "")
+					return
+				}
+				if ifMatch != nil && *ifMatch != bl.Version() {
+					rest.WriteError(w, http.StatusPreconditionFailed, list2.StatusEventErrorVersionConflict, fmt.Sprintf("The buddy list is at version [%d].", bl.Version()))
+					return
+				}
+
+				wait := rest.PreferWait(w, r)
+				events, cancel := subscribeOutcome(d, characterId, wait)
+				defer cancel()
+
+				correlationId := requestCorrelationId(r)
+				err = producer.ProviderImpl(d.Logger())(d.Context())(list2.EnvCommandTopic)(list3.RequestAddCommandProvider(characterId, bl.WorldId(), i.CharacterId, i.CharacterName, group, ifMatch, correlationId))
+				if err != nil {
+					rest.WriteError(w, http.StatusInternalServerError, list2.StatusEventErrorUnknownError, "")
+					return
+				}
+
+				w.Header().Set(CorrelationIdHeader, correlationId)
+				if e, ok := awaitOutcome(r.Context(), events, correlationId, wait, list2.StatusEventTypeBuddyAdded); ok {
+					if e.Type == list2.StatusEventTypeError {
+						writeCommandError(w, e)
+						return
+					}
+					bl, err = NewProcessor(d.Logger(), d.Context(), db).GetByCharacterId(characterId)
+					if err != nil {
+						rest.WriteError(w, http.StatusInternalServerError, list2.StatusEventErrorUnknownError, "")
+						return
+					}
+					b, ok := bl.Buddy(i.CharacterId)
+					if !ok {
+						// Removed again before it could be read.
+						rest.WriteError(w, http.StatusNotFound, rest.ErrorCodeNotFound, fmt.Sprintf("Character [%d] is not on the buddy list of character [%d].", i.CharacterId, characterId))
+						return
+					}
+					res, err := model.Map(buddy.Transform)(model.FixedProvider(b))()
+					if err != nil {
+						d.Logger().WithError(err).Errorf("Creating REST model.")
+						rest.WriteError(w, http.StatusInternalServerError, list2.StatusEventErrorUnknownError, "")
+						return
+					}
+					w.Header().Set("Location", fmt.Sprintf("%s%scharacters/%d/buddy-list/buddies/%d", c.ServerInformation().GetBaseURL(), c.ServerInformation().GetPrefix(), characterId, i.CharacterId))
+					w.Header().Set("Content-Type", "application/vnd.api+json")
+					w.WriteHeader(http.StatusCreated)
+					server.Marshal[buddy.RestModel](d.Logger())(w)(c.ServerInformation())(res)
+					return
+				}
+
+				w.Header().Set("Location", changesLocation(c.ServerInformation(), characterId, bl.Version()))
+				w.WriteHeader(http.StatusAccepted)
+			}
+		})
+	}
+}
+
+// handleRemoveBuddyFromList requests that a buddy is removed from the character's buddy list, by producing a
+// REQUEST_DELETE command. It is correlated and followed the same way as adding a buddy, and with a Prefer: wait header
+// 204 No Content is returned once the buddy was removed. An If-Match header carrying a list version makes the removal
+// conditional on the list still being at that version.
+func handleRemoveBuddyFromList(db *gorm.DB) rest.GetHandler {
+	return func(d *rest.HandlerDependency, c *rest.HandlerContext) http.HandlerFunc {
+		return rest.ParseCharacterId(d.Logger(), func(characterId uint32) http.HandlerFunc {
+			return rest.ParseBuddyId(d.Logger(), func(buddyId uint32) http.HandlerFunc {
+				return func(w http.ResponseWriter, r *http.Request) {
+					ifMatch, err := rest.ParseIfMatch(r)
+					if err != nil {
+						rest.WriteError(w, http.StatusBadRequest, rest.ErrorCodeInvalidRequest, "The If-Match header must be a buddy list version.")
+						return
+					}
+
+					bl, err := NewProcessor(d.Logger(), d.Context(), db).GetByCharacterId(characterId)
+					if errors.Is(err, gorm.ErrRecordNotFound) {
+						writeNoBuddyList(w, characterId)
+						return
+					}
+					if err != nil {
+						rest.WriteError(w, http.StatusInternalServerError, list2.StatusEventErrorUnknownError, "")
+						return
+					}
+					if ifMatch != nil && *ifMatch != bl.Version() {
+						rest.WriteError(w, http.StatusPreconditionFailed, list2.StatusEventErrorVersionConflict, fmt.Sprintf("The buddy list is at version [%d].", bl.Version()))
+						return
+					}
+					if _, ok := bl.Buddy(buddyId); !ok {
+						rest.WriteError(w, http.StatusNotFound, rest.ErrorCodeNotFound, fmt.Sprintf("Character [%d] is not on the buddy list of character [%d].", buddyId, characterId))
+						return
+					}
+
+					wait := rest.PreferWait(w, r)
+					events, cancel := subscribeOutcome(d, characterId, wait)
+					defer cancel()
+
+					correlationId := requestCorrelationId(r)
+					err = producer.ProviderImpl(d.Logger())(d.Context())(list2.EnvCommandTopic)(list3.RequestDeleteCommandProvider(characterId, bl.WorldId(), buddyId, ifMatch, correlationId))
+					if err != nil {
+						rest.WriteError(w, http.StatusInternalServerError, list2.StatusEventErrorUnknownError, "")
+						return
+					}
+
+					w.Header().Set(CorrelationIdHeader, correlationId)
+					if e, ok := awaitOutcome(r.Context(), events, correlationId, wait, list2.StatusEventTypeBuddyRemoved); ok {
+						if e.Type == list2.StatusEventTypeError {
+							writeCommandError(w, e)
+							return
+						}
+						w.WriteHeader(http.StatusNoContent)
+						return
+					}
+
+					w.Header().Set("Location", changesLocation(c.ServerInformation(), characterId, bl.Version()))
+					w.WriteHeader(http.StatusAccepted)
+				}
+			})
+		})
+	}
+}
+
+// requestCorrelationId returns the correlation id of the X-Correlation-Id request header, or a generated one when
+// absent.
+func requestCorrelationId(r *http.Request) string {
+	if correlationId := r.Header.Get(CorrelationIdHeader); correlationId != "" {
+		return correlationId
+	}
+	return uuid.New().String()
+}
+
+// writeNoBuddyList reports that a character has no buddy list.
+func writeNoBuddyList(w http.ResponseWriter, characterId uint32) {
+	rest.WriteError(w, http.StatusNotFound, list2.StatusEventErrorCharacterNotFound, fmt.Sprintf("Character [%d] has no buddy list.", characterId))
+}
+
+// changesLocation is the location of the change feed of a character's buddy list from the given version.
+func changesLocation(si jsonapi.ServerInformation, characterId uint32, version uint32) string {
+	return fmt.Sprintf("%s%scharacters/%d/buddy-list/changes?since=%d", si.GetBaseURL(), si.GetPrefix(), characterId, version)
+}
+
+// subscribeOutcome subscribes to the status events of a character when the request asked to wait for the outcome of a
+// command. It must be called before the command is produced, so the outcome cannot be missed.
+func subscribeOutcome(d *rest.HandlerDependency, characterId uint32, wait time.Duration) (<-chan stream.Event, func()) {
+	if wait <= 0 {
+		return nil, func() {}
+	}
+	t := tenant.MustFromContext(d.Context())
+	return stream.GetHub().Subscribe(t.Id(), characterId)
+}
+
+// awaitOutcome waits up to wait for the status event of the correlated command, which is either of the success type or
+// an ERROR. False is returned when there is nothing to wait for or no outcome arrived in time, in which case the
+// command is reported as accepted.
+func awaitOutcome(ctx context.Context, events <-chan stream.Event, correlationId string, wait time.Duration, success string) (stream.Event, bool) {
+	if events == nil {
+		return stream.Event{}, false
+	}
+	ctx, cancel := context.WithTimeout(ctx, wait)
+	defer cancel()
+	return stream.Await(ctx, events, correlationId, success, list2.StatusEventTypeError)
+}
+
+// writeCommandError writes the error of an ERROR status event as a JSON:API error, carrying the status event error code.
+func writeCommandError(w http.ResponseWriter, e stream.Event) {
+	code := list2.StatusEventErrorUnknownError
+	var se list2.StatusEvent[list2.ErrorStatusEventBody]
+	if err := json.Unmarshal(e.Data, &se); err == nil && se.Body.Error != "" {
+		code = se.Body.Error
+	}
+	rest.WriteError(w, commandErrorStatus(code), code, "")
+}
+
+// commandErrorStatus is the HTTP status of a status event error code.
+func commandErrorStatus(code string) int {
+	switch code {
+	case list2.StatusEventErrorCharacterNotFound:
+		return http.StatusNotFound
+	case list2.StatusEventErrorListFull, list2.StatusEventErrorOtherListFull, list2.StatusEventErrorAlreadyBuddy, list2.StatusEventErrorCannotBuddyGm, list2.StatusEventErrorDifferentWorld, list2.StatusEventErrorVersionConflict:
+		return http.StatusConflict
+	case list2.StatusEventErrorInvalidCapacity, list2.StatusEventErrorCapacityOverflow, list2.StatusEventErrorInvalidSettings:
+		return http.StatusUnprocessableEntity
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// writeBuddyList writes a buddy list with the given status, tagged with its version.
+func writeBuddyList(d *rest.HandlerDependency, c *rest.HandlerContext, w http.ResponseWriter, bl Model, status int) {
+	res, err := model.Map(Transform)(model.FixedProvider(bl))()
+	if err != nil {
+		d.Logger().WithError(err).Errorf("Creating REST model.")
+		rest.WriteError(w, http.StatusInternalServerError, list2.StatusEventErrorUnknownError, "")
+		return
+	}
+	w.Header().Set("Content-Type", "application/vnd.api+json")
+	w.Header().Set("ETag", rest.ETag(bl.Version()))
+	w.WriteHeader(status)
+	server.Marshal[RestModel](d.Logger())(w)(c.ServerInformation())(res)
+}
+
+// handleGetSettings retrieves the privacy settings of a character, which are the defaults until first changed.
+func handleGetSettings(db *gorm.DB) rest.GetHandler {
+	return func(d *rest.HandlerDependency, c *rest.HandlerContext) http.HandlerFunc {
+		return rest.ParseCharacterId(d.Logger(), func(characterId uint32) http.HandlerFunc {
+			return func(w http.ResponseWriter, r *http.Request) {
+				s, err := settings.NewProcessor(d.Logger(), d.Context(), db).GetByCharacterId(characterId)
+				if err != nil {
+					d.Logger().WithError(err).Errorf("Unable to retrieve settings of character [%d].", characterId)
+					rest.WriteError(w, http.StatusInternalServerError, list2.StatusEventErrorUnknownError, "")
+					return
+				}
+				writeSettings(d, c, w, s)
+			}
+		})
+	}
+}
+
+// handleUpdateSettings requests a change to the privacy and visibility settings of a character, by producing an
+// UPDATE_SETTINGS command. Omitted settings are left unchanged. With a Prefer: wait header the changed settings are
+// returned once the SETTINGS_CHANGE event arrives.
+func handleUpdateSettings(db *gorm.DB) rest.InputHandler[settings.RestModel] {
+	return func(d *rest.HandlerDependency, c *rest.HandlerContext, i settings.RestModel) http.HandlerFunc {
+		return rest.ParseCharacterId(d.Logger(), func(characterId uint32) http.HandlerFunc {
+			return func(w http.ResponseWriter, r *http.Request) {
+				ch, err := character.NewProcessor(d.Logger(), d.Context()).GetById(characterId)
+				if err != nil {
+					d.Logger().WithError(err).Errorf("Unable to retrieve character [%d] information.", characterId)
+					rest.WriteError(w, http.StatusNotFound, list2.StatusEventErrorCharacterNotFound, fmt.Sprintf("Character [%d] does not exist.", characterId))
+					return
+				}
+
+				var whisperPolicy *string
+				if i.WhisperPolicy != "" {
+					whisperPolicy = &i.WhisperPolicy
+				}
+				var visibility *string
+				if i.Visibility != "" {
+					visibility = &i.Visibility
+				}
+
+				wait := rest.PreferWait(w, r)
+				events, cancel := subscribeOutcome(d, characterId, wait)
+				defer cancel()
+
+				correlationId := requestCorrelationId(r)
+				err = producer.ProviderImpl(d.Logger())(d.Context())(list2.EnvCommandTopic)(list3.UpdateSettingsCommandProvider(characterId, ch.WorldId(), whisperPolicy, i.DeclineRequests, visibility, i.HiddenGroups, correlationId))
+				if err != nil {
+					rest.WriteError(w, http.StatusInternalServerError, list2.StatusEventErrorUnknownError, "")
+					return
+				}
 
-			w.WriteHeader(http.StatusAccepted)
+				w.Header().Set(CorrelationIdHeader, correlationId)
+				if e, ok := awaitOutcome(r.Context(), events, correlationId, wait, list2.StatusEventTypeSettingsChange); ok {
+					if e.Type == list2.StatusEventTypeError {
+						writeCommandError(w, e)
+						return
+					}
+					s, err := settings.NewProcessor(d.Logger(), d.Context(), db).GetByCharacterId(characterId)
+					if err != nil {
+						rest.WriteError(w, http.StatusInternalServerError, list2.StatusEventErrorUnknownError, "")
+						return
+					}
+					writeSettings(d, c, w, s)
+					return
+				}
+				w.WriteHeader(http.StatusAccepted)
+			}
+		})
+	}
+}
+
+func writeSettings(d *rest.HandlerDependency, c *rest.HandlerContext, w http.ResponseWriter, s settings.Model) {
+	res, err := model.Map(settings.Transform)(model.FixedProvider(s))()
+	if err != nil {
+		d.Logger().WithError(err).Errorf("Creating REST model.")
+		rest.WriteError(w, http.StatusInternalServerError, list2.StatusEventErrorUnknownError, "")
+		return
+	}
+	server.Marshal[settings.RestModel](d.Logger())(w)(c.ServerInformation())(res)
+}
+
+// handleGetRelationship retrieves how a character relates to another.
+func handleGetRelationship(db *gorm.DB) rest.GetHandler {
+	return func(d *rest.HandlerDependency, c *rest.HandlerContext) http.HandlerFunc {
+		return rest.ParseCharacterId(d.Logger(), func(characterId uint32) http.HandlerFunc {
+			return rest.ParseOtherId(d.Logger(), func(otherId uint32) http.HandlerFunc {
+				return func(w http.ResponseWriter, r *http.Request) {
+					rm, err := NewProcessor(d.Logger(), d.Context(), db).GetRelationship(characterId, otherId)
+					if err != nil {
+						d.Logger().WithError(err).Errorf("Unable to retrieve relationship of character [%d] to [%d].", characterId, otherId)
+						rest.WriteError(w, http.StatusInternalServerError, list2.StatusEventErrorUnknownError, "")
+						return
+					}
+
+					res, err := model.Map(TransformRelationship)(model.FixedProvider(rm))()
+					if err != nil {
+						d.Logger().WithError(err).Errorf("Creating REST model.")
+						rest.WriteError(w, http.StatusInternalServerError, list2.StatusEventErrorUnknownError, "")
+						return
+					}
+
+					server.Marshal[RelationshipRestModel](d.Logger())(w)(c.ServerInformation())(res)
+				}
+			})
+		})
+	}
+}
+
+// handleGetRelationships retrieves how a character relates to each of the characters named by filter[characterId], a
+// comma separated list.
+func handleGetRelationships(db *gorm.DB) rest.GetHandler {
+	return func(d *rest.HandlerDependency, c *rest.HandlerContext) http.HandlerFunc {
+		return rest.ParseCharacterId(d.Logger(), func(characterId uint32) http.HandlerFunc {
+			return func(w http.ResponseWriter, r *http.Request) {
+				otherIds, err := rest.ParseUintListFilter(r, "characterId")
+				if err != nil {
+					rest.WriteError(w, http.StatusBadRequest, rest.ErrorCodeInvalidRequest, "filter[characterId] must be a comma separated list of numbers.")
+					return
+				}
+				if err = checkBatchSize(d, otherIds); err != nil {
+					rest.WriteError(w, http.StatusBadRequest, rest.ErrorCodeInvalidRequest, "filter[characterId]: "+err.Error()+".")
+					return
+				}
+
+				rms, err := NewProcessor(d.Logger(), d.Context(), db).GetRelationships(characterId, otherIds)
+				if err != nil {
+					d.Logger().WithError(err).Errorf("Unable to retrieve relationships of character [%d].", characterId)
+					rest.WriteError(w, http.StatusInternalServerError, list2.StatusEventErrorUnknownError, "")
+					return
+				}
+
+				res, err := model.SliceMap(TransformRelationship)(model.FixedProvider(rms))()()
+				if err != nil {
+					d.Logger().WithError(err).Errorf("Creating REST model.")
+					rest.WriteError(w, http.StatusInternalServerError, list2.StatusEventErrorUnknownError, "")
+					return
+				}
+
+				server.Marshal[[]RelationshipRestModel](d.Logger())(w)(c.ServerInformation())(res)
+			}
+		})
+	}
+}
+
+func handleGetBuddyLocation(db *gorm.DB) rest.GetHandler {
+	return func(d *rest.HandlerDependency, c *rest.HandlerContext) http.HandlerFunc {
+		return rest.ParseCharacterId(d.Logger(), func(characterId uint32) http.HandlerFunc {
+			return rest.ParseBuddyId(d.Logger(), func(buddyId uint32) http.HandlerFunc {
+				return func(w http.ResponseWriter, r *http.Request) {
+					b, err := NewProcessor(d.Logger(), d.Context(), db).GetBuddyLocation(characterId, buddyId)
+					if errors.Is(err, gorm.ErrRecordNotFound) || errors.Is(err, ErrNotMutualBuddy) {
+						rest.WriteError(w, http.StatusNotFound, rest.ErrorCodeNotFound, fmt.Sprintf("Character [%d] is not a mutual buddy of character [%d].", buddyId, characterId))
+						return
+					}
+					if err != nil {
+						rest.WriteError(w, http.StatusInternalServerError, list2.StatusEventErrorUnknownError, "")
+						return
+					}
+
+					res, err := model.Map(buddy.TransformLocation)(model.FixedProvider(b))()
+					if err != nil {
+						d.Logger().WithError(err).Errorf("Creating REST model.")
+						rest.WriteError(w, http.StatusInternalServerError, list2.StatusEventErrorUnknownError, "")
+						return
+					}
+
+					server.Marshal[buddy.LocationRestModel](d.Logger())(w)(c.ServerInformation())(res)
+				}
+			})
+		})
+	}
+}
+
+func handleGetCapacityHistory(db *gorm.DB) rest.GetHandler {
+	return func(d *rest.HandlerDependency, c *rest.HandlerContext) http.HandlerFunc {
+		return rest.ParseCharacterId(d.Logger(), func(characterId uint32) http.HandlerFunc {
+			return func(w http.ResponseWriter, r *http.Request) {
+				cs, err := NewProcessor(d.Logger(), d.Context(), db).GetCapacityHistory(characterId)
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					writeNoBuddyList(w, characterId)
+					return
+				}
+				if err != nil {
+					rest.WriteError(w, http.StatusInternalServerError, list2.StatusEventErrorUnknownError, "")
+					return
+				}
+
+				res, err := model.SliceMap(ledger.Transform)(model.FixedProvider(cs))()()
+				if err != nil {
+					d.Logger().WithError(err).Errorf("Creating REST model.")
+					rest.WriteError(w, http.StatusInternalServerError, list2.StatusEventErrorUnknownError, "")
+					return
+				}
+
+				server.Marshal[[]ledger.RestModel](d.Logger())(w)(c.ServerInformation())(res)
+			}
+		})
+	}
+}
+
+// handleGetAudits is an administrative endpoint returning the audit log of a character. The optional filter[from] and
+// filter[to] RFC 3339 timestamps bound the time range.
+func handleGetAudits(db *gorm.DB) rest.GetHandler {
+	return func(d *rest.HandlerDependency, c *rest.HandlerContext) http.HandlerFunc {
+		return rest.ParseCharacterId(d.Logger(), func(characterId uint32) http.HandlerFunc {
+			return func(w http.ResponseWriter, r *http.Request) {
+				from, err := rest.ParseTimeFilter(r, "from")
+				if err != nil {
+					rest.WriteError(w, http.StatusBadRequest, rest.ErrorCodeInvalidRequest, "The filter[from] query parameter must be an RFC 3339 timestamp.")
+					return
+				}
+				to, err := rest.ParseTimeFilter(r, "to")
+				if err != nil {
+					rest.WriteError(w, http.StatusBadRequest, rest.ErrorCodeInvalidRequest, "The filter[to] query parameter must be an RFC 3339 timestamp.")
+					return
+				}
+
+				as, err := NewProcessor(d.Logger(), d.Context(), db).GetAudits(characterId, from, to)
+				if err != nil {
+					rest.WriteError(w, http.StatusInternalServerError, list2.StatusEventErrorUnknownError, "")
+					return
+				}
+
+				res, err := model.SliceMap(audit.Transform)(model.FixedProvider(as))()()
+				if err != nil {
+					d.Logger().WithError(err).Errorf("Creating REST model.")
+					rest.WriteError(w, http.StatusInternalServerError, list2.StatusEventErrorUnknownError, "")
+					return
+				}
+
+				server.Marshal[[]audit.RestModel](d.Logger())(w)(c.ServerInformation())(res)
+			}
+		})
+	}
+}
+
+// handleGetChanges returns the changes of a character's buddy list after the version given by the since query
+// parameter. 410 Gone is returned when those changes are no longer available, in which case the client must reload
+// the list.
+func handleGetChanges(db *gorm.DB) rest.GetHandler {
+	return func(d *rest.HandlerDependency, c *rest.HandlerContext) http.HandlerFunc {
+		return rest.ParseCharacterId(d.Logger(), func(characterId uint32) http.HandlerFunc {
+			return func(w http.ResponseWriter, r *http.Request) {
+				since, err := strconv.ParseUint(r.URL.Query().Get("since"), 10, 32)
+				if err != nil {
+					rest.WriteError(w, http.StatusBadRequest, rest.ErrorCodeInvalidRequest, "The since query parameter must be a buddy list version.")
+					return
+				}
+
+				cs, err := NewProcessor(d.Logger(), d.Context(), db).GetChangesSince(characterId, uint32(since))
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					writeNoBuddyList(w, characterId)
+					return
+				}
+				if errors.Is(err, ErrChangesUnavailable) {
+					rest.WriteError(w, http.StatusGone, rest.ErrorCodeChangesUnavailable, fmt.Sprintf("Changes since version [%d] are no longer available.", since))
+					return
+				}
+				if err != nil {
+					rest.WriteError(w, http.StatusInternalServerError, list2.StatusEventErrorUnknownError, "")
+					return
+				}
+
+				res, err := model.SliceMap(change.Transform)(model.FixedProvider(cs))()()
+				if err != nil {
+					d.Logger().WithError(err).Errorf("Creating REST model.")
+					rest.WriteError(w, http.StatusInternalServerError, list2.StatusEventErrorUnknownError, "")
+					return
+				}
+
+				server.Marshal[[]change.RestModel](d.Logger())(w)(c.ServerInformation())(res)
+			}
+		})
+	}
+}
+
+// HeartbeatInterval is how often an idle event stream is sent a heartbeat.
+const HeartbeatInterval = 15 * time.Second
+
+// handleGetEvents streams the buddy list status events of a character as server-sent events, until the client
+// disconnects or ctx is done. Each event carries the list version as its id. A client reconnecting with a Last-Event-ID
+// header is first sent the changes it missed as CHANGE events, or a RESYNC event when they are no longer available. A
+// client which falls behind the stream is disconnected, and catches up the same way when it reconnects.
+func handleGetEvents(ctx context.Context, db *gorm.DB) rest.GetHandler {
+	return func(d *rest.HandlerDependency, c *rest.HandlerContext) http.HandlerFunc {
+		return rest.ParseCharacterId(d.Logger(), func(characterId uint32) http.HandlerFunc {
+			return func(w http.ResponseWriter, r *http.Request) {
+				var since *uint32
+				if raw := r.Header.Get("Last-Event-ID"); raw != "" {
+					v, err := strconv.ParseUint(raw, 10, 32)
+					if err != nil {
+						rest.WriteError(w, http.StatusBadRequest, rest.ErrorCodeInvalidRequest, "The Last-Event-ID header must be a buddy list version.")
+						return
+					}
+					v32 := uint32(v)
+					since = &v32
+				}
+
+				p := NewProcessor(d.Logger(), d.Context(), db)
+				_, err := p.GetByCharacterId(characterId)
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					writeNoBuddyList(w, characterId)
+					return
+				}
+				if err != nil {
+					rest.WriteError(w, http.StatusInternalServerError, list2.StatusEventErrorUnknownError, "")
+					return
+				}
+
+				// Subscribe before catching up, so no event falls between the two.
+				t := tenant.MustFromContext(d.Context())
+				events, cancel := stream.GetHub().Subscribe(t.Id(), characterId)
+				defer cancel()
+
+				rc := http.NewResponseController(w)
+				_ = rc.SetWriteDeadline(time.Time{})
+				w.Header().Set("Content-Type", "text/event-stream")
+				w.Header().Set("Cache-Control", "no-cache")
+				w.Header().Set("Connection", "keep-alive")
+				w.WriteHeader(http.StatusOK)
+
+				// Events up to the caught up version were already sent as changes.
+				var caughtUp uint32
+				if since != nil {
+					caughtUp, err = writeMissedChanges(w, p, characterId, *since)
+					if err != nil {
+						d.Logger().WithError(err).Debugf("Event stream of character [%d] closed while catching up.", characterId)
+						return
+					}
+				}
+				if err = rc.Flush(); err != nil {
+					return
+				}
+
+				heartbeat := time.NewTicker(HeartbeatInterval)
+				defer heartbeat.Stop()
+				for {
+					select {
+					case <-ctx.Done():
+						return
+					case <-r.Context().Done():
+						return
+					case e, ok := <-events:
+						if !ok {
+							d.Logger().Debugf("Event stream of character [%d] fell behind and was closed.", characterId)
+							return
+						}
+						if e.Version != 0 && e.Version <= caughtUp {
+							continue
+						}
+						err = stream.WriteEvent(w, e.Version, e.Type, e.Data)
+					case <-heartbeat.C:
+						err = stream.WriteHeartbeat(w)
+					}
+					if err == nil {
+						err = rc.Flush()
+					}
+					if err != nil {
+						d.Logger().WithError(err).Debugf("Event stream of character [%d] closed.", characterId)
+						return
+					}
+				}
+			}
+		})
+	}
+}
+
+// writeMissedChanges writes the changes after since as CHANGE events, and returns the version caught up to. When the
+// changes are no longer available a RESYNC event carrying the current list version is written instead, and the client
+// must reload the list.
+func writeMissedChanges(w http.ResponseWriter, p Processor, characterId uint32, since uint32) (uint32, error) {
+	cs, err := p.GetChangesSince(characterId, since)
+	if errors.Is(err, ErrChangesUnavailable) {
+		bl, err := p.GetByCharacterId(characterId)
+		if err != nil {
+			return 0, err
 		}
-	})
+		return bl.Version(), stream.WriteEvent(w, bl.Version(), StreamEventResync, []byte("{}"))
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	caughtUp := since
+	for _, cm := range cs {
+		rm, err := change.Transform(cm)
+		if err != nil {
+			return 0, err
+		}
+		data, err := json.Marshal(rm)
+		if err != nil {
+			return 0, err
+		}
+		err = stream.WriteEvent(w, cm.Version(), StreamEventChange, data)
+		if err != nil {
+			return 0, err
+		}
+		caughtUp = cm.Version()
+	}
+	return caughtUp, nil
 }
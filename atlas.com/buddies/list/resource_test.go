@@ -0,0 +1,48 @@
+package list
+
+import (
+	"atlas-buddies/buddy"
+	list2 "atlas-buddies/kafka/message/list"
+	"net/http"
+	"testing"
+)
+
+func TestCommandErrorStatus(t *testing.T) {
+	tests := []struct {
+		code     string
+		expected int
+	}{
+		{list2.StatusEventErrorCharacterNotFound, http.StatusNotFound},
+		{list2.StatusEventErrorListFull, http.StatusConflict},
+		{list2.StatusEventErrorOtherListFull, http.StatusConflict},
+		{list2.StatusEventErrorAlreadyBuddy, http.StatusConflict},
+		{list2.StatusEventErrorCannotBuddyGm, http.StatusConflict},
+		{list2.StatusEventErrorDifferentWorld, http.StatusConflict},
+		{list2.StatusEventErrorVersionConflict, http.StatusConflict},
+		{list2.StatusEventErrorInvalidCapacity, http.StatusUnprocessableEntity},
+		{list2.StatusEventErrorCapacityOverflow, http.StatusUnprocessableEntity},
+		{list2.StatusEventErrorInvalidSettings, http.StatusUnprocessableEntity},
+		{list2.StatusEventErrorUnknownError, http.StatusInternalServerError},
+		{"", http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		if status := commandErrorStatus(tt.code); status != tt.expected {
+			t.Errorf("Expected error [%s] to map to %d, got %d", tt.code, tt.expected, status)
+		}
+	}
+}
+
+func TestBuddyQueryKey(t *testing.T) {
+	online := true
+	offline := false
+	a := buddyQueryKey(buddy.Query{Filter: buddy.Filter{Online: &online}, Limit: 20})
+	if a != buddyQueryKey(buddy.Query{Filter: buddy.Filter{Online: &online}, Limit: 20}) {
+		t.Errorf("Expected equal queries to share a key")
+	}
+	if a == buddyQueryKey(buddy.Query{Filter: buddy.Filter{Online: &offline}, Limit: 20}) {
+		t.Errorf("Expected a different filter to change the key")
+	}
+	if a == buddyQueryKey(buddy.Query{Filter: buddy.Filter{Online: &online}, Offset: 20, Limit: 20}) {
+		t.Errorf("Expected a different page to change the key")
+	}
+}
@@ -3,13 +3,16 @@ package list
 import (
 	"atlas-buddies/buddy"
 	"github.com/google/uuid"
+	"strconv"
 )
 
 type RestModel struct {
 	Id          uuid.UUID         `json:"-"`
 	TenantId    uuid.UUID         `json:"-"`
 	CharacterId uint32            `json:"characterId"`
+	WorldId     byte              `json:"worldId"`
 	Capacity    byte              `json:"capacity"`
+	Version     uint32            `json:"version"`
 	Buddies     []buddy.RestModel `json:"buddies"`
 }
 
@@ -35,6 +38,25 @@ func (r *RestModel) SetID(strId string) error {
 	return nil
 }
 
+// QueryRestModel asks for the buddy lists of many characters at once, for sets too large for a query string.
+type QueryRestModel struct {
+	Id           string   `json:"-"`
+	CharacterIds []uint32 `json:"characterIds"`
+}
+
+func (r QueryRestModel) GetName() string {
+	return "buddy-list-queries"
+}
+
+func (r QueryRestModel) GetID() string {
+	return r.Id
+}
+
+func (r *QueryRestModel) SetID(strId string) error {
+	r.Id = strId
+	return nil
+}
+
 func Transform(m Model) (RestModel, error) {
 	buddies := make([]buddy.RestModel, 0)
 	for _, bm := range m.buddies {
@@ -49,7 +71,9 @@ func Transform(m Model) (RestModel, error) {
 		Id:          m.id,
 		TenantId:    m.tenantId,
 		CharacterId: m.characterId,
+		WorldId:     m.worldId,
 		Capacity:    m.capacity,
+		Version:     m.version,
 		Buddies:     buddies,
 	}, nil
 }
@@ -63,3 +87,42 @@ func Extract(rm RestModel) (Model, error) {
 		buddies:     nil,
 	}, nil
 }
+
+// RelationshipRestModel describes how the character owning the buddy list relates to another character, identified by
+// the id of the resource.
+type RelationshipRestModel struct {
+	OtherId        uint32 `json:"-"`
+	CharacterId    uint32 `json:"characterId"`
+	Relationship   string `json:"relationship"`
+	Mutual         bool   `json:"mutual"`
+	WhisperPolicy  string `json:"whisperPolicy"`
+	WhisperAllowed bool   `json:"whisperAllowed"`
+}
+
+func (r RelationshipRestModel) GetName() string {
+	return "buddy-relationships"
+}
+
+func (r RelationshipRestModel) GetID() string {
+	return strconv.Itoa(int(r.OtherId))
+}
+
+func (r *RelationshipRestModel) SetID(strId string) error {
+	id, err := strconv.Atoi(strId)
+	if err != nil {
+		return err
+	}
+	r.OtherId = uint32(id)
+	return nil
+}
+
+func TransformRelationship(m RelationshipModel) (RelationshipRestModel, error) {
+	return RelationshipRestModel{
+		OtherId:        m.otherId,
+		CharacterId:    m.characterId,
+		Relationship:   m.relationship,
+		Mutual:         m.Mutual(),
+		WhisperPolicy:  m.WhisperPolicy(),
+		WhisperAllowed: m.WhisperAllowed(),
+	}, nil
+}
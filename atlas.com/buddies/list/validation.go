@@ -0,0 +1,141 @@
+package list
+
+import (
+	"atlas-buddies/buddy"
+	"atlas-buddies/configuration"
+	list2 "atlas-buddies/kafka/message/list"
+	"atlas-buddies/rest"
+	"atlas-buddies/settings"
+	"errors"
+	"fmt"
+	"github.com/Chronicle20/atlas-tenant"
+	"github.com/gorilla/mux"
+	"net/http"
+	"strconv"
+	"unicode"
+)
+
+// MaxGroupLength is the longest buddy group name accepted, in characters.
+const MaxGroupLength = 16
+
+// createBuddyListRules validate a buddy list created through the REST API.
+var createBuddyListRules = []rest.Rule[RestModel]{
+	{Field: "capacity", Code: list2.StatusEventErrorInvalidCapacity, Check: capacityWithinPolicyOrDefault},
+}
+
+// buddyListRules validate a buddy list updated through the REST API.
+var buddyListRules = []rest.Rule[RestModel]{
+	{Field: "capacity", Code: list2.StatusEventErrorInvalidCapacity, Check: capacityWithinPolicy},
+}
+
+// queryRules validate a batch buddy list query made through the REST API.
+var queryRules = []rest.Rule[QueryRestModel]{
+	{Field: "characterIds", Check: withinBatchSize},
+}
+
+// settingsRules validate privacy settings updated through the REST API.
+var settingsRules = []rest.Rule[settings.RestModel]{
+	{Field: "whisperPolicy", Code: list2.StatusEventErrorInvalidSettings, Check: knownWhisperPolicy},
+	{Field: "visibility", Code: list2.StatusEventErrorInvalidSettings, Check: knownVisibility},
+	{Field: "hiddenGroups", Code: list2.StatusEventErrorInvalidSettings, Check: validHiddenGroups},
+}
+
+// buddyRules validate a buddy added through the REST API.
+var buddyRules = []rest.Rule[buddy.RestModel]{
+	// The buddy character id is the resource id.
+	{Field: "characterId", Pointer: "/data/id", Check: notSelf},
+	{Field: "group", Check: validGroup},
+}
+
+// capacityWithinPolicy requires the capacity to be allowed by the capacity policy of the tenant.
+func capacityWithinPolicy(d *rest.HandlerDependency, _ *http.Request, m RestModel) error {
+	return checkCapacity(configuration.ForTenant(d.Logger(), tenant.MustFromContext(d.Context())).Capacity(), m.Capacity)
+}
+
+func checkCapacity(p configuration.CapacityPolicy, capacity byte) error {
+	if !p.Allows(capacity) {
+		return fmt.Errorf("capacity must be between 1 and %d", p.Maximum())
+	}
+	return nil
+}
+
+// capacityWithinPolicyOrDefault allows an omitted capacity, which is created with the tenant default, and otherwise
+// requires the capacity to be allowed by the capacity policy of the tenant.
+func capacityWithinPolicyOrDefault(d *rest.HandlerDependency, r *http.Request, m RestModel) error {
+	if m.Capacity == 0 {
+		return nil
+	}
+	return capacityWithinPolicy(d, r, m)
+}
+
+// withinBatchSize requires at least one character, and no more than the tenant allows in a single request.
+func withinBatchSize(d *rest.HandlerDependency, _ *http.Request, m QueryRestModel) error {
+	return checkBatchSize(d, m.CharacterIds)
+}
+
+func checkBatchSize(d *rest.HandlerDependency, characterIds []uint32) error {
+	if len(characterIds) == 0 {
+		return errors.New("at least one characterId is required")
+	}
+	if limit := configuration.ForTenant(d.Logger(), tenant.MustFromContext(d.Context())).MaxBatchSize(); len(characterIds) > limit {
+		return fmt.Errorf("at most %d characterIds may be requested at once", limit)
+	}
+	return nil
+}
+
+// notSelf requires a buddy other than the owner of the buddy list. A malformed owner in the path is left to be reported
+// by the handler.
+func notSelf(_ *rest.HandlerDependency, r *http.Request, m buddy.RestModel) error {
+	if m.CharacterId == 0 {
+		return errors.New("characterId is required")
+	}
+	characterId, err := strconv.ParseUint(mux.Vars(r)["characterId"], 10, 32)
+	if err == nil && uint32(characterId) == m.CharacterId {
+		return errors.New("a character cannot add themselves as a buddy")
+	}
+	return nil
+}
+
+// validGroup requires a group name of at most MaxGroupLength letters, digits, spaces, hyphens and underscores. An
+// empty group is allowed, and stands for the default group.
+func validGroup(_ *rest.HandlerDependency, _ *http.Request, m buddy.RestModel) error {
+	return checkGroup("group", m.Group)
+}
+
+func checkGroup(field string, group string) error {
+	if len([]rune(group)) > MaxGroupLength {
+		return fmt.Errorf("%s must be at most %d characters", field, MaxGroupLength)
+	}
+	for _, c := range group {
+		if !unicode.IsLetter(c) && !unicode.IsDigit(c) && c != ' ' && c != '-' && c != '_' {
+			return fmt.Errorf("%s may only contain letters, digits, spaces, hyphens and underscores", field)
+		}
+	}
+	return nil
+}
+
+// knownWhisperPolicy requires the whisper policy, when given, to be EVERYONE or BUDDIES.
+func knownWhisperPolicy(_ *rest.HandlerDependency, _ *http.Request, m settings.RestModel) error {
+	if m.WhisperPolicy != "" && !settings.ValidWhisperPolicy(m.WhisperPolicy) {
+		return fmt.Errorf("whisperPolicy must be %s or %s", settings.WhisperPolicyEveryone, settings.WhisperPolicyBuddies)
+	}
+	return nil
+}
+
+// knownVisibility requires the visibility, when given, to be VISIBLE, HIDDEN or HIDDEN_FROM_GROUPS.
+func knownVisibility(_ *rest.HandlerDependency, _ *http.Request, m settings.RestModel) error {
+	if m.Visibility != "" && !settings.ValidVisibility(m.Visibility) {
+		return fmt.Errorf("visibility must be %s, %s or %s", settings.VisibilityVisible, settings.VisibilityHidden, settings.VisibilityHiddenFromGroups)
+	}
+	return nil
+}
+
+// validHiddenGroups requires each hidden group to be a valid group name.
+func validHiddenGroups(_ *rest.HandlerDependency, _ *http.Request, m settings.RestModel) error {
+	for _, g := range m.HiddenGroups {
+		if err := checkGroup("hiddenGroups", g); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,137 @@
+package list
+
+import (
+	"atlas-buddies/buddy"
+	"atlas-buddies/configuration"
+	"atlas-buddies/rest"
+	"atlas-buddies/settings"
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Chronicle20/atlas-tenant"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+)
+
+func TestValidGroup(t *testing.T) {
+	tests := []struct {
+		group string
+		valid bool
+	}{
+		{"", true},
+		{DefaultGroup, true},
+		{"Guild_Mates-2", true},
+		{strings.Repeat("a", MaxGroupLength), true},
+		{strings.Repeat("a", MaxGroupLength+1), false},
+		{"Friends!", false},
+		{"<script>", false},
+	}
+	for _, tt := range tests {
+		err := validGroup(nil, nil, buddy.RestModel{Group: tt.group})
+		if (err == nil) != tt.valid {
+			t.Errorf("Expected group [%s] valid to be %t, got error %v", tt.group, tt.valid, err)
+		}
+	}
+}
+
+func TestCheckCapacity(t *testing.T) {
+	maximum := byte(20)
+	c := configuration.Configuration{Defaults: configuration.Settings{Capacity: &configuration.CapacitySettings{Maximum: &maximum}}}
+	p := c.Resolve(uuid.New(), "GMS", 83).Capacity()
+
+	tests := []struct {
+		capacity byte
+		valid    bool
+	}{
+		{0, false},
+		{1, true},
+		{20, true},
+		{21, false},
+	}
+	for _, tt := range tests {
+		err := checkCapacity(p, tt.capacity)
+		if (err == nil) != tt.valid {
+			t.Errorf("Expected capacity [%d] valid to be %t, got error %v", tt.capacity, tt.valid, err)
+		}
+	}
+}
+
+func TestCapacityWithinPolicy(t *testing.T) {
+	tm, _ := tenant.Create(uuid.New(), "GMS", 83, 1)
+	d := rest.NewHandlerDependency(logrus.New(), tenant.WithContext(context.Background(), tm))
+
+	tests := []struct {
+		capacity       byte
+		valid          bool
+		validOrDefault bool
+	}{
+		{0, false, true},
+		{1, true, true},
+		{255, true, true},
+	}
+	for _, tt := range tests {
+		if err := capacityWithinPolicy(d, nil, RestModel{Capacity: tt.capacity}); (err == nil) != tt.valid {
+			t.Errorf("Expected capacity [%d] valid to be %t, got error %v", tt.capacity, tt.valid, err)
+		}
+		if err := capacityWithinPolicyOrDefault(d, nil, RestModel{Capacity: tt.capacity}); (err == nil) != tt.validOrDefault {
+			t.Errorf("Expected capacity [%d] valid or default to be %t, got error %v", tt.capacity, tt.validOrDefault, err)
+		}
+	}
+}
+
+func TestNotSelf(t *testing.T) {
+	r := mux.SetURLVars(httptest.NewRequest("POST", "/characters/12345/buddy-list/buddies", nil), map[string]string{"characterId": "12345"})
+
+	if err := notSelf(nil, r, buddy.RestModel{CharacterId: 67890}); err != nil {
+		t.Errorf("Expected another character to be valid, got %v", err)
+	}
+	if err := notSelf(nil, r, buddy.RestModel{CharacterId: 12345}); err == nil {
+		t.Errorf("Expected the owner to be rejected")
+	}
+	if err := notSelf(nil, r, buddy.RestModel{}); err == nil {
+		t.Errorf("Expected a missing characterId to be rejected")
+	}
+}
+
+func TestKnownWhisperPolicy(t *testing.T) {
+	tests := []struct {
+		policy string
+		valid  bool
+	}{
+		{"", true},
+		{settings.WhisperPolicyEveryone, true},
+		{settings.WhisperPolicyBuddies, true},
+		{"NOBODY", false},
+	}
+	for _, tt := range tests {
+		err := knownWhisperPolicy(nil, nil, settings.RestModel{WhisperPolicy: tt.policy})
+		if (err == nil) != tt.valid {
+			t.Errorf("Expected whisper policy [%s] valid to be %t, got error %v", tt.policy, tt.valid, err)
+		}
+	}
+}
+
+func TestKnownVisibility(t *testing.T) {
+	tests := []struct {
+		visibility string
+		valid      bool
+	}{
+		{"", true},
+		{settings.VisibilityVisible, true},
+		{settings.VisibilityHidden, true},
+		{settings.VisibilityHiddenFromGroups, true},
+		{"INVISIBLE", false},
+	}
+	for _, tt := range tests {
+		err := knownVisibility(nil, nil, settings.RestModel{Visibility: tt.visibility})
+		if (err == nil) != tt.valid {
+			t.Errorf("Expected visibility [%s] valid to be %t, got error %v", tt.visibility, tt.valid, err)
+		}
+	}
+	if err := validHiddenGroups(nil, nil, settings.RestModel{HiddenGroups: []string{"Guild", "a,b"}}); err == nil {
+		t.Errorf("Expected an invalid hidden group to be rejected")
+	}
+}
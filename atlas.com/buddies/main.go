@@ -1,18 +1,29 @@
 package main
 
 import (
+	"atlas-buddies/audit"
 	"atlas-buddies/buddy"
+	"atlas-buddies/change"
 	"atlas-buddies/database"
+	"atlas-buddies/grant"
 	"atlas-buddies/kafka/consumer/cashshop"
+	"atlas-buddies/kafka/consumer/channel"
 	"atlas-buddies/kafka/consumer/character"
 	invite2 "atlas-buddies/kafka/consumer/invite"
 	list2 "atlas-buddies/kafka/consumer/list"
+	"atlas-buddies/ledger"
 	"atlas-buddies/list"
 	"atlas-buddies/logger"
+	"atlas-buddies/presence"
 	"atlas-buddies/service"
+	"atlas-buddies/settings"
+	"atlas-buddies/stream"
 	"atlas-buddies/tracing"
+	"fmt"
 	"github.com/Chronicle20/atlas-kafka/consumer"
 	"github.com/Chronicle20/atlas-rest/server"
+	"github.com/google/uuid"
+	"os"
 )
 
 const serviceName = "atlas-buddies"
@@ -49,22 +60,38 @@ func main() {
 		l.WithError(err).Fatal("Unable to initialize tracer.")
 	}
 
-	db := database.Connect(l, database.SetMigrations(list.Migration, buddy.Migration))
+	db := database.Connect(l, database.SetMigrations(list.Migration, buddy.Migration, grant.Migration, ledger.Migration, audit.Migration, change.Migration, settings.Migration, presence.Migration))
 
 	cmf := consumer.GetManager().AddConsumer(l, tdm.Context(), tdm.WaitGroup())
 	character.InitConsumers(l)(cmf)(consumerGroupId)
 	list2.InitConsumers(l)(cmf)(consumerGroupId)
 	invite2.InitConsumers(l)(cmf)(consumerGroupId)
 	cashshop.InitConsumers(l)(cmf)(consumerGroupId)
+	channel.InitConsumers(l)(cmf)(consumerGroupId)
 	character.InitHandlers(l)(db)(consumer.GetManager().RegisterHandler)
 	list2.InitHandlers(l)(db)(consumer.GetManager().RegisterHandler)
 	invite2.InitHandlers(l)(db)(consumer.GetManager().RegisterHandler)
 	cashshop.InitHandlers(l)(db)(consumer.GetManager().RegisterHandler)
+	channel.InitHandlers(l)(db)(consumer.GetManager().RegisterHandler)
 
-	server.CreateService(l, tdm.Context(), tdm.WaitGroup(), GetServer().GetPrefix(), list.InitResource(GetServer())(db))
+	// Every instance streams every status event to its own subscribers.
+	streamConsumerGroupId := fmt.Sprintf("%s - %s", consumerGroupId, instanceId())
+	list2.InitStatusEventConsumers(l)(cmf)(streamConsumerGroupId)
+	list2.InitStatusEventHandlers(l)(stream.GetHub())(consumer.GetManager().RegisterHandler)
+
+	server.CreateService(l, tdm.Context(), tdm.WaitGroup(), GetServer().GetPrefix(), list.InitResource(GetServer())(tdm.Context())(db))
 
 	tdm.TeardownFunc(tracing.Teardown(l)(tc))
 
 	tdm.Wait()
 	l.Infoln("Service shutdown.")
 }
+
+// instanceId identifies this instance across restarts, so its stream consumer group is reused rather than a new group
+// being left behind on every start. The host name is the pod name when running in Kubernetes.
+func instanceId() string {
+	if h, err := os.Hostname(); err == nil && h != "" {
+		return h
+	}
+	return uuid.New().String()
+}
@@ -0,0 +1,27 @@
+package presence
+
+import (
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"time"
+)
+
+func Migration(db *gorm.DB) error {
+	return db.AutoMigrate(&Entity{})
+}
+
+// Entity records where a character actually is, regardless of what their buddies are shown. It is what a character
+// hiding their presence is revealed as once they become visible again.
+type Entity struct {
+	TenantId    uuid.UUID `gorm:"primaryKey;type:uuid;not null"`
+	CharacterId uint32    `gorm:"primaryKey;autoIncrement:false;not null"`
+	WorldId     byte      `gorm:"not null;default:0"`
+	ChannelId   int8      `gorm:"not null;default:-1"`
+	MapId       uint32    `gorm:"not null;default:0"`
+	InShop      bool      `gorm:"not null;default:false"`
+	UpdatedAt   time.Time
+}
+
+func (e Entity) TableName() string {
+	return "character_presence"
+}
@@ -0,0 +1,58 @@
+package rest
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+)
+
+// Error codes of failures which have no counterpart among the status event error codes. Handlers otherwise respond with
+// the status event error code of the failure, such as CHARACTER_NOT_FOUND or UNKNOWN_ERROR.
+const (
+	// ErrorCodeInvalidRequest indicates a malformed path parameter, query parameter, header or body.
+	ErrorCodeInvalidRequest = "INVALID_REQUEST"
+	// ErrorCodeInvalidTenant indicates missing or malformed tenant headers.
+	ErrorCodeInvalidTenant = "INVALID_TENANT"
+	// ErrorCodeNotFound indicates the requested resource does not exist.
+	ErrorCodeNotFound = "NOT_FOUND"
+	// ErrorCodeAlreadyExists indicates the resource to create already exists.
+	ErrorCodeAlreadyExists = "ALREADY_EXISTS"
+	// ErrorCodeChangesUnavailable indicates the requested buddy list changes are no longer retained.
+	ErrorCodeChangesUnavailable = "CHANGES_UNAVAILABLE"
+	// ErrorCodeInvalidAttribute indicates an attribute of the request resource failed validation.
+	ErrorCodeInvalidAttribute = "INVALID_ATTRIBUTE"
+)
+
+// Error is a JSON:API error object.
+type Error struct {
+	Status string       `json:"status"`
+	Code   string       `json:"code,omitempty"`
+	Detail string       `json:"detail,omitempty"`
+	Source *ErrorSource `json:"source,omitempty"`
+}
+
+// ErrorSource locates the cause of an error within the request document.
+type ErrorSource struct {
+	Pointer string `json:"pointer"`
+}
+
+type errorDocument struct {
+	Errors []Error `json:"errors"`
+}
+
+// WriteError writes a JSON:API errors document holding a single error with the given status, code and detail.
+func WriteError(w http.ResponseWriter, status int, code string, detail string) {
+	WriteErrors(w, status, Error{Code: code, Detail: detail})
+}
+
+// WriteErrors writes a JSON:API errors document with the given status. Errors without a status are given it.
+func WriteErrors(w http.ResponseWriter, status int, errs ...Error) {
+	for i := range errs {
+		if errs[i].Status == "" {
+			errs[i].Status = strconv.Itoa(status)
+		}
+	}
+	w.Header().Set("Content-Type", "application/vnd.api+json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(errorDocument{Errors: errs})
+}
@@ -2,7 +2,10 @@ package rest
 
 import (
 	"context"
+	"fmt"
 	"github.com/Chronicle20/atlas-rest/server"
+	"github.com/Chronicle20/atlas-tenant"
+	"github.com/google/uuid"
 	"github.com/gorilla/mux"
 	"github.com/jtumidanski/api2go/jsonapi"
 	"github.com/sirupsen/logrus"
@@ -16,6 +19,11 @@ type HandlerDependency struct {
 	ctx context.Context
 }
 
+// NewHandlerDependency creates the dependencies of a handler, as RegisterHandler does for each request.
+func NewHandlerDependency(l logrus.FieldLogger, ctx context.Context) *HandlerDependency {
+	return &HandlerDependency{l: l, ctx: ctx}
+}
+
 func (h HandlerDependency) Logger() logrus.FieldLogger {
 	return h.l
 }
@@ -42,7 +50,7 @@ func ParseInput[M any](d *HandlerDependency, c *HandlerContext, next InputHandle
 
 		body, err := io.ReadAll(r.Body)
 		if err != nil {
-			w.WriteHeader(http.StatusBadRequest)
+			WriteError(w, http.StatusBadRequest, ErrorCodeInvalidRequest, "Unable to read the request body.")
 			return
 		}
 		defer r.Body.Close()
@@ -50,7 +58,7 @@ func ParseInput[M any](d *HandlerDependency, c *HandlerContext, next InputHandle
 		err = jsonapi.Unmarshal(body, &model)
 		if err != nil {
 			d.l.WithError(err).Errorln("Deserializing input", err)
-			w.WriteHeader(http.StatusBadRequest)
+			WriteError(w, http.StatusBadRequest, ErrorCodeInvalidRequest, "The request body is not a valid JSON:API document.")
 			return
 		}
 		next(d, c, model)(w, r)
@@ -62,7 +70,7 @@ func RegisterHandler(l logrus.FieldLogger) func(si jsonapi.ServerInformation) fu
 		return func(handlerName string, handler GetHandler) http.HandlerFunc {
 			return server.RetrieveSpan(l, handlerName, context.Background(), func(sl logrus.FieldLogger, sctx context.Context) http.HandlerFunc {
 				fl := sl.WithFields(logrus.Fields{"originator": handlerName, "type": "rest_handler"})
-				return server.ParseTenant(fl, sctx, func(tl logrus.FieldLogger, tctx context.Context) http.HandlerFunc {
+				return ParseTenant(fl, sctx, func(tl logrus.FieldLogger, tctx context.Context) http.HandlerFunc {
 					return handler(&HandlerDependency{l: tl, ctx: tctx}, &HandlerContext{si: si})
 				})
 			})
@@ -75,7 +83,7 @@ func RegisterInputHandler[M any](l logrus.FieldLogger) func(si jsonapi.ServerInf
 		return func(handlerName string, handler InputHandler[M]) http.HandlerFunc {
 			return server.RetrieveSpan(l, handlerName, context.Background(), func(sl logrus.FieldLogger, sctx context.Context) http.HandlerFunc {
 				fl := sl.WithFields(logrus.Fields{"originator": handlerName, "type": "rest_handler"})
-				return server.ParseTenant(fl, sctx, func(tl logrus.FieldLogger, tctx context.Context) http.HandlerFunc {
+				return ParseTenant(fl, sctx, func(tl logrus.FieldLogger, tctx context.Context) http.HandlerFunc {
 					return ParseInput[M](&HandlerDependency{l: tl, ctx: tctx}, &HandlerContext{si: si}, handler)
 				})
 			})
@@ -83,6 +91,43 @@ func RegisterInputHandler[M any](l logrus.FieldLogger) func(si jsonapi.ServerInf
 	}
 }
 
+// ParseTenant reads the tenant of a request from its TENANT_ID, REGION, MAJOR_VERSION and MINOR_VERSION headers, as
+// server.ParseTenant does, but reports missing or malformed headers as a JSON:API error.
+func ParseTenant(l logrus.FieldLogger, ctx context.Context, next server.SpanHandler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := uuid.Parse(r.Header.Get("TENANT_ID"))
+		if err != nil {
+			l.WithError(err).Errorf("Unable to parse TENANT_ID header.")
+			WriteError(w, http.StatusBadRequest, ErrorCodeInvalidTenant, "The TENANT_ID header must be a UUID.")
+			return
+		}
+		region := r.Header.Get("REGION")
+		if region == "" {
+			WriteError(w, http.StatusBadRequest, ErrorCodeInvalidTenant, "The REGION header is required.")
+			return
+		}
+		majorVersion, err := strconv.ParseUint(r.Header.Get("MAJOR_VERSION"), 10, 16)
+		if err != nil {
+			WriteError(w, http.StatusBadRequest, ErrorCodeInvalidTenant, "The MAJOR_VERSION header must be a number.")
+			return
+		}
+		minorVersion, err := strconv.ParseUint(r.Header.Get("MINOR_VERSION"), 10, 16)
+		if err != nil {
+			WriteError(w, http.StatusBadRequest, ErrorCodeInvalidTenant, "The MINOR_VERSION header must be a number.")
+			return
+		}
+
+		t, err := tenant.Create(id, region, uint16(majorVersion), uint16(minorVersion))
+		if err != nil {
+			l.WithError(err).Errorf("Unable to create tenant from headers.")
+			WriteError(w, http.StatusBadRequest, ErrorCodeInvalidTenant, "")
+			return
+		}
+		tl := l.WithField("tenant", t.Id().String()).WithField("region", t.Region()).WithField("ms.version", fmt.Sprintf("%d.%d", t.MajorVersion(), t.MinorVersion()))
+		next(tl, tenant.WithContext(ctx, t))(w, r)
+	}
+}
+
 type CharacterIdHandler func(characterId uint32) http.HandlerFunc
 
 func ParseCharacterId(l logrus.FieldLogger, next CharacterIdHandler) http.HandlerFunc {
@@ -90,9 +135,37 @@ func ParseCharacterId(l logrus.FieldLogger, next CharacterIdHandler) http.Handle
 		characterId, err := strconv.Atoi(mux.Vars(r)["characterId"])
 		if err != nil {
 			l.WithError(err).Errorf("Unable to properly parse characterId from path.")
-			w.WriteHeader(http.StatusBadRequest)
+			WriteError(w, http.StatusBadRequest, ErrorCodeInvalidRequest, "The characterId path parameter must be a number.")
 			return
 		}
 		next(uint32(characterId))(w, r)
 	}
 }
+
+type OtherIdHandler func(otherId uint32) http.HandlerFunc
+
+func ParseOtherId(l logrus.FieldLogger, next OtherIdHandler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		otherId, err := strconv.Atoi(mux.Vars(r)["otherId"])
+		if err != nil {
+			l.WithError(err).Errorf("Unable to properly parse otherId from path.")
+			WriteError(w, http.StatusBadRequest, ErrorCodeInvalidRequest, "The otherId path parameter must be a number.")
+			return
+		}
+		next(uint32(otherId))(w, r)
+	}
+}
+
+type BuddyIdHandler func(buddyId uint32) http.HandlerFunc
+
+func ParseBuddyId(l logrus.FieldLogger, next BuddyIdHandler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		buddyId, err := strconv.Atoi(mux.Vars(r)["buddyId"])
+		if err != nil {
+			l.WithError(err).Errorf("Unable to properly parse buddyId from path.")
+			WriteError(w, http.StatusBadRequest, ErrorCodeInvalidRequest, "The buddyId path parameter must be a number.")
+			return
+		}
+		next(uint32(buddyId))(w, r)
+	}
+}
@@ -0,0 +1,220 @@
+package rest
+
+import (
+	"errors"
+	"hash/fnv"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultPageNumber = 1
+	MaxPageSize       = 100
+	// MaxWait bounds how long a request asking to wait for the outcome of a command is held.
+	MaxWait = 30 * time.Second
+)
+
+type SortField struct {
+	Field      string
+	Descending bool
+}
+
+type Page struct {
+	Number int
+	Size   int
+}
+
+// Offset returns the zero based row offset of the page. A page without a size has no offset.
+func (p Page) Offset() int {
+	if p.Size == 0 {
+		return 0
+	}
+	return (p.Number - 1) * p.Size
+}
+
+// ParseBoolFilter reads a JSON:API filter[name] query parameter as a boolean. Nil is returned when absent.
+func ParseBoolFilter(r *http.Request, name string) (*bool, error) {
+	raw, ok := r.URL.Query()["filter["+name+"]"]
+	if !ok || len(raw) == 0 || raw[0] == "" {
+		return nil, nil
+	}
+	v, err := strconv.ParseBool(raw[0])
+	if err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+// ParseUintFilter reads a JSON:API filter[name] query parameter as an unsigned integer. Nil is returned when absent.
+func ParseUintFilter(r *http.Request, name string) (*uint32, error) {
+	raw, ok := r.URL.Query()["filter["+name+"]"]
+	if !ok || len(raw) == 0 || raw[0] == "" {
+		return nil, nil
+	}
+	v, err := strconv.ParseUint(raw[0], 10, 32)
+	if err != nil {
+		return nil, err
+	}
+	r32 := uint32(v)
+	return &r32, nil
+}
+
+// ParseUintListFilter reads a JSON:API filter[name] query parameter as a comma separated list of unsigned integers. Nil
+// is returned when absent.
+func ParseUintListFilter(r *http.Request, name string) ([]uint32, error) {
+	raw := ParseStringFilter(r, name)
+	if raw == nil {
+		return nil, nil
+	}
+	results := make([]uint32, 0)
+	for _, f := range strings.Split(*raw, ",") {
+		v, err := strconv.ParseUint(strings.TrimSpace(f), 10, 32)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, uint32(v))
+	}
+	return results, nil
+}
+
+// ParseStringFilter reads a JSON:API filter[name] query parameter. Nil is returned when absent.
+func ParseStringFilter(r *http.Request, name string) *string {
+	raw, ok := r.URL.Query()["filter["+name+"]"]
+	if !ok || len(raw) == 0 || raw[0] == "" {
+		return nil
+	}
+	return &raw[0]
+}
+
+// ParseTimeFilter reads a JSON:API filter[name] query parameter as an RFC 3339 timestamp. The zero time is returned when
+// absent.
+func ParseTimeFilter(r *http.Request, name string) (time.Time, error) {
+	raw := ParseStringFilter(r, name)
+	if raw == nil {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339, *raw)
+}
+
+// ParseIfMatch reads the If-Match header as a buddy list version. Surrounding quotes, a weak validator prefix and the
+// query suffix of a QueryETag are ignored. Nil is returned when absent.
+func ParseIfMatch(r *http.Request) (*uint32, error) {
+	raw := strings.TrimSpace(r.Header.Get("If-Match"))
+	if raw == "" {
+		return nil, nil
+	}
+	raw = strings.Trim(strings.TrimPrefix(raw, "W/"), "\"")
+	raw, _, _ = strings.Cut(raw, "-")
+	v, err := strconv.ParseUint(raw, 10, 32)
+	if err != nil {
+		return nil, err
+	}
+	r32 := uint32(v)
+	return &r32, nil
+}
+
+// ParsePreferWait reads the wait preference of the Prefer header (RFC 7240), e.g. "Prefer: wait=10", as the time the
+// client is willing to wait for the outcome of a command, capped at MaxWait. Zero is returned when absent. As
+// preferences are advisory, a malformed wait is ignored rather than rejected.
+func ParsePreferWait(r *http.Request) time.Duration {
+	for _, h := range r.Header.Values("Prefer") {
+		for _, p := range strings.Split(h, ",") {
+			p, _, _ = strings.Cut(p, ";")
+			name, value, ok := strings.Cut(strings.TrimSpace(p), "=")
+			if !ok || !strings.EqualFold(strings.TrimSpace(name), "wait") {
+				continue
+			}
+			seconds, err := strconv.ParseUint(strings.Trim(strings.TrimSpace(value), "\""), 10, 32)
+			if err != nil {
+				return 0
+			}
+			return min(time.Duration(seconds)*time.Second, MaxWait)
+		}
+	}
+	return 0
+}
+
+// PreferWait reads the wait preference as ParsePreferWait does. A wait which is honoured is acknowledged with a
+// Preference-Applied response header carrying the wait applied, e.g. "Preference-Applied: wait=10".
+func PreferWait(w http.ResponseWriter, r *http.Request) time.Duration {
+	wait := ParsePreferWait(r)
+	if wait > 0 {
+		w.Header().Set("Preference-Applied", "wait="+strconv.Itoa(int(wait/time.Second)))
+	}
+	return wait
+}
+
+// ETag formats a buddy list version as a strong entity tag. The tag is accepted back by ParseIfMatch.
+func ETag(version uint32) string {
+	return "\"" + strconv.FormatUint(uint64(version), 10) + "\""
+}
+
+// QueryETag formats a buddy list version as a strong entity tag of a response which also depends on the query, given
+// in a normal form. The tag is accepted back by ParseIfMatch, which reads the version alone.
+func QueryETag(version uint32, query string) string {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(query))
+	return "\"" + strconv.FormatUint(uint64(version), 10) + "-" + strconv.FormatUint(uint64(h.Sum32()), 16) + "\""
+}
+
+// NotModified sets the ETag response header, and reports whether the If-None-Match request header matches it. When it
+// does, 304 Not Modified has been written and the handler must not write a body.
+func NotModified(w http.ResponseWriter, r *http.Request, etag string) bool {
+	w.Header().Set("ETag", etag)
+	raw := r.Header.Get("If-None-Match")
+	if raw == "" {
+		return false
+	}
+	for _, t := range strings.Split(raw, ",") {
+		t = strings.TrimPrefix(strings.TrimSpace(t), "W/")
+		if t == "*" || t == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return true
+		}
+	}
+	return false
+}
+
+// ParseSort reads the JSON:API sort query parameter. A leading '-' denotes descending order.
+func ParseSort(r *http.Request) []SortField {
+	raw := r.URL.Query().Get("sort")
+	if raw == "" {
+		return nil
+	}
+	results := make([]SortField, 0)
+	for _, f := range strings.Split(raw, ",") {
+		f = strings.TrimSpace(f)
+		if f == "" {
+			continue
+		}
+		if strings.HasPrefix(f, "-") {
+			results = append(results, SortField{Field: f[1:], Descending: true})
+		} else {
+			results = append(results, SortField{Field: f})
+		}
+	}
+	return results
+}
+
+// ParsePage reads the JSON:API page[number] and page[size] query parameters. A zero size means unpaged.
+func ParsePage(r *http.Request) (Page, error) {
+	p := Page{Number: DefaultPageNumber}
+	q := r.URL.Query()
+	if raw := q.Get("page[number]"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 {
+			return Page{}, errors.New("invalid page number")
+		}
+		p.Number = n
+	}
+	if raw := q.Get("page[size]"); raw != "" {
+		s, err := strconv.Atoi(raw)
+		if err != nil || s < 1 || s > MaxPageSize {
+			return Page{}, errors.New("invalid page size")
+		}
+		p.Size = s
+	}
+	return p, nil
+}
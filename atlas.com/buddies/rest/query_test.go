@@ -0,0 +1,69 @@
+package rest
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestParsePreferWait(t *testing.T) {
+	tests := []struct {
+		prefer   []string
+		expected time.Duration
+	}{
+		{nil, 0},
+		{[]string{"wait=10"}, 10 * time.Second},
+		{[]string{"WAIT = \"5\""}, 5 * time.Second},
+		{[]string{"respond-async, wait=3"}, 3 * time.Second},
+		{[]string{"return=minimal", "wait=7;foo=bar"}, 7 * time.Second},
+		{[]string{"wait=3600"}, MaxWait},
+		{[]string{"wait=soon"}, 0},
+		{[]string{"wait=-1"}, 0},
+		{[]string{"respond-async"}, 0},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest("POST", "/", nil)
+		for _, p := range tt.prefer {
+			r.Header.Add("Prefer", p)
+		}
+		if wait := ParsePreferWait(r); wait != tt.expected {
+			t.Errorf("Expected Prefer %v to wait %s, got %s", tt.prefer, tt.expected, wait)
+		}
+	}
+}
+
+func TestPreferWaitApplied(t *testing.T) {
+	r := httptest.NewRequest("POST", "/", nil)
+	r.Header.Set("Prefer", "wait=3600")
+	w := httptest.NewRecorder()
+	if wait := PreferWait(w, r); wait != MaxWait {
+		t.Errorf("Expected to wait %s, got %s", MaxWait, wait)
+	}
+	if applied := w.Header().Get("Preference-Applied"); applied != "wait=30" {
+		t.Errorf("Expected Preference-Applied [wait=30], got [%s]", applied)
+	}
+
+	r = httptest.NewRequest("POST", "/", nil)
+	w = httptest.NewRecorder()
+	PreferWait(w, r)
+	if applied := w.Header().Get("Preference-Applied"); applied != "" {
+		t.Errorf("Expected no Preference-Applied without a wait preference, got [%s]", applied)
+	}
+}
+
+func TestQueryETag(t *testing.T) {
+	a := QueryETag(7, `{"Limit":20}`)
+	if a != QueryETag(7, `{"Limit":20}`) {
+		t.Errorf("Expected equal queries to share a tag, got %s and %s", a, QueryETag(7, `{"Limit":20}`))
+	}
+	if a == QueryETag(7, `{"Limit":10}`) || a == QueryETag(8, `{"Limit":20}`) {
+		t.Errorf("Expected the tag to change with the query and the version, got %s", a)
+	}
+
+	r := httptest.NewRequest("PATCH", "/", nil)
+	r.Header.Set("If-Match", a)
+	v, err := ParseIfMatch(r)
+	if err != nil || v == nil || *v != 7 {
+		t.Errorf("Expected If-Match %s to be read as version 7, got %v, %v", a, v, err)
+	}
+}
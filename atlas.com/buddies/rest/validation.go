@@ -0,0 +1,51 @@
+package rest
+
+import (
+	"net/http"
+)
+
+// Rule validates an attribute of a request resource. Check returns an error describing why the attribute is invalid,
+// or nil when it is valid. Checks are given the request, for rules depending on the tenant or path parameters.
+type Rule[M any] struct {
+	// Field is the name of the validated attribute.
+	Field string
+	// Code is the error code of a failure. It defaults to ErrorCodeInvalidAttribute.
+	Code string
+	// Pointer is the JSON pointer of the validated member, for members other than attributes such as "/data/id". It
+	// defaults to the attribute named by Field.
+	Pointer string
+	Check   func(d *HandlerDependency, r *http.Request, m M) error
+}
+
+// Validate runs the rules against the request resource before next. When any rule fails, 422 Unprocessable Entity is
+// returned with an error per failed rule, pointing at its member, and next is not run.
+func Validate[M any](rules ...Rule[M]) func(next InputHandler[M]) InputHandler[M] {
+	return func(next InputHandler[M]) InputHandler[M] {
+		return func(d *HandlerDependency, c *HandlerContext, m M) http.HandlerFunc {
+			return func(w http.ResponseWriter, r *http.Request) {
+				errs := make([]Error, 0)
+				for _, rule := range rules {
+					err := rule.Check(d, r, m)
+					if err == nil {
+						continue
+					}
+					code := rule.Code
+					if code == "" {
+						code = ErrorCodeInvalidAttribute
+					}
+					pointer := rule.Pointer
+					if pointer == "" {
+						pointer = "/data/attributes/" + rule.Field
+					}
+					errs = append(errs, Error{Code: code, Detail: err.Error(), Source: &ErrorSource{Pointer: pointer}})
+				}
+				if len(errs) > 0 {
+					d.Logger().Debugf("Request failed [%d] validation rules.", len(errs))
+					WriteErrors(w, http.StatusUnprocessableEntity, errs...)
+					return
+				}
+				next(d, c, m)(w, r)
+			}
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+type testResource struct {
+	Name  string
+	Count int
+}
+
+func TestValidate(t *testing.T) {
+	rules := []Rule[testResource]{
+		{Field: "name", Pointer: "/data/id", Check: func(_ *HandlerDependency, _ *http.Request, m testResource) error {
+			if m.Name == "" {
+				return errors.New("name is required")
+			}
+			return nil
+		}},
+		{Field: "count", Code: "INVALID_COUNT", Check: func(_ *HandlerDependency, _ *http.Request, m testResource) error {
+			if m.Count < 0 {
+				return errors.New("count must not be negative")
+			}
+			return nil
+		}},
+	}
+	d := NewHandlerDependency(logrus.New(), context.Background())
+
+	tests := []struct {
+		name     string
+		resource testResource
+		expected []Error
+	}{
+		{name: "valid", resource: testResource{Name: "a", Count: 1}},
+		{name: "custom pointer", resource: testResource{Count: 1}, expected: []Error{
+			{Status: "422", Code: ErrorCodeInvalidAttribute, Detail: "name is required", Source: &ErrorSource{Pointer: "/data/id"}},
+		}},
+		{name: "every failure", resource: testResource{Count: -1}, expected: []Error{
+			{Status: "422", Code: ErrorCodeInvalidAttribute, Detail: "name is required", Source: &ErrorSource{Pointer: "/data/id"}},
+			{Status: "422", Code: "INVALID_COUNT", Detail: "count must not be negative", Source: &ErrorSource{Pointer: "/data/attributes/count"}},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handled := false
+			next := func(_ *HandlerDependency, _ *HandlerContext, _ testResource) http.HandlerFunc {
+				return func(w http.ResponseWriter, _ *http.Request) {
+					handled = true
+					w.WriteHeader(http.StatusNoContent)
+				}
+			}
+			w := httptest.NewRecorder()
+			Validate(rules...)(next)(d, &HandlerContext{}, tt.resource)(w, httptest.NewRequest("POST", "/", nil))
+
+			if tt.expected == nil {
+				if !handled || w.Code != http.StatusNoContent {
+					t.Errorf("Expected a valid resource to be handled, got status %d", w.Code)
+				}
+				return
+			}
+			if handled {
+				t.Errorf("Expected an invalid resource not to be handled")
+			}
+			if w.Code != http.StatusUnprocessableEntity {
+				t.Errorf("Expected status %d, got %d", http.StatusUnprocessableEntity, w.Code)
+			}
+			if ct := w.Header().Get("Content-Type"); ct != "application/vnd.api+json" {
+				t.Errorf("Expected a JSON:API content type, got [%s]", ct)
+			}
+			var doc errorDocument
+			if err := json.Unmarshal(w.Body.Bytes(), &doc); err != nil {
+				t.Fatalf("Failed to decode error document: %v", err)
+			}
+			if len(doc.Errors) != len(tt.expected) {
+				t.Fatalf("Expected %d errors, got %+v", len(tt.expected), doc.Errors)
+			}
+			for i, e := range tt.expected {
+				a := doc.Errors[i]
+				if a.Status != e.Status || a.Code != e.Code || a.Detail != e.Detail || a.Source == nil || *a.Source != *e.Source {
+					t.Errorf("Expected error %+v, got %+v", e, a)
+				}
+			}
+		})
+	}
+}
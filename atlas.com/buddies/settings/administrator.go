@@ -0,0 +1,24 @@
+package settings
+
+import (
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// upsert stores the settings of a character, replacing any held before.
+func upsert(db *gorm.DB, tenantId uuid.UUID, m Model) (Entity, error) {
+	e := Entity{
+		TenantId:        tenantId,
+		CharacterId:     m.characterId,
+		WhisperPolicy:   m.whisperPolicy,
+		DeclineRequests: m.declineRequests,
+		Visibility:      m.visibility,
+		HiddenGroups:    joinGroups(m.hiddenGroups),
+	}
+	err := db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "tenant_id"}, {Name: "character_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"whisper_policy", "decline_requests", "visibility", "hidden_groups", "updated_at"}),
+	}).Create(&e).Error
+	return e, err
+}
@@ -0,0 +1,49 @@
+package settings
+
+import (
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"strings"
+	"time"
+)
+
+func Migration(db *gorm.DB) error {
+	return db.AutoMigrate(&Entity{})
+}
+
+// Entity records the privacy settings of a character. Characters without a row hold the default settings.
+type Entity struct {
+	TenantId        uuid.UUID `gorm:"primaryKey;type:uuid;not null"`
+	CharacterId     uint32    `gorm:"primaryKey;autoIncrement:false;not null"`
+	WhisperPolicy   string    `gorm:"not null;default:EVERYONE"`
+	DeclineRequests bool      `gorm:"not null;default:false"`
+	Visibility      string    `gorm:"not null;default:VISIBLE"`
+	// HiddenGroups is the comma separated groups hidden from under HIDDEN_FROM_GROUPS. Group names cannot hold commas.
+	HiddenGroups string `gorm:"not null;default:''"`
+	UpdatedAt    time.Time
+}
+
+func (e Entity) TableName() string {
+	return "character_settings"
+}
+
+func Make(e Entity) (Model, error) {
+	return Model{
+		characterId:     e.CharacterId,
+		whisperPolicy:   e.WhisperPolicy,
+		declineRequests: e.DeclineRequests,
+		visibility:      e.Visibility,
+		hiddenGroups:    splitGroups(e.HiddenGroups),
+	}, nil
+}
+
+func splitGroups(groups string) []string {
+	if groups == "" {
+		return make([]string, 0)
+	}
+	return strings.Split(groups, ",")
+}
+
+func joinGroups(groups []string) string {
+	return strings.Join(groups, ",")
+}
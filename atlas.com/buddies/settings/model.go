@@ -0,0 +1,125 @@
+package settings
+
+import (
+	"slices"
+	"strings"
+)
+
+const (
+	// WhisperPolicyEveryone lets any character whisper the character.
+	WhisperPolicyEveryone = "EVERYONE"
+	// WhisperPolicyBuddies lets only mutual, confirmed buddies whisper the character.
+	WhisperPolicyBuddies = "BUDDIES"
+
+	// VisibilityVisible shows the presence of the character to every buddy.
+	VisibilityVisible = "VISIBLE"
+	// VisibilityHidden shows the character as offline to every buddy.
+	VisibilityHidden = "HIDDEN"
+	// VisibilityHiddenFromGroups shows the character as offline to the buddies in the hidden groups of their list.
+	VisibilityHiddenFromGroups = "HIDDEN_FROM_GROUPS"
+)
+
+type Model struct {
+	characterId     uint32
+	whisperPolicy   string
+	declineRequests bool
+	visibility      string
+	hiddenGroups    []string
+}
+
+// Default is the settings of a character which has never changed them.
+func Default(characterId uint32) Model {
+	return Model{characterId: characterId, whisperPolicy: WhisperPolicyEveryone, visibility: VisibilityVisible, hiddenGroups: make([]string, 0)}
+}
+
+func (m Model) CharacterId() uint32 {
+	return m.characterId
+}
+
+func (m Model) WhisperPolicy() string {
+	return m.whisperPolicy
+}
+
+// DeclineRequests reports whether buddy requests made to the character are declined.
+func (m Model) DeclineRequests() bool {
+	return m.declineRequests
+}
+
+func (m Model) Visibility() string {
+	return m.visibility
+}
+
+// HiddenGroups are the groups of the character's buddy list hidden from under HIDDEN_FROM_GROUPS.
+func (m Model) HiddenGroups() []string {
+	return m.hiddenGroups
+}
+
+// HiddenFrom reports whether the character appears offline to a buddy, given the group the buddy is in on the
+// character's buddy list.
+func (m Model) HiddenFrom(group string) bool {
+	switch m.visibility {
+	case VisibilityHidden:
+		return true
+	case VisibilityHiddenFromGroups:
+		return slices.Contains(m.hiddenGroups, group)
+	default:
+		return false
+	}
+}
+
+// AllowsWhisper reports whether a character may whisper the owner of the settings, given whether the two are mutual
+// buddies.
+func (m Model) AllowsWhisper(mutual bool) bool {
+	return m.whisperPolicy != WhisperPolicyBuddies || mutual
+}
+
+// ValidWhisperPolicy reports whether the whisper policy is known.
+func ValidWhisperPolicy(policy string) bool {
+	return policy == WhisperPolicyEveryone || policy == WhisperPolicyBuddies
+}
+
+// ValidVisibility reports whether the visibility is known.
+func ValidVisibility(visibility string) bool {
+	return visibility == VisibilityVisible || visibility == VisibilityHidden || visibility == VisibilityHiddenFromGroups
+}
+
+// Change is a change to the settings of a character. Nil settings are left unchanged.
+type Change struct {
+	WhisperPolicy   *string
+	DeclineRequests *bool
+	Visibility      *string
+	HiddenGroups    []string
+}
+
+// Valid reports whether the changed settings are known, and the hidden groups can be stored.
+func (c Change) Valid() bool {
+	if c.WhisperPolicy != nil && !ValidWhisperPolicy(*c.WhisperPolicy) {
+		return false
+	}
+	if c.Visibility != nil && !ValidVisibility(*c.Visibility) {
+		return false
+	}
+	for _, g := range c.HiddenGroups {
+		if strings.Contains(g, ",") {
+			return false
+		}
+	}
+	return true
+}
+
+// Apply returns the settings with the change made.
+func (m Model) Apply(c Change) Model {
+	if c.WhisperPolicy != nil {
+		m.whisperPolicy = *c.WhisperPolicy
+	}
+	if c.DeclineRequests != nil {
+		m.declineRequests = *c.DeclineRequests
+	}
+	if c.Visibility != nil {
+		m.visibility = *c.Visibility
+	}
+	if c.HiddenGroups != nil {
+		m.hiddenGroups = slices.Clone(c.HiddenGroups)
+	}
+	return m
+}
@@ -0,0 +1,81 @@
+package settings
+
+import "testing"
+
+func TestAllowsWhisper(t *testing.T) {
+	tests := []struct {
+		name     string
+		policy   string
+		mutual   bool
+		expected bool
+	}{
+		{"Everyone from stranger", WhisperPolicyEveryone, false, true},
+		{"Buddies from stranger", WhisperPolicyBuddies, false, false},
+		{"Buddies from buddy", WhisperPolicyBuddies, true, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := Model{whisperPolicy: tt.policy}
+			if m.AllowsWhisper(tt.mutual) != tt.expected {
+				t.Errorf("Expected whisper allowed %t, but got %t", tt.expected, !tt.expected)
+			}
+		})
+	}
+}
+
+func TestHiddenFrom(t *testing.T) {
+	tests := []struct {
+		name       string
+		visibility string
+		group      string
+		expected   bool
+	}{
+		{"Visible", VisibilityVisible, "Friends", false},
+		{"Hidden", VisibilityHidden, "Friends", true},
+		{"Hidden group", VisibilityHiddenFromGroups, "Guild", true},
+		{"Shown group", VisibilityHiddenFromGroups, "Friends", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := Model{visibility: tt.visibility, hiddenGroups: []string{"Guild"}}
+			if m.HiddenFrom(tt.group) != tt.expected {
+				t.Errorf("Expected hidden from [%s] %t, but got %t", tt.group, tt.expected, !tt.expected)
+			}
+		})
+	}
+}
+
+func TestApply(t *testing.T) {
+	hidden := VisibilityHiddenFromGroups
+	m := Default(12345).Apply(Change{Visibility: &hidden, HiddenGroups: []string{"Guild"}})
+	if m.Visibility() != VisibilityHiddenFromGroups {
+		t.Errorf("Expected visibility %s, but got %s", VisibilityHiddenFromGroups, m.Visibility())
+	}
+	if m.WhisperPolicy() != WhisperPolicyEveryone {
+		t.Errorf("Expected unchanged whisper policy %s, but got %s", WhisperPolicyEveryone, m.WhisperPolicy())
+	}
+
+	m = m.Apply(Change{})
+	if len(m.HiddenGroups()) != 1 {
+		t.Errorf("Expected omitted hidden groups to be unchanged, but got %v", m.HiddenGroups())
+	}
+	m = m.Apply(Change{HiddenGroups: []string{}})
+	if len(m.HiddenGroups()) != 0 {
+		t.Errorf("Expected empty hidden groups to clear them, but got %v", m.HiddenGroups())
+	}
+}
+
+func TestChangeValid(t *testing.T) {
+	unknown := "INVISIBLE"
+	if (Change{Visibility: &unknown}).Valid() {
+		t.Errorf("Expected unknown visibility to be invalid")
+	}
+	if (Change{HiddenGroups: []string{"a,b"}}).Valid() {
+		t.Errorf("Expected a hidden group holding a comma to be invalid")
+	}
+	if !(Change{HiddenGroups: []string{"Guild"}}).Valid() {
+		t.Errorf("Expected hidden groups to be valid")
+	}
+}
@@ -0,0 +1,89 @@
+package settings
+
+import (
+	"atlas-buddies/database"
+	"atlas-buddies/kafka/message"
+	list2 "atlas-buddies/kafka/message/list"
+	list3 "atlas-buddies/kafka/producer/list"
+	"context"
+	"errors"
+	"github.com/Chronicle20/atlas-model/model"
+	"github.com/Chronicle20/atlas-tenant"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+type Processor interface {
+	WithTransaction(*gorm.DB) Processor
+	ByCharacterIdProvider(characterId uint32) model.Provider[Model]
+	// GetByCharacterId retrieves the settings of a character, which are the defaults until first changed.
+	GetByCharacterId(characterId uint32) (Model, error)
+	// Update changes the settings of a character. Nil settings are left unchanged. The outcome is reported by a
+	// SETTINGS_CHANGE or ERROR status event. Settings are not part of the buddy list, so SETTINGS_CHANGE carries the
+	// unchanged version of the character's list.
+	Update(mb *message.Buffer) func(characterId uint32, worldId byte, version uint32, c Change) error
+}
+
+type ProcessorImpl struct {
+	l   logrus.FieldLogger
+	ctx context.Context
+	db  *gorm.DB
+	t   tenant.Model
+}
+
+func NewProcessor(l logrus.FieldLogger, ctx context.Context, db *gorm.DB) Processor {
+	return &ProcessorImpl{
+		l:   l,
+		ctx: ctx,
+		db:  db,
+		t:   tenant.MustFromContext(ctx),
+	}
+}
+
+func (p *ProcessorImpl) WithTransaction(tx *gorm.DB) Processor {
+	return &ProcessorImpl{
+		l:   p.l,
+		ctx: p.ctx,
+		db:  tx,
+		t:   p.t,
+	}
+}
+
+func (p *ProcessorImpl) ByCharacterIdProvider(characterId uint32) model.Provider[Model] {
+	return func() (Model, error) {
+		m, err := model.Map(Make)(byCharacterIdEntityProvider(p.t.Id(), characterId)(p.db))()
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return Default(characterId), nil
+		}
+		return m, err
+	}
+}
+
+func (p *ProcessorImpl) GetByCharacterId(characterId uint32) (Model, error) {
+	return p.ByCharacterIdProvider(characterId)()
+}
+
+func (p *ProcessorImpl) Update(mb *message.Buffer) func(characterId uint32, worldId byte, version uint32, c Change) error {
+	return func(characterId uint32, worldId byte, version uint32, c Change) error {
+		if !c.Valid() {
+			p.l.Infof("Character [%d] attempted to change to invalid settings.", characterId)
+			return mb.Put(list2.EnvStatusEventTopic, list3.ErrorStatusEventProvider(characterId, worldId, list2.StatusEventErrorInvalidSettings))
+		}
+
+		var m Model
+		txErr := database.ExecuteTransaction(p.db, func(tx *gorm.DB) error {
+			om, err := p.WithTransaction(tx).GetByCharacterId(characterId)
+			if err != nil {
+				return err
+			}
+			m = om.Apply(c)
+			_, err = upsert(tx, p.t.Id(), m)
+			return err
+		})
+		if txErr != nil {
+			p.l.WithError(txErr).Errorf("Unable to update settings of character [%d].", characterId)
+			return mb.Put(list2.EnvStatusEventTopic, list3.ErrorStatusEventProvider(characterId, worldId, list2.StatusEventErrorUnknownError))
+		}
+		return mb.Put(list2.EnvStatusEventTopic, list3.SettingsChangeStatusEventProvider(characterId, worldId, version, m.WhisperPolicy(), m.DeclineRequests(), m.Visibility(), m.HiddenGroups()))
+	}
+}
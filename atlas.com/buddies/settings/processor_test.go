@@ -0,0 +1,74 @@
+package settings
+
+import (
+	"atlas-buddies/kafka/message"
+	"context"
+	"testing"
+
+	"github.com/Chronicle20/atlas-tenant"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to open test database: %v", err)
+	}
+	err = db.Exec(`
+		CREATE TABLE character_settings (
+			tenant_id TEXT NOT NULL,
+			character_id INTEGER NOT NULL,
+			whisper_policy TEXT NOT NULL DEFAULT 'EVERYONE',
+			decline_requests BOOLEAN NOT NULL DEFAULT false,
+			visibility TEXT NOT NULL DEFAULT 'VISIBLE',
+			hidden_groups TEXT NOT NULL DEFAULT '',
+			updated_at DATETIME,
+			PRIMARY KEY (tenant_id, character_id)
+		)
+	`).Error
+	if err != nil {
+		t.Fatalf("Failed to create character_settings table: %v", err)
+	}
+	return db
+}
+
+func TestUpdateMergesPartialChanges(t *testing.T) {
+	db := setupTestDB(t)
+	tm, _ := tenant.Create(uuid.New(), "GMS", 83, 1)
+	p := NewProcessor(logrus.New(), tenant.WithContext(context.Background(), tm), db)
+
+	policy := WhisperPolicyBuddies
+	decline := true
+	visibility := VisibilityHiddenFromGroups
+	changes := []Change{
+		{WhisperPolicy: &policy},
+		{DeclineRequests: &decline},
+		{Visibility: &visibility, HiddenGroups: []string{"Guild"}},
+		{WhisperPolicy: nil, HiddenGroups: nil},
+	}
+	for _, c := range changes {
+		if err := p.Update(message.NewBuffer())(1, 0, 0, c); err != nil {
+			t.Fatalf("Expected no error, but got: %v", err)
+		}
+	}
+
+	m, err := p.GetByCharacterId(1)
+	if err != nil {
+		t.Fatalf("Failed to retrieve settings: %v", err)
+	}
+	if m.WhisperPolicy() != WhisperPolicyBuddies || !m.DeclineRequests() || m.Visibility() != VisibilityHiddenFromGroups {
+		t.Errorf("Expected every partial change to be kept, got %+v", m)
+	}
+	if len(m.HiddenGroups()) != 1 || m.HiddenGroups()[0] != "Guild" {
+		t.Errorf("Expected hidden groups [Guild], got %v", m.HiddenGroups())
+	}
+
+	var count int64
+	db.Model(&Entity{}).Count(&count)
+	if count != 1 {
+		t.Errorf("Expected a single settings row, got %d", count)
+	}
+}
@@ -0,0 +1,19 @@
+package settings
+
+import (
+	"atlas-buddies/database"
+	"github.com/Chronicle20/atlas-model/model"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+func byCharacterIdEntityProvider(tenantId uuid.UUID, characterId uint32) database.EntityProvider[Entity] {
+	return func(db *gorm.DB) model.Provider[Entity] {
+		var result Entity
+		err := db.Where(&Entity{TenantId: tenantId, CharacterId: characterId}).First(&result).Error
+		if err != nil {
+			return model.ErrorProvider[Entity](err)
+		}
+		return model.FixedProvider[Entity](result)
+	}
+}
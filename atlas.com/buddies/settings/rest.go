@@ -0,0 +1,43 @@
+package settings
+
+import "strconv"
+
+type RestModel struct {
+	CharacterId     uint32 `json:"-"`
+	WhisperPolicy   string `json:"whisperPolicy"`
+	DeclineRequests *bool  `json:"declineRequests"`
+	Visibility      string `json:"visibility"`
+	// HiddenGroups is nil when omitted from a request, leaving the hidden groups unchanged.
+	HiddenGroups []string `json:"hiddenGroups"`
+}
+
+func (r RestModel) GetName() string {
+	return "buddy-settings"
+}
+
+func (r RestModel) GetID() string {
+	return strconv.Itoa(int(r.CharacterId))
+}
+
+func (r *RestModel) SetID(strId string) error {
+	if strId == "" {
+		return nil
+	}
+	id, err := strconv.Atoi(strId)
+	if err != nil {
+		return err
+	}
+	r.CharacterId = uint32(id)
+	return nil
+}
+
+func Transform(m Model) (RestModel, error) {
+	declineRequests := m.declineRequests
+	return RestModel{
+		CharacterId:     m.characterId,
+		WhisperPolicy:   m.whisperPolicy,
+		DeclineRequests: &declineRequests,
+		Visibility:      m.visibility,
+		HiddenGroups:    m.hiddenGroups,
+	}, nil
+}
@@ -0,0 +1,139 @@
+package spec
+
+import (
+	"atlas-buddies/kafka/message/cashshop"
+	"atlas-buddies/kafka/message/channel"
+	"atlas-buddies/kafka/message/character"
+	"atlas-buddies/kafka/message/invite"
+	"atlas-buddies/kafka/message/list"
+)
+
+type message struct {
+	typ string
+	// payload is the zero message, whose type property is restricted to typ.
+	payload any
+}
+
+type topic struct {
+	// env names the environment variable holding the topic name.
+	env         string
+	description string
+	consumed    bool
+	produced    bool
+	messages    []message
+}
+
+// topics documents the Kafka topics the service consumes and produces, with the messages it handles or emits on each.
+var topics = []topic{
+	{
+		env:         list.EnvCommandTopic,
+		description: "Buddy list commands.",
+		consumed:    true,
+		produced:    true,
+		messages: []message{
+			{typ: list.CommandTypeCreate, payload: list.Command[list.CreateCommandBody]{}},
+			{typ: list.CommandTypeRequestAdd, payload: list.Command[list.RequestAddBuddyCommandBody]{}},
+			{typ: list.CommandTypeRequestDelete, payload: list.Command[list.RequestDeleteBuddyCommandBody]{}},
+			{typ: list.CommandTypeIncreaseCapacity, payload: list.Command[list.IncreaseCapacityCommandBody]{}},
+			{typ: list.CommandTypeSetCapacity, payload: list.Command[list.SetCapacityCommandBody]{}},
+			{typ: list.CommandTypeDelete, payload: list.Command[list.DeleteCommandBody]{}},
+			{typ: list.CommandTypeUpdateSettings, payload: list.Command[list.UpdateSettingsCommandBody]{}},
+		},
+	},
+	{
+		env:         list.EnvStatusEventTopic,
+		description: "Buddy list status events. Consumed to feed the REST event stream.",
+		consumed:    true,
+		produced:    true,
+		messages: []message{
+			{typ: list.StatusEventTypeBuddyAdded, payload: list.StatusEvent[list.BuddyAddedStatusEventBody]{}},
+			{typ: list.StatusEventTypeBuddyRemoved, payload: list.StatusEvent[list.BuddyRemovedStatusEventBody]{}},
+			{typ: list.StatusEventTypeBuddyUpdated, payload: list.StatusEvent[list.BuddyUpdatedStatusEventBody]{}},
+			{typ: list.StatusEventTypeBuddyChannelChange, payload: list.StatusEvent[list.BuddyChannelChangeStatusEventBody]{}},
+			{typ: list.StatusEventTypeBuddyCapacityUpdate, payload: list.StatusEvent[list.BuddyCapacityChangeStatusEventBody]{}},
+			{typ: list.StatusEventTypeListSnapshot, payload: list.StatusEvent[list.ListSnapshotStatusEventBody]{}},
+			{typ: list.StatusEventTypeSettingsChange, payload: list.StatusEvent[list.SettingsChangeStatusEventBody]{}},
+			{typ: list.StatusEventTypeListDeleted, payload: list.StatusEvent[list.ListDeletedStatusEventBody]{}},
+			{typ: list.StatusEventTypeError, payload: list.StatusEvent[list.ErrorStatusEventBody]{}},
+		},
+	},
+	{
+		env:         invite.EnvCommandTopic,
+		description: "Invite commands, for buddy invites.",
+		produced:    true,
+		messages: []message{
+			{typ: invite.CommandInviteTypeCreate, payload: invite.Command[invite.CreateCommandBody]{}},
+			{typ: invite.CommandInviteTypeReject, payload: invite.Command[invite.RejectCommandBody]{}},
+		},
+	},
+	{
+		env:         invite.EnvEventStatusTopic,
+		description: "Invite status events. Only BUDDY invites are handled.",
+		consumed:    true,
+		messages: []message{
+			{typ: invite.EventInviteStatusTypeAccepted, payload: invite.StatusEvent[invite.AcceptedEventBody]{}},
+			{typ: invite.EventInviteStatusTypeRejected, payload: invite.StatusEvent[invite.RejectedEventBody]{}},
+		},
+	},
+	{
+		env:         character.EnvEventTopicStatus,
+		description: "Character status events.",
+		consumed:    true,
+		messages: []message{
+			{typ: character.StatusEventTypeCreated, payload: character.StatusEvent[character.CreatedStatusEventBody]{}},
+			{typ: character.StatusEventTypeDeleted, payload: character.StatusEvent[character.DeletedStatusEventBody]{}},
+			{typ: character.StatusEventTypeLogin, payload: character.StatusEvent[character.LoginStatusEventBody]{}},
+			{typ: character.StatusEventTypeLogout, payload: character.StatusEvent[character.LogoutStatusEventBody]{}},
+			{typ: character.StatusEventTypeChannelChanged, payload: character.StatusEvent[character.ChannelChangedStatusEventBody]{}},
+			{typ: character.StatusEventTypeMapChanged, payload: character.StatusEvent[character.MapChangedStatusEventBody]{}},
+		},
+	},
+	{
+		env:         channel.EnvEventTopicStatus,
+		description: "Channel status events.",
+		consumed:    true,
+		messages: []message{
+			{typ: channel.EventStatusTypeShutdown, payload: channel.StatusEvent{}},
+		},
+	},
+	{
+		env:         cashshop.EnvEventTopicStatus,
+		description: "Cash shop status events.",
+		consumed:    true,
+		messages: []message{
+			{typ: cashshop.EventStatusTypeCharacterEnter, payload: cashshop.StatusEvent[cashshop.MovementBody]{}},
+			{typ: cashshop.EventStatusTypeCharacterExit, payload: cashshop.StatusEvent[cashshop.MovementBody]{}},
+			{typ: cashshop.EventStatusTypeItemUsed, payload: cashshop.StatusEvent[cashshop.ItemUsedBody]{}},
+		},
+	},
+}
+
+// AsyncAPI builds the AsyncAPI document of the Kafka topics. Channels are named by the environment variable holding
+// the topic name. Following AsyncAPI 2, publish describes messages the service consumes, and subscribe messages it
+// produces.
+func AsyncAPI() ([]byte, error) {
+	channels := make(map[string]any)
+	for _, t := range topics {
+		messages := make([]any, 0)
+		for _, m := range t.messages {
+			messages = append(messages, map[string]any{"name": m.typ, "contentType": "application/json", "payload": withType(m.payload, m.typ)})
+		}
+		op := map[string]any{"message": map[string]any{"oneOf": messages}}
+
+		c := map[string]any{"description": t.description + " The topic name is read from the " + t.env + " environment variable."}
+		if t.consumed {
+			c["publish"] = op
+		}
+		if t.produced {
+			c["subscribe"] = op
+		}
+		channels[t.env] = c
+	}
+
+	doc := map[string]any{
+		"asyncapi": "2.6.0",
+		"info":     map[string]any{"title": "atlas-buddies", "version": "1.0.0"},
+		"channels": channels,
+	}
+	return marshal(doc)
+}
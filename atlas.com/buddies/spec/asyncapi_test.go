@@ -0,0 +1,78 @@
+package spec
+
+import (
+	"atlas-buddies/kafka/message/list"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// TestTopicsMatchMessageTypes guards against a buddy list command or status event type being added without being
+// documented, as the service owns both topics and handles or emits every type declared for them.
+func TestTopicsMatchMessageTypes(t *testing.T) {
+	path := filepath.Join("..", "kafka", "message", "list", "kafka.go")
+	tests := []struct {
+		env    string
+		prefix string
+	}{
+		{list.EnvCommandTopic, "CommandType"},
+		{list.EnvStatusEventTopic, "StatusEventType"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.env, func(t *testing.T) {
+			declared := constantValues(t, path, tt.prefix)
+			documented := make([]string, 0)
+			for _, tp := range topics {
+				if tp.env != tt.env {
+					continue
+				}
+				for _, m := range tp.messages {
+					documented = append(documented, m.typ)
+				}
+			}
+			sort.Strings(documented)
+			if strings.Join(declared, ",") != strings.Join(documented, ",") {
+				t.Errorf("Topic [%s] documents %v, but %v are declared. Update the topics of asyncapi.go.", tt.env, documented, declared)
+			}
+		})
+	}
+}
+
+// constantValues returns the sorted values of the string constants of the file whose names start with prefix.
+func constantValues(t *testing.T, path string, prefix string) []string {
+	f, err := parser.ParseFile(token.NewFileSet(), path, nil, 0)
+	if err != nil {
+		t.Fatalf("Unable to parse [%s]: %v", path, err)
+	}
+	values := make([]string, 0)
+	for _, d := range f.Decls {
+		gd, ok := d.(*ast.GenDecl)
+		if !ok || gd.Tok != token.CONST {
+			continue
+		}
+		for _, s := range gd.Specs {
+			vs := s.(*ast.ValueSpec)
+			for i, n := range vs.Names {
+				if !strings.HasPrefix(n.Name, prefix) || i >= len(vs.Values) {
+					continue
+				}
+				lit, ok := vs.Values[i].(*ast.BasicLit)
+				if !ok || lit.Kind != token.STRING {
+					continue
+				}
+				v, err := strconv.Unquote(lit.Value)
+				if err != nil {
+					t.Fatalf("Unable to read constant [%s]: %v", n.Name, err)
+				}
+				values = append(values, v)
+			}
+		}
+	}
+	sort.Strings(values)
+	return values
+}
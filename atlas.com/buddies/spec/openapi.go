@@ -0,0 +1,463 @@
+package spec
+
+import (
+	"atlas-buddies/audit"
+	"atlas-buddies/buddy"
+	"atlas-buddies/change"
+	"atlas-buddies/ledger"
+	"atlas-buddies/list"
+	"atlas-buddies/rest"
+	"atlas-buddies/settings"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+)
+
+// Prefix is the path prefix the REST routes are served under.
+const Prefix = "/api"
+
+const contentType = "application/vnd.api+json"
+
+type parameter struct {
+	in          string
+	name        string
+	description string
+	required    bool
+	schema      map[string]any
+}
+
+type response struct {
+	status      int
+	description string
+	// body is a zero REST model, or a slice of them, returned as a JSON:API document. Nil for no body.
+	body any
+	// stream marks a server-sent event stream.
+	stream bool
+}
+
+type operation struct {
+	summary    string
+	parameters []parameter
+	// request is the zero REST model accepted as a JSON:API document. Nil for no body.
+	request   any
+	responses []response
+}
+
+var correlationId = parameter{in: "header", name: list.CorrelationIdHeader, description: "Correlation id of the resulting command. Generated when absent."}
+var preferWait = parameter{in: "header", name: "Prefer", description: "wait=N waits up to N seconds for the outcome of the command, acknowledged by a Preference-Applied header."}
+var ifNoneMatch = parameter{in: "header", name: "If-None-Match", description: "Buddy list version tags which answer 304 Not Modified."}
+
+func query(name string, description string, schema map[string]any) parameter {
+	return parameter{in: "query", name: name, description: description, schema: schema}
+}
+
+var str = map[string]any{"type": "string"}
+var boolean = map[string]any{"type": "boolean"}
+var integer = map[string]any{"type": "integer", "minimum": 0}
+
+// operations documents the routes registered by list.InitResource, keyed by route name.
+var operations = map[string]operation{
+	list.GetSettings: {
+		summary: "Get a character's privacy and visibility settings.",
+		responses: []response{
+			{status: http.StatusOK, description: "The settings, which are the defaults until first changed.", body: settings.RestModel{}},
+		},
+	},
+	list.UpdateSettings: {
+		summary:    "Change a character's privacy and visibility settings. Omitted settings are left unchanged.",
+		parameters: []parameter{correlationId, preferWait},
+		request:    settings.RestModel{},
+		responses: []response{
+			{status: http.StatusOK, description: "The settings were changed.", body: settings.RestModel{}},
+			{status: http.StatusAccepted, description: "An UPDATE_SETTINGS command was produced."},
+			{status: http.StatusNotFound, description: "The character does not exist."},
+			{status: http.StatusUnprocessableEntity, description: "The settings are invalid."},
+		},
+	},
+	list.GetRelationship: {
+		summary: "Get how a character relates to another.",
+		responses: []response{
+			{status: http.StatusOK, description: "The relationship.", body: list.RelationshipRestModel{}},
+		},
+	},
+	list.GetRelationships: {
+		summary: "Get how a character relates to many others.",
+		parameters: []parameter{
+			{in: "query", name: "filter[characterId]", description: "Comma separated character ids, at most the tenant's maxBatchSize.", required: true},
+		},
+		responses: []response{
+			{status: http.StatusOK, description: "The relationships, in the order requested.", body: []list.RelationshipRestModel{}},
+			{status: http.StatusBadRequest, description: "The filter is missing, malformed or asks for too many characters."},
+		},
+	},
+	list.GetBuddyLists: {
+		summary: "Get the buddy lists of many characters.",
+		parameters: []parameter{
+			{in: "query", name: "filter[characterId]", description: "Comma separated character ids, at most the tenant's maxBatchSize.", required: true},
+		},
+		responses: []response{
+			{status: http.StatusOK, description: "The buddy lists which exist, in the order requested.", body: []list.RestModel{}},
+			{status: http.StatusBadRequest, description: "The filter is missing, malformed or asks for too many characters."},
+		},
+	},
+	list.QueryBuddyLists: {
+		summary: "Get the buddy lists of a set of characters too large for a query string.",
+		request: list.QueryRestModel{},
+		responses: []response{
+			{status: http.StatusOK, description: "The buddy lists which exist, in the order requested.", body: []list.RestModel{}},
+			{status: http.StatusUnprocessableEntity, description: "No characters, or more than the tenant's maxBatchSize, were asked for."},
+		},
+	},
+	list.GetBuddyList: {
+		summary:    "Get a character's buddy list.",
+		parameters: []parameter{ifNoneMatch},
+		responses: []response{
+			{status: http.StatusOK, description: "The buddy list.", body: list.RestModel{}},
+			{status: http.StatusNotModified, description: "The buddy list is unchanged."},
+			{status: http.StatusNotFound, description: "The character has no buddy list."},
+		},
+	},
+	list.CreateBuddyList: {
+		summary:    "Create a character's buddy list.",
+		parameters: []parameter{preferWait},
+		request:    list.RestModel{},
+		responses: []response{
+			{status: http.StatusCreated, description: "The buddy list was created.", body: list.RestModel{}},
+			{status: http.StatusAccepted, description: "A CREATE command was produced."},
+			{status: http.StatusNotFound, description: "The character does not exist."},
+			{status: http.StatusConflict, description: "The buddy list already exists."},
+			{status: http.StatusUnprocessableEntity, description: "The buddy list is invalid."},
+		},
+	},
+	list.UpdateBuddyList: {
+		summary: "Set the capacity of a character's buddy list.",
+		parameters: []parameter{
+			query("overflowPolicy", "REJECT or TRIM_PENDING.", map[string]any{"type": "string", "enum": []string{"REJECT", "TRIM_PENDING"}}),
+			{in: "header", name: "If-Match", description: "Buddy list version the update requires."},
+			correlationId, preferWait,
+		},
+		request: list.RestModel{},
+		responses: []response{
+			{status: http.StatusOK, description: "The capacity was set.", body: list.RestModel{}},
+			{status: http.StatusAccepted, description: "A SET_CAPACITY command was produced."},
+			{status: http.StatusBadRequest, description: "The request is malformed."},
+			{status: http.StatusNotFound, description: "The character has no buddy list."},
+			{status: http.StatusConflict, description: "The command failed."},
+			{status: http.StatusPreconditionFailed, description: "The buddy list is not at the If-Match version."},
+			{status: http.StatusUnprocessableEntity, description: "The buddy list is invalid."},
+		},
+	},
+	list.DeleteBuddyList: {
+		summary: "Delete a character's buddy list.",
+		parameters: []parameter{
+			{in: "header", name: "If-Match", description: "Buddy list version the deletion requires."},
+			correlationId, preferWait,
+		},
+		responses: []response{
+			{status: http.StatusAccepted, description: "A DELETE command was produced."},
+			{status: http.StatusNoContent, description: "The buddy list was deleted."},
+			{status: http.StatusBadRequest, description: "The request is malformed."},
+			{status: http.StatusNotFound, description: "The character has no buddy list."},
+			{status: http.StatusConflict, description: "The command failed."},
+			{status: http.StatusPreconditionFailed, description: "The buddy list is not at the If-Match version."},
+		},
+	},
+	list.GetBuddiesInBuddyList: {
+		summary: "Get the buddies of a character's buddy list.",
+		parameters: []parameter{
+			query("filter[online]", "Buddies with or without a channel.", boolean),
+			query("filter[group]", "Group name.", str),
+			query("filter[pending]", "Pending buddies.", boolean),
+			query("filter[inShop]", "Buddies in the cash shop.", boolean),
+			query("filter[notSeenDays]", "Offline buddies not seen online within the number of days, or never seen online.", integer),
+			query("sort", "Comma separated sort fields. Prefix with - for descending.", str),
+			query("page[number]", "1 based page number.", integer),
+			query("page[size]", "Page size, at most 100.", integer),
+			ifNoneMatch,
+		},
+		responses: []response{
+			{status: http.StatusOK, description: "The buddies.", body: []buddy.RestModel{}},
+			{status: http.StatusNotModified, description: "The buddy list is unchanged."},
+			{status: http.StatusBadRequest, description: "The query is malformed."},
+			{status: http.StatusNotFound, description: "The character has no buddy list."},
+		},
+	},
+	list.AddBuddyToBuddyList: {
+		summary: "Add a buddy to a character's buddy list.",
+		parameters: []parameter{
+			{in: "header", name: "If-Match", description: "Buddy list version the addition requires."},
+			correlationId, preferWait,
+		},
+		request: buddy.RestModel{},
+		responses: []response{
+			{status: http.StatusCreated, description: "The buddy was added.", body: buddy.RestModel{}},
+			{status: http.StatusAccepted, description: "A REQUEST_ADD command was produced."},
+			{status: http.StatusBadRequest, description: "The request is malformed."},
+			{status: http.StatusNotFound, description: "The character has no buddy list."},
+			{status: http.StatusConflict, description: "The command failed."},
+			{status: http.StatusPreconditionFailed, description: "The buddy list is not at the If-Match version."},
+			{status: http.StatusUnprocessableEntity, description: "The buddy is invalid."},
+		},
+	},
+	list.GetBuddyInBuddyList: {
+		summary:    "Get a buddy of a character's buddy list.",
+		parameters: []parameter{ifNoneMatch},
+		responses: []response{
+			{status: http.StatusOK, description: "The buddy.", body: buddy.RestModel{}},
+			{status: http.StatusNotModified, description: "The buddy list is unchanged."},
+			{status: http.StatusNotFound, description: "The character has no buddy list, or the buddy is not on it."},
+		},
+	},
+	list.RemoveBuddyFromList: {
+		summary: "Remove a buddy from a character's buddy list.",
+		parameters: []parameter{
+			{in: "header", name: "If-Match", description: "Buddy list version the removal requires."},
+			correlationId, preferWait,
+		},
+		responses: []response{
+			{status: http.StatusAccepted, description: "A REQUEST_DELETE command was produced."},
+			{status: http.StatusNoContent, description: "The buddy was removed."},
+			{status: http.StatusBadRequest, description: "The request is malformed."},
+			{status: http.StatusNotFound, description: "The character has no buddy list, or the buddy is not on it."},
+			{status: http.StatusConflict, description: "The command failed."},
+			{status: http.StatusPreconditionFailed, description: "The buddy list is not at the If-Match version."},
+		},
+	},
+	list.GetBuddyLocation: {
+		summary: "Get the presence of a mutual buddy.",
+		responses: []response{
+			{status: http.StatusOK, description: "The presence of the buddy.", body: buddy.LocationRestModel{}},
+			{status: http.StatusNotFound, description: "The characters are not mutual buddies."},
+		},
+	},
+	list.GetCapacityHistory: {
+		summary: "Get the capacity changes of a character's buddy list.",
+		responses: []response{
+			{status: http.StatusOK, description: "The capacity changes.", body: []ledger.RestModel{}},
+			{status: http.StatusNotFound, description: "The character has no buddy list."},
+		},
+	},
+	list.GetAudits: {
+		summary: "Get the audit log of a character.",
+		parameters: []parameter{
+			query("filter[from]", "RFC 3339 start of the time range.", map[string]any{"type": "string", "format": "date-time"}),
+			query("filter[to]", "RFC 3339 end of the time range.", map[string]any{"type": "string", "format": "date-time"}),
+		},
+		responses: []response{
+			{status: http.StatusOK, description: "The audit entries.", body: []audit.RestModel{}},
+			{status: http.StatusBadRequest, description: "The query is malformed."},
+		},
+	},
+	list.GetChanges: {
+		summary: "Get the changes of a character's buddy list after a version.",
+		parameters: []parameter{
+			{in: "query", name: "since", description: "Version to return the changes after.", required: true, schema: integer},
+		},
+		responses: []response{
+			{status: http.StatusOK, description: "The changes.", body: []change.RestModel{}},
+			{status: http.StatusBadRequest, description: "The query is malformed."},
+			{status: http.StatusNotFound, description: "The character has no buddy list."},
+			{status: http.StatusGone, description: "The changes are no longer available."},
+		},
+	},
+	list.GetEvents: {
+		summary: "Stream the status events of a character's buddy list.",
+		parameters: []parameter{
+			{in: "header", name: "Last-Event-ID", description: "Version to catch up from."},
+		},
+		responses: []response{
+			{status: http.StatusOK, description: "Server-sent events.", stream: true},
+			{status: http.StatusBadRequest, description: "The Last-Event-ID header is malformed."},
+			{status: http.StatusNotFound, description: "The character has no buddy list."},
+		},
+	},
+}
+
+type serverInformation struct{}
+
+func (serverInformation) GetBaseURL() string {
+	return ""
+}
+
+func (serverInformation) GetPrefix() string {
+	return Prefix + "/"
+}
+
+type route struct {
+	name   string
+	path   string
+	method string
+}
+
+// routes returns the routes registered by list.InitResource.
+func routes() ([]route, error) {
+	l := logrus.New()
+	l.SetOutput(io.Discard)
+	router := mux.NewRouter()
+	list.InitResource(serverInformation{})(context.Background())(nil)(router, l)
+
+	results := make([]route, 0)
+	err := router.Walk(func(r *mux.Route, _ *mux.Router, _ []*mux.Route) error {
+		methods, err := r.GetMethods()
+		if err != nil {
+			// Path prefixes have no methods.
+			return nil
+		}
+		path, err := r.GetPathTemplate()
+		if err != nil {
+			return err
+		}
+		for _, m := range methods {
+			results = append(results, route{name: r.GetName(), path: path, method: m})
+		}
+		return nil
+	})
+	return results, err
+}
+
+var pathParameter = regexp.MustCompile(`{(\w+)}`)
+
+// OpenAPI builds the OpenAPI document of the registered REST routes. Every route must be documented.
+func OpenAPI() ([]byte, error) {
+	rs, err := routes()
+	if err != nil {
+		return nil, err
+	}
+
+	schemas := map[string]any{
+		"errors": map[string]any{
+			"type":       "object",
+			"properties": map[string]any{"errors": schemaOf(reflect.TypeOf([]rest.Error{}))},
+		},
+	}
+	paths := make(map[string]any)
+	documented := make(map[string]bool)
+	for _, r := range rs {
+		o, ok := operations[r.name]
+		if !ok {
+			return nil, fmt.Errorf("route [%s %s] named [%s] is not documented", r.method, r.path, r.name)
+		}
+		documented[r.name] = true
+
+		params := []any{
+			map[string]any{"$ref": "#/components/parameters/TENANT_ID"},
+			map[string]any{"$ref": "#/components/parameters/REGION"},
+			map[string]any{"$ref": "#/components/parameters/MAJOR_VERSION"},
+			map[string]any{"$ref": "#/components/parameters/MINOR_VERSION"},
+		}
+		for _, m := range pathParameter.FindAllStringSubmatch(r.path, -1) {
+			params = append(params, map[string]any{"in": "path", "name": m[1], "required": true, "schema": integer})
+		}
+		for _, p := range o.parameters {
+			schema := p.schema
+			if schema == nil {
+				schema = str
+			}
+			params = append(params, map[string]any{"in": p.in, "name": p.name, "description": p.description, "required": p.required, "schema": schema})
+		}
+
+		responses := make(map[string]any)
+		for _, resp := range o.responses {
+			res := map[string]any{"description": resp.description}
+			switch {
+			case resp.stream:
+				res["content"] = map[string]any{"text/event-stream": map[string]any{"schema": str}}
+			case resp.body != nil:
+				res["content"] = map[string]any{contentType: map[string]any{"schema": document(schemas, resp.body)}}
+			case resp.status >= http.StatusBadRequest:
+				res["content"] = map[string]any{contentType: map[string]any{"schema": map[string]any{"$ref": "#/components/schemas/errors"}}}
+			}
+			responses[fmt.Sprint(resp.status)] = res
+		}
+
+		op := map[string]any{
+			"operationId": r.name,
+			"summary":     o.summary,
+			"parameters":  params,
+			"responses":   responses,
+		}
+		if o.request != nil {
+			op["requestBody"] = map[string]any{"required": true, "content": map[string]any{contentType: map[string]any{"schema": document(schemas, o.request)}}}
+		}
+
+		path, ok := paths[r.path].(map[string]any)
+		if !ok {
+			path = make(map[string]any)
+			paths[r.path] = path
+		}
+		path[strings.ToLower(r.method)] = op
+	}
+
+	undocumented := make([]string, 0)
+	for name := range operations {
+		if !documented[name] {
+			undocumented = append(undocumented, name)
+		}
+	}
+	if len(undocumented) > 0 {
+		sort.Strings(undocumented)
+		return nil, fmt.Errorf("operations %v are not registered routes", undocumented)
+	}
+
+	header := func(name string, schema map[string]any) map[string]any {
+		return map[string]any{"in": "header", "name": name, "required": true, "schema": schema}
+	}
+	doc := map[string]any{
+		"openapi": "3.0.3",
+		"info":    map[string]any{"title": "atlas-buddies", "version": "1.0.0"},
+		"servers": []any{map[string]any{"url": Prefix}},
+		"paths":   paths,
+		"components": map[string]any{
+			"parameters": map[string]any{
+				"TENANT_ID":     header("TENANT_ID", map[string]any{"type": "string", "format": "uuid"}),
+				"REGION":        header("REGION", str),
+				"MAJOR_VERSION": header("MAJOR_VERSION", integer),
+				"MINOR_VERSION": header("MINOR_VERSION", integer),
+			},
+			"schemas": schemas,
+		},
+	}
+	return marshal(doc)
+}
+
+// document returns the schema of a JSON:API document holding a REST model, or a slice of them, registering the
+// resource schema of the model in schemas.
+func document(schemas map[string]any, body any) map[string]any {
+	t := reflect.TypeOf(body)
+	many := t.Kind() == reflect.Slice
+	if many {
+		t = t.Elem()
+	}
+	name := reflect.Zero(t).Interface().(interface{ GetName() string }).GetName()
+	schemas[name] = map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"type":       map[string]any{"type": "string", "enum": []string{name}},
+			"id":         str,
+			"attributes": schemaOf(t),
+		},
+	}
+
+	data := map[string]any{"$ref": "#/components/schemas/" + name}
+	if many {
+		data = map[string]any{"type": "array", "items": data}
+	}
+	return map[string]any{"type": "object", "properties": map[string]any{"data": data}}
+}
+
+func marshal(doc map[string]any) ([]byte, error) {
+	b, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(b, '\n'), nil
+}
@@ -0,0 +1,93 @@
+package spec
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var timeType = reflect.TypeOf(time.Time{})
+var uuidType = reflect.TypeOf(uuid.UUID{})
+var rawMessageType = reflect.TypeOf(json.RawMessage{})
+
+// schemaOf returns the JSON schema of the JSON encoding of a Go type, following its json struct tags.
+func schemaOf(t reflect.Type) map[string]any {
+	switch t {
+	case timeType:
+		return map[string]any{"type": "string", "format": "date-time"}
+	case uuidType:
+		return map[string]any{"type": "string", "format": "uuid"}
+	case rawMessageType:
+		return map[string]any{}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		return schemaOf(t.Elem())
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int8:
+		return map[string]any{"type": "integer", "minimum": -128, "maximum": 127}
+	case reflect.Uint8:
+		return map[string]any{"type": "integer", "minimum": 0, "maximum": 255}
+	case reflect.Int16:
+		return map[string]any{"type": "integer", "minimum": -32768, "maximum": 32767}
+	case reflect.Uint16:
+		return map[string]any{"type": "integer", "minimum": 0, "maximum": 65535}
+	case reflect.Int32:
+		return map[string]any{"type": "integer", "format": "int32"}
+	case reflect.Int, reflect.Int64:
+		return map[string]any{"type": "integer", "format": "int64"}
+	case reflect.Uint, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer", "format": "int64", "minimum": 0}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		return map[string]any{"type": "array", "items": schemaOf(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": schemaOf(t.Elem())}
+	case reflect.Struct:
+		return map[string]any{"type": "object", "properties": propertiesOf(t)}
+	default:
+		return map[string]any{}
+	}
+}
+
+// propertiesOf returns the schemas of the JSON encoded fields of a struct, keyed by their JSON name. Fields of embedded
+// structs are promoted, as encoding/json does.
+func propertiesOf(t reflect.Type) map[string]any {
+	properties := make(map[string]any)
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if f.Anonymous && name == "" && f.Type.Kind() == reflect.Struct {
+			for k, v := range propertiesOf(f.Type) {
+				properties[k] = v
+			}
+			continue
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		properties[name] = schemaOf(f.Type)
+	}
+	return properties
+}
+
+// withType returns the schema of a message envelope, with its type property restricted to the given type.
+func withType(payload any, typ string) map[string]any {
+	s := schemaOf(reflect.TypeOf(payload))
+	s["properties"].(map[string]any)["type"] = map[string]any{"type": "string", "enum": []string{typ}}
+	return s
+}
@@ -0,0 +1,13 @@
+// Package spec builds the OpenAPI document of the REST API and the AsyncAPI document of the Kafka topics from the
+// registered routes, REST models and message types. The documents are committed under api/, and a test fails when
+// they drift from the code.
+package spec
+
+//go:generate go run ../cmd/specgen -out ../api
+
+const (
+	// OpenAPIFile is the file name of the committed OpenAPI document.
+	OpenAPIFile = "openapi.json"
+	// AsyncAPIFile is the file name of the committed AsyncAPI document.
+	AsyncAPIFile = "asyncapi.json"
+)
@@ -0,0 +1,24 @@
+package spec
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCommittedDocumentsMatchCode(t *testing.T) {
+	for name, build := range map[string]func() ([]byte, error){OpenAPIFile: OpenAPI, AsyncAPIFile: AsyncAPI} {
+		want, err := build()
+		if err != nil {
+			t.Fatalf("Unable to build [%s]: %v", name, err)
+		}
+		got, err := os.ReadFile(filepath.Join("..", "api", name))
+		if err != nil {
+			t.Fatalf("Unable to read committed [%s]: %v", name, err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("Committed [%s] is out of date. Run go generate ./spec to update it.", name)
+		}
+	}
+}
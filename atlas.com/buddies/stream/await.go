@@ -0,0 +1,26 @@
+package stream
+
+import "context"
+
+// Await returns the first event carrying the correlation id and one of the given types. It returns false when ctx is
+// done or the subscription is closed before such an event arrives. Other events are discarded.
+func Await(ctx context.Context, events <-chan Event, correlationId string, types ...string) (Event, bool) {
+	for {
+		select {
+		case <-ctx.Done():
+			return Event{}, false
+		case e, ok := <-events:
+			if !ok {
+				return Event{}, false
+			}
+			if e.CorrelationId != correlationId {
+				continue
+			}
+			for _, t := range types {
+				if e.Type == t {
+					return e, true
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package stream
+
+import (
+	"github.com/google/uuid"
+	"sync"
+)
+
+// SubscriberBuffer is the number of events a subscriber may fall behind by before it is dropped.
+const SubscriberBuffer = 64
+
+// Event is a buddy list status event delivered to the subscribers of a character.
+type Event struct {
+	// Version is the buddy list version after the change the event describes. It is zero for ERROR events.
+	Version uint32
+	// CorrelationId is the correlation id of the command which resulted in the event, if any.
+	CorrelationId string
+	Type          string
+	Data          []byte
+}
+
+type key struct {
+	tenantId    uuid.UUID
+	characterId uint32
+}
+
+// Hub fans buddy list status events out to the subscribers of the character and tenant they belong to.
+type Hub struct {
+	mu          sync.Mutex
+	subscribers map[key]map[chan Event]struct{}
+}
+
+var hub *Hub
+var once sync.Once
+
+func GetHub() *Hub {
+	once.Do(func() {
+		hub = &Hub{subscribers: make(map[key]map[chan Event]struct{})}
+	})
+	return hub
+}
+
+// Subscribe registers a subscriber for the events of a character. The returned function cancels the subscription. The
+// channel is closed on cancellation, or when the subscriber falls more than SubscriberBuffer events behind, in which
+// case it must resubscribe and catch up.
+func (h *Hub) Subscribe(tenantId uuid.UUID, characterId uint32) (<-chan Event, func()) {
+	k := key{tenantId: tenantId, characterId: characterId}
+	c := make(chan Event, SubscriberBuffer)
+
+	h.mu.Lock()
+	if _, ok := h.subscribers[k]; !ok {
+		h.subscribers[k] = make(map[chan Event]struct{})
+	}
+	h.subscribers[k][c] = struct{}{}
+	h.mu.Unlock()
+
+	return c, func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		h.remove(k, c)
+	}
+}
+
+// Publish delivers an event to the subscribers of a character without blocking.
+func (h *Hub) Publish(tenantId uuid.UUID, characterId uint32, e Event) {
+	k := key{tenantId: tenantId, characterId: characterId}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for c := range h.subscribers[k] {
+		select {
+		case c <- e:
+		default:
+			h.remove(k, c)
+		}
+	}
+}
+
+// remove closes and forgets a subscriber. It must be called with the lock held, and is a no-op for a subscriber which
+// was already removed.
+func (h *Hub) remove(k key, c chan Event) {
+	cs, ok := h.subscribers[k]
+	if !ok {
+		return
+	}
+	if _, ok = cs[c]; !ok {
+		return
+	}
+	delete(cs, c)
+	close(c)
+	if len(cs) == 0 {
+		delete(h.subscribers, k)
+	}
+}
@@ -0,0 +1,112 @@
+package stream
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestPublishFiltersByTenantAndCharacter(t *testing.T) {
+	h := &Hub{subscribers: make(map[key]map[chan Event]struct{})}
+	tenantId := uuid.New()
+
+	c, cancel := h.Subscribe(tenantId, 12345)
+	defer cancel()
+
+	h.Publish(uuid.New(), 12345, Event{Version: 1, Type: "BUDDY_ADDED"})
+	h.Publish(tenantId, 67890, Event{Version: 2, Type: "BUDDY_ADDED"})
+	h.Publish(tenantId, 12345, Event{Version: 3, Type: "BUDDY_REMOVED"})
+
+	select {
+	case e := <-c:
+		if e.Version != 3 || e.Type != "BUDDY_REMOVED" {
+			t.Errorf("Expected version 3 BUDDY_REMOVED, got version %d %s", e.Version, e.Type)
+		}
+	default:
+		t.Fatalf("Expected an event")
+	}
+	select {
+	case e := <-c:
+		t.Errorf("Expected no further events, got version %d %s", e.Version, e.Type)
+	default:
+	}
+}
+
+func TestSlowSubscriberIsClosed(t *testing.T) {
+	h := &Hub{subscribers: make(map[key]map[chan Event]struct{})}
+	tenantId := uuid.New()
+
+	c, cancel := h.Subscribe(tenantId, 12345)
+	for i := 0; i <= SubscriberBuffer; i++ {
+		h.Publish(tenantId, 12345, Event{Version: uint32(i + 1), Type: "BUDDY_CHANNEL_CHANGE"})
+	}
+
+	n := 0
+	for range c {
+		n++
+	}
+	if n != SubscriberBuffer {
+		t.Errorf("Expected %d buffered events before close, got %d", SubscriberBuffer, n)
+	}
+
+	// Cancelling an already closed subscription must not panic.
+	cancel()
+	if len(h.subscribers) != 0 {
+		t.Errorf("Expected no subscribers, got %d", len(h.subscribers))
+	}
+}
+
+func TestWriteEvent(t *testing.T) {
+	var b bytes.Buffer
+	err := WriteEvent(&b, 7, "BUDDY_ADDED", []byte("{\"a\":1}\n{\"b\":2}"))
+	if err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+	expected := "id: 7\nevent: BUDDY_ADDED\ndata: {\"a\":1}\ndata: {\"b\":2}\n\n"
+	if b.String() != expected {
+		t.Errorf("Expected %q, got %q", expected, b.String())
+	}
+
+	b.Reset()
+	_ = WriteEvent(&b, 0, "ERROR", []byte("{}"))
+	if b.String() != "event: ERROR\ndata: {}\n\n" {
+		t.Errorf("Expected no id line, got %q", b.String())
+	}
+}
+
+func TestAwaitMatchesCorrelationAndType(t *testing.T) {
+	c := make(chan Event, 4)
+	c <- Event{Version: 1, CorrelationId: "other", Type: "BUDDY_ADDED"}
+	c <- Event{Version: 2, CorrelationId: "abc", Type: "BUDDY_REMOVED"}
+	c <- Event{Version: 3, CorrelationId: "abc", Type: "CAPACITY_CHANGE"}
+
+	e, ok := Await(context.Background(), c, "abc", "CAPACITY_CHANGE", "ERROR")
+	if !ok {
+		t.Fatalf("Expected an event")
+	}
+	if e.Version != 3 {
+		t.Errorf("Expected version 3, got %d", e.Version)
+	}
+}
+
+func TestAwaitEndsWithContext(t *testing.T) {
+	c := make(chan Event)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if _, ok := Await(ctx, c, "abc", "ERROR"); ok {
+		t.Errorf("Expected no event")
+	}
+}
+
+func TestAwaitEndsWithClosedSubscription(t *testing.T) {
+	c := make(chan Event)
+	close(c)
+
+	if _, ok := Await(context.Background(), c, "abc", "ERROR"); ok {
+		t.Errorf("Expected no event")
+	}
+}
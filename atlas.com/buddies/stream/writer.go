@@ -0,0 +1,31 @@
+package stream
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// WriteEvent writes a server-sent event. A zero id leaves the last event id of the client unchanged.
+func WriteEvent(w io.Writer, id uint32, event string, data []byte) error {
+	var b bytes.Buffer
+	if id != 0 {
+		b.WriteString("id: " + strconv.Itoa(int(id)) + "\n")
+	}
+	b.WriteString("event: " + event + "\n")
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		b.WriteString("data: ")
+		b.Write(line)
+		b.WriteString("\n")
+	}
+	b.WriteString("\n")
+	_, err := w.Write(b.Bytes())
+	return err
+}
+
+// WriteHeartbeat writes a comment line, which keeps idle connections and intermediate proxies alive.
+func WriteHeartbeat(w io.Writer) error {
+	_, err := fmt.Fprint(w, ": heartbeat\n\n")
+	return err
+}